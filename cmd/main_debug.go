@@ -4,8 +4,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
 	_ "github.com/Irurnnen/ordinary-calc/docs"
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
 	"github.com/Irurnnen/ordinary-calc/internal/application"
+	"github.com/Irurnnen/ordinary-calc/internal/config"
 )
 
 // @title		Ordinary Calc
@@ -18,7 +29,59 @@ import (
 // @host		127.0.0.1:8080
 // @BasePath	/api/v1
 
+// @securityDefinitions.apikey	ApiKeyAuth
+// @in							header
+// @name						X-API-Key
+// @description				API key created by the apikey command, required when the file of API keys is configured
+
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description				API key with the Bearer prefix, e.g. "Bearer ock_..."
+
 func main() {
-	app := application.NewDebug()
-	app.Run()
+	// Stop the server gracefully on Ctrl+C and on SIGTERM from docker
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// API keys are managed by the apikey command, e.g. apikey create -name ci
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		err := apikeys.Command(os.Args[2:], os.Stdout, os.Getenv)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "apikey: %s\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	// The health check of docker runs the binary with the healthcheck command
+	args := os.Args[1:]
+	healthcheck := len(args) > 0 && args[0] == "healthcheck"
+	if healthcheck {
+		args = args[1:]
+	}
+
+	app, err := application.NewDebug(args)
+	if errors.Is(err, flag.ErrHelp) {
+		config.Usage(os.Stderr)
+		return
+	}
+	if err != nil {
+		// Errors of config are written line by line to be readable
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%s\n", err)
+		os.Exit(2)
+	}
+
+	if healthcheck {
+		if err := app.Healthcheck(ctx); err != nil {
+			slog.Error("Server is not healthy", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := app.Run(ctx); err != nil {
+		slog.Error("Fatal error while running server", "error", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,95 @@
+package apikeys
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// EnvFile is the environment variable with the path to the file of API keys
+const EnvFile = "API_KEYS_FILE"
+
+// ErrUnknownCommand is returned for the unknown command of API keys
+var ErrUnknownCommand = errors.New("unknown command, expected create, revoke or list")
+
+// ErrNoFile is returned when the file of API keys is not set
+var ErrNoFile = errors.New("file of API keys is not set, use the flag -file or " + EnvFile)
+
+// Command manages API keys in the file from the flag -file or the
+// environment variable API_KEYS_FILE:
+//
+//	create -name ci -scopes calc:evaluate,calc:history -namespace team
+//	revoke 3f2c1e9a0b7d4c1e
+//	list
+//
+// The secret of created key is written to stdout only once
+func Command(args []string, stdout io.Writer, getenv func(string) string) error {
+	if len(args) == 0 {
+		return ErrUnknownCommand
+	}
+	command, args := args[0], args[1:]
+	if command != "create" && command != "revoke" && command != "list" {
+		return fmt.Errorf("%w: %q", ErrUnknownCommand, command)
+	}
+
+	flags := flag.NewFlagSet("apikey "+command, flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	path := flags.String("file", getenv(EnvFile), "JSON file of API keys, overrides "+EnvFile)
+	var name, scopes, namespace string
+	if command == "create" {
+		flags.StringVar(&name, "name", "", "name of the key, e.g. the name of integration")
+		flags.StringVar(&scopes, "scopes", ScopeEvaluate, "comma-separated scopes: "+strings.Join(Scopes, ", "))
+		flags.StringVar(&namespace, "namespace", "", "namespace of user-defined functions shared by keys, the ID of key by default")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return ErrNoFile
+	}
+
+	file, err := ReadFile(*path)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "create":
+		raw, key, err := file.Create(name, namespace, strings.Split(scopes, ","), time.Now())
+		if err != nil {
+			return err
+		}
+		if err := file.Write(*path); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Key %s has been created with scopes %s. Save it now, it is not shown again:\n%s\n", key.ID, strings.Join(key.Scopes, ", "), raw)
+		return nil
+	case "revoke":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("revoke: expected the ID of key")
+		}
+		if err := file.Revoke(flags.Arg(0), time.Now()); err != nil {
+			return err
+		}
+		if err := file.Write(*path); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Key %s has been revoked\n", flags.Arg(0))
+		return nil
+	default:
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPES\tNAMESPACE\tCREATED\tREVOKED")
+		for _, key := range file.Keys {
+			revoked := "-"
+			if key.RevokedAt != nil {
+				revoked = key.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, strings.Join(key.Scopes, ","), key.FunctionNamespace(), key.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		return w.Flush()
+	}
+}
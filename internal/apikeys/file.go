@@ -0,0 +1,179 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes of API keys
+const (
+	// ScopeEvaluate allows calculation of expressions, numeric methods, plots
+	// and reading of user-defined functions
+	ScopeEvaluate = "calc:evaluate"
+	// ScopeHistory allows reading of the history of calculations
+	ScopeHistory = "calc:history"
+	// ScopeAdmin allows everything, including changing of user-defined functions
+	ScopeAdmin = "admin"
+)
+
+// Scopes are all known scopes
+var Scopes = []string{ScopeEvaluate, ScopeHistory, ScopeAdmin}
+
+// prefix starts every API key, so keys are easy to find in leaked texts
+const prefix = "ock"
+
+var ErrInvalidScope = errors.New("scope is unknown")
+var ErrNoScopes = errors.New("key must have at least one scope")
+var ErrUnknownKey = errors.New("key is not found")
+var ErrRevoked = errors.New("key is already revoked")
+
+// Key is the API key kept in the file. The secret of key is not kept, only
+// its SHA-256 hash. Keys with the same namespace share user-defined functions
+type Key struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	Scopes    []string   `json:"scopes"`
+	Namespace string     `json:"namespace,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key has the scope. The admin scope includes
+// all scopes
+func (k Key) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// FunctionNamespace returns the namespace of user-defined functions of the
+// key. Keys without the namespace have their own namespace named by their ID
+func (k Key) FunctionNamespace() string {
+	if k.Namespace != "" {
+		return k.Namespace
+	}
+	return k.ID
+}
+
+// File is the content of the file with API keys, e.g.
+//
+//	{"keys": [{"id": "3f2c1e9a0b7d4c1e", "name": "ci", "hash": "...", "scopes": ["calc:evaluate"], "namespace": "team", "created_at": "2026-10-19T12:00:00Z"}]}
+type File struct {
+	Keys []Key `json:"keys"`
+}
+
+// ReadFile reads the file with API keys. The empty file is returned when the
+// file does not exist
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("API keys file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Write writes the file with API keys. The file is replaced at once, so the
+// server never reads the partially written file
+func (f *File) Write(path string) error {
+	data, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(append(data, '\n')); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Create adds the new key with the scopes and the namespace of functions. It
+// returns the secret key which is shown only once, because the file keeps
+// only its hash
+func (f *File) Create(name, namespace string, scopes []string, now time.Time) (string, Key, error) {
+	if len(scopes) == 0 {
+		return "", Key{}, ErrNoScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return "", Key{}, fmt.Errorf("%w: %q, expected one of %s", ErrInvalidScope, scope, strings.Join(Scopes, ", "))
+		}
+	}
+
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", Key{}, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", Key{}, err
+	}
+
+	key := Key{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Scopes:    slices.Clone(scopes),
+		Namespace: namespace,
+		CreatedAt: now.UTC(),
+	}
+	raw := prefix + "_" + key.ID + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	key.Hash = hash(raw)
+	f.Keys = append(f.Keys, key)
+	return raw, key, nil
+}
+
+// Revoke marks the key as revoked, so it is not accepted any more. The key is
+// kept in the file to know who used it before
+func (f *File) Revoke(id string, now time.Time) error {
+	for i := range f.Keys {
+		if f.Keys[i].ID != id {
+			continue
+		}
+		if f.Keys[i].RevokedAt != nil {
+			return ErrRevoked
+		}
+		revokedAt := now.UTC()
+		f.Keys[i].RevokedAt = &revokedAt
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownKey, id)
+}
+
+// parseKey returns the ID of the key or false when it has wrong format
+func parseKey(raw string) (string, bool) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) != 3 || parts[0] != prefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,186 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func TestFileCreate(t *testing.T) {
+	cases := []struct {
+		name        string
+		scopes      []string
+		exceptedErr error
+	}{
+		{
+			name:   "Single scope",
+			scopes: []string{ScopeEvaluate},
+		},
+		{
+			name:   "All scopes",
+			scopes: []string{ScopeEvaluate, ScopeHistory, ScopeAdmin},
+		},
+		{
+			name:        "Without scopes",
+			exceptedErr: ErrNoScopes,
+		},
+		{
+			name:        "Unknown scope",
+			scopes:      []string{ScopeEvaluate, "calc:delete"},
+			exceptedErr: ErrInvalidScope,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var file File
+			raw, key, err := file.Create("ci", "", tt.scopes, now)
+			if !errors.Is(err, tt.exceptedErr) {
+				t.Fatalf("excepted error %v, got %v", tt.exceptedErr, err)
+			}
+			if err != nil {
+				if len(file.Keys) != 0 {
+					t.Errorf("excepted no keys after error, got %d", len(file.Keys))
+				}
+				return
+			}
+
+			if id, ok := parseKey(raw); !ok || id != key.ID {
+				t.Errorf("excepted the key with ID %s, got %q", key.ID, raw)
+			}
+			if key.Hash != hash(raw) || strings.Contains(key.Hash, raw) {
+				t.Errorf("excepted the hash of key instead of the key")
+			}
+			if !reflect.DeepEqual(key.Scopes, tt.scopes) || !key.CreatedAt.Equal(now) {
+				t.Errorf("excepted scopes %v created at %s, got %v at %s", tt.scopes, now, key.Scopes, key.CreatedAt)
+			}
+			if len(file.Keys) != 1 || !reflect.DeepEqual(file.Keys[0], key) {
+				t.Errorf("excepted the key in the file, got %+v", file.Keys)
+			}
+		})
+	}
+}
+
+func TestKeyFunctionNamespace(t *testing.T) {
+	var file File
+	_, own, _ := file.Create("own", "", []string{ScopeEvaluate}, now)
+	_, shared, _ := file.Create("shared", "team", []string{ScopeEvaluate}, now)
+
+	if got := own.FunctionNamespace(); got != own.ID {
+		t.Errorf("excepted the namespace %q of key without namespace, got %q", own.ID, got)
+	}
+	if got := shared.FunctionNamespace(); got != "team" {
+		t.Errorf("excepted the namespace %q, got %q", "team", got)
+	}
+}
+
+func TestFileRevoke(t *testing.T) {
+	var file File
+	_, key, err := file.Create("ci", "", []string{ScopeEvaluate}, now)
+	if err != nil {
+		t.Fatalf("Create: unexcepted error %q", err)
+	}
+
+	if err := file.Revoke(key.ID, now); err != nil {
+		t.Fatalf("Revoke: unexcepted error %q", err)
+	}
+	if file.Keys[0].RevokedAt == nil || !file.Keys[0].RevokedAt.Equal(now) {
+		t.Errorf("excepted the key is revoked at %s, got %v", now, file.Keys[0].RevokedAt)
+	}
+	if err := file.Revoke(key.ID, now); !errors.Is(err, ErrRevoked) {
+		t.Errorf("excepted ErrRevoked for the second revoke, got %v", err)
+	}
+	if err := file.Revoke("0000000000000000", now); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("excepted ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestFileReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	// Missing file has no keys
+	file, err := ReadFile(path)
+	if err != nil || len(file.Keys) != 0 {
+		t.Fatalf("ReadFile: excepted empty file, got %+v with error %v", file, err)
+	}
+
+	if _, _, err := file.Create("ci", "", []string{ScopeEvaluate}, now); err != nil {
+		t.Fatalf("Create: unexcepted error %q", err)
+	}
+	if err := file.Write(path); err != nil {
+		t.Fatalf("Write: unexcepted error %q", err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: unexcepted error %q", err)
+	}
+	if !reflect.DeepEqual(got, file) {
+		t.Errorf("excepted %+v, got %+v", file, got)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	getenv := func(name string) string {
+		if name == EnvFile {
+			return path
+		}
+		return ""
+	}
+	run := func(args ...string) (string, error) {
+		var stdout bytes.Buffer
+		err := Command(args, &stdout, getenv)
+		return stdout.String(), err
+	}
+
+	output, err := run("create", "-name", "ci", "-scopes", "calc:evaluate,calc:history", "-namespace", "team")
+	if err != nil {
+		t.Fatalf("create: unexcepted error %q", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	raw := lines[len(lines)-1]
+	id, ok := parseKey(raw)
+	if !ok {
+		t.Fatalf("create: excepted the key in the last line, got %q", output)
+	}
+
+	output, err = run("list")
+	if err != nil || !strings.Contains(output, id) || !strings.Contains(output, "calc:evaluate,calc:history") || !strings.Contains(output, "team") {
+		t.Errorf("list: excepted the key %s with scopes and namespace, got %q with error %v", id, output, err)
+	}
+	if strings.Contains(output, raw) {
+		t.Errorf("list: excepted no secret keys, got %q", output)
+	}
+
+	if _, err := run("revoke", id); err != nil {
+		t.Errorf("revoke: unexcepted error %q", err)
+	}
+	file, _ := ReadFile(path)
+	if len(file.Keys) != 1 || file.Keys[0].RevokedAt == nil {
+		t.Errorf("excepted the revoked key in the file, got %+v", file.Keys)
+	}
+
+	errorCases := []struct {
+		name        string
+		args        []string
+		exceptedErr error
+	}{
+		{"Without command", nil, ErrUnknownCommand},
+		{"Unknown command", []string{"delete", id}, ErrUnknownCommand},
+		{"Unknown key", []string{"revoke", "0000000000000000"}, ErrUnknownKey},
+		{"Unknown scope", []string{"create", "-scopes", "root"}, ErrInvalidScope},
+		{"Without file", []string{"list", "-file", ""}, ErrNoFile},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := run(tt.args...); !errors.Is(err, tt.exceptedErr) {
+				t.Errorf("excepted error %v, got %v", tt.exceptedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Header is the header with the API key. The key could also be sent in the
+// Authorization header with the Bearer scheme
+const Header = "X-API-Key"
+
+// ProtocolPrefix is the prefix of the WebSocket subprotocol with the API key.
+// Browsers could not set headers of WebSocket requests, so they send the key
+// as the subprotocol, e.g. new WebSocket(url, ["calc", "apikey." + key])
+const ProtocolPrefix = "apikey."
+
+var ErrUnauthorized = errors.New("API key is missing or invalid")
+var ErrForbidden = errors.New("API key has no required scope")
+
+// ScopeError is returned when the key has no scope required by the endpoint
+type ScopeError struct {
+	Scope string
+}
+
+func (e *ScopeError) Error() string {
+	return ErrForbidden.Error() + " " + e.Scope
+}
+
+func (e *ScopeError) Unwrap() error {
+	return ErrForbidden
+}
+
+type contextKey int
+
+const keyKey contextKey = iota
+
+// NewContext returns the context with the authenticated key
+func NewContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, keyKey, key)
+}
+
+// FromContext returns the authenticated key of request
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(keyKey).(Key)
+	return key, ok
+}
+
+// raw returns the API key from the X-API-Key header, the Authorization
+// header with the Bearer scheme or the WebSocket subprotocol with ProtocolPrefix
+func raw(r *http.Request) string {
+	if raw := r.Header.Get(Header); raw != "" {
+		return raw
+	}
+	if raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return raw
+	}
+	for _, protocol := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(protocol), ProtocolPrefix); ok {
+			return raw
+		}
+	}
+	return ""
+}
+
+// Middleware authenticates requests by API keys of the store and puts the key
+// to the context of request. Requests without the valid key are rejected by
+// the error handler with ErrUnauthorized. Requests are not authenticated
+// when the store is nil
+func Middleware(store *Store, errorHandler func(w http.ResponseWriter, r *http.Request, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, err := store.Authenticate(raw(r))
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="ordinary-calc"`)
+				errorHandler(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), key)))
+		})
+	}
+}
+
+// RequireScope rejects requests whose key has no scope with the ScopeError.
+// Requests without authenticated key are passed, so the endpoint is open
+// when authentication is disabled
+func RequireScope(scope string, errorHandler func(w http.ResponseWriter, r *http.Request, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := FromContext(r.Context()); ok && !key.HasScope(scope) {
+				errorHandler(w, r, &ScopeError{Scope: scope})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
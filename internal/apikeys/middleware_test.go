@@ -0,0 +1,130 @@
+package apikeys
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testErrorHandler writes 401 for ErrUnauthorized and 403 for ErrForbidden
+func testErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, ErrForbidden):
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	store, _, active, revoked := newTestStore(t, DefaultInterval)
+	id, _ := parseKey(active)
+
+	var authenticated Key
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticated, _ = FromContext(r.Context())
+	})
+
+	cases := []struct {
+		name           string
+		store          *Store
+		headers        map[string]string
+		scope          string
+		exceptedStatus int
+		exceptedID     string
+	}{
+		{
+			name:           "X-API-Key header",
+			store:          store,
+			headers:        map[string]string{"X-API-Key": active},
+			scope:          ScopeEvaluate,
+			exceptedStatus: http.StatusOK,
+			exceptedID:     id,
+		},
+		{
+			name:           "Bearer",
+			store:          store,
+			headers:        map[string]string{"Authorization": "Bearer " + active},
+			scope:          ScopeEvaluate,
+			exceptedStatus: http.StatusOK,
+			exceptedID:     id,
+		},
+		{
+			name:           "WebSocket subprotocol",
+			store:          store,
+			headers:        map[string]string{"Sec-WebSocket-Protocol": "calc, " + ProtocolPrefix + active},
+			scope:          ScopeEvaluate,
+			exceptedStatus: http.StatusOK,
+			exceptedID:     id,
+		},
+		{
+			name:           "Without key",
+			store:          store,
+			scope:          ScopeEvaluate,
+			exceptedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Revoked key",
+			store:          store,
+			headers:        map[string]string{"X-API-Key": revoked},
+			scope:          ScopeEvaluate,
+			exceptedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Missing scope",
+			store:          store,
+			headers:        map[string]string{"X-API-Key": active},
+			scope:          ScopeAdmin,
+			exceptedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Authentication disabled",
+			scope:          ScopeAdmin,
+			exceptedStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticated = Key{}
+			h := Middleware(tt.store, testErrorHandler)(RequireScope(tt.scope, testErrorHandler)(next))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, r)
+
+			if recorder.Code != tt.exceptedStatus {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedStatus, recorder.Code)
+			}
+			if authenticated.ID != tt.exceptedID {
+				t.Errorf("excepted key %q in the context, got %q", tt.exceptedID, authenticated.ID)
+			}
+			if tt.exceptedStatus == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("excepted WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	cases := []struct {
+		scopes   []string
+		scope    string
+		excepted bool
+	}{
+		{[]string{ScopeEvaluate}, ScopeEvaluate, true},
+		{[]string{ScopeEvaluate}, ScopeHistory, false},
+		{[]string{ScopeAdmin}, ScopeHistory, true},
+		{nil, ScopeEvaluate, false},
+	}
+	for _, tt := range cases {
+		if got := (Key{Scopes: tt.scopes}).HasScope(tt.scope); got != tt.excepted {
+			t.Errorf("%v has scope %s: excepted %v, got %v", tt.scopes, tt.scope, tt.excepted, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync/atomic"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/watch"
+)
+
+// DefaultInterval is the period of checking the file of API keys for changes
+const DefaultInterval = watch.DefaultInterval
+
+// Store authenticates API keys from the file. The file is reloaded by Run
+// when it changes, so keys created and revoked by the command are applied
+// without restart of the server
+type Store struct {
+	path    string
+	keys    atomic.Pointer[map[string]Key]
+	watcher *watch.Watcher
+}
+
+// NewStore loads API keys from the file
+func NewStore(path string, interval time.Duration) (*Store, error) {
+	s := &Store{path: path}
+	watcher, err := watch.New("API keys", interval, s.reload, path)
+	if err != nil {
+		return nil, err
+	}
+	s.watcher = watcher
+	return s, nil
+}
+
+// Authenticate returns the key of the secret. It returns ErrUnauthorized when
+// the key is unknown or revoked
+func (s *Store) Authenticate(raw string) (Key, error) {
+	id, ok := parseKey(raw)
+	if !ok {
+		return Key{}, ErrUnauthorized
+	}
+	key, ok := (*s.keys.Load())[id]
+	if !ok || key.RevokedAt != nil {
+		return Key{}, ErrUnauthorized
+	}
+	if subtle.ConstantTimeCompare([]byte(hash(raw)), []byte(key.Hash)) != 1 {
+		return Key{}, ErrUnauthorized
+	}
+	return key, nil
+}
+
+// Run checks the file for changes until the context is done. Previous keys
+// are kept if the changed file could not be loaded
+func (s *Store) Run(ctx context.Context) {
+	s.watcher.Run(ctx)
+}
+
+func (s *Store) reload() error {
+	file, err := ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]Key, len(file.Keys))
+	for _, key := range file.Keys {
+		keys[key.ID] = key
+	}
+	s.keys.Store(&keys)
+	return nil
+}
@@ -0,0 +1,89 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestStore returns the store of the file with the active and the revoked
+// keys and their secrets
+func newTestStore(t *testing.T, interval time.Duration) (store *Store, path, active, revoked string) {
+	t.Helper()
+
+	var file File
+	active, _, _ = file.Create("active", "", []string{ScopeEvaluate}, now)
+	revoked, key, _ := file.Create("revoked", "", []string{ScopeAdmin}, now)
+	file.Revoke(key.ID, now)
+
+	path = filepath.Join(t.TempDir(), "keys.json")
+	if err := file.Write(path); err != nil {
+		t.Fatalf("Write: unexcepted error %q", err)
+	}
+	store, err := NewStore(path, interval)
+	if err != nil {
+		t.Fatalf("NewStore: unexcepted error %q", err)
+	}
+	return store, path, active, revoked
+}
+
+func TestStoreAuthenticate(t *testing.T) {
+	store, _, active, revoked := newTestStore(t, DefaultInterval)
+	id, _ := parseKey(active)
+
+	cases := []struct {
+		name        string
+		raw         string
+		exceptedErr error
+	}{
+		{"Active key", active, nil},
+		{"Revoked key", revoked, ErrUnauthorized},
+		{"Wrong secret", "ock_" + id + "_wrong", ErrUnauthorized},
+		{"Unknown ID", "ock_0000000000000000_secret", ErrUnauthorized},
+		{"Wrong format", "secret", ErrUnauthorized},
+		{"Empty key", "", ErrUnauthorized},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := store.Authenticate(tt.raw)
+			if !errors.Is(err, tt.exceptedErr) {
+				t.Fatalf("excepted error %v, got %v", tt.exceptedErr, err)
+			}
+			if err == nil && (key.ID != id || key.Name != "active") {
+				t.Errorf("excepted the active key, got %+v", key)
+			}
+		})
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	store, path, active, _ := newTestStore(t, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Run(ctx)
+
+	// Broken file does not replace keys
+	if err := os.WriteFile(path, []byte(`{"keys": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := store.Authenticate(active); err != nil {
+		t.Fatalf("Authenticate: unexcepted error %q after broken file", err)
+	}
+
+	// Revoked key is not accepted after reload
+	if err := os.WriteFile(path, []byte(`{"keys": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := store.Authenticate(active); err != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Authenticate: excepted error after the key is removed from the file")
+}
@@ -1,59 +1,350 @@
 package application
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
 
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
+	"github.com/Irurnnen/ordinary-calc/internal/cache"
+	"github.com/Irurnnen/ordinary-calc/internal/certificates"
 	"github.com/Irurnnen/ordinary-calc/internal/config"
+	"github.com/Irurnnen/ordinary-calc/internal/conversion"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
 	"github.com/Irurnnen/ordinary-calc/internal/handler"
+	"github.com/Irurnnen/ordinary-calc/internal/logging"
+	"github.com/Irurnnen/ordinary-calc/internal/metrics"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/internal/ratelimit"
+	"github.com/Irurnnen/ordinary-calc/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
+// errors of readiness checks
+var errShuttingDown = errors.New("server is shutting down")
+var errNoConversionTable = errors.New("conversion table is not loaded")
+
 type Application struct {
 	Config config.Config
 	Debug  bool
+	// Logger writes logs of the server. The default logger is used when it is nil
+	Logger *slog.Logger
+
+	// shuttingDown is set when the server stops, so it is not ready for requests
+	shuttingDown atomic.Bool
+}
+
+// New returns the application configured by the file, env and flags of
+// command line, see config.Load
+func New(args []string) (*Application, error) {
+	return newApplication(false, args)
 }
 
-func New() *Application {
+func NewDebug(args []string) (*Application, error) {
+	return newApplication(true, args)
+}
+
+// newApplication returns the application configured by the file, env and
+// flags which writes JSON logs to the standard output
+func newApplication(debug bool, args []string) (*Application, error) {
+	cfg, err := config.Load(args, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	logger := logging.New(os.Stdout, cfg.LogLevel)
+	slog.SetDefault(logger)
+
 	return &Application{
-		Config: *config.NewConfigFromEnv(),
-		Debug:  false,
+		Config: *cfg,
+		Debug:  debug,
+		Logger: logger,
+	}, nil
+}
+
+// Run listens on the host and the port of config and serves requests until
+// the context is done, see Serve
+func (a *Application) Run(ctx context.Context) error {
+	address := net.JoinHostPort(a.Config.Host, strconv.Itoa(a.Config.Port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", address, err)
 	}
+	return a.Serve(ctx, listener)
 }
 
-func NewDebug() *Application {
-	return &Application{
-		Config: *config.NewConfigFromEnv(),
-		Debug:  true,
+// Serve serves requests on the listener until the context is done. Then it
+// stops accepting new connections and waits for in-flight requests within
+// the shutdown timeout of config. The listener is closed on return
+func (a *Application) Serve(ctx context.Context, listener net.Listener) error {
+	logger := a.logger()
+
+	shutdownTracing, err := tracing.Setup(ctx, a.Config.TracingEndpoint)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.WithoutCancel(ctx)); err != nil {
+			logger.Error("Error while shutting down tracing", "error", err)
+		}
+	}()
+
+	router, err := a.router(ctx, logger)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	server := a.newServer(router)
+
+	// Certificates are reloaded when files change, so they are renewed without restart
+	if a.Config.TLS.Enabled() {
+		watcher, err := certificates.NewWatcher(a.Config.TLS.CertFile, a.Config.TLS.KeyFile, a.Config.TLS.ClientCAFile, certificates.DefaultInterval)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("load TLS certificates: %w", err)
+		}
+		go watcher.Run(ctx)
+		server.TLSConfig = watcher.TLSConfig()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if a.Config.TLS.Enabled() {
+			serveErr <- server.ServeTLS(listener, "", "")
+			return
+		}
+		serveErr <- server.Serve(listener)
+	}()
+	logger.Info("Ordinary-calc has started without errors", "address", listener.Addr().String(), "tls", a.Config.TLS.Enabled())
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Wait for in-flight requests
+	logger.Info("Ordinary-calc is shutting down")
+	a.shuttingDown.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.Config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+	logger.Info("Ordinary-calc has stopped")
+	return nil
+}
+
+// Healthcheck requests the liveness probe of the server listening on the host
+// and the port of config. It returns the error when the server is not alive.
+// It is used by docker, because the image has no HTTP client
+func (a *Application) Healthcheck(ctx context.Context) error {
+	host := a.Config.Host
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	scheme, client := "http", http.DefaultClient
+	if a.Config.TLS.Enabled() {
+		// The certificate is issued for the public name of server, so it is
+		// not verified for the local address
+		tlsConfig := &tls.Config{InsecureSkipVerify: true}
+		if a.Config.TLS.ClientCAFile != "" {
+			// With mutual TLS the certificate of server is presented as the
+			// client certificate, so it must be issued by the client authority
+			certificate, err := tls.LoadX509KeyPair(a.Config.TLS.CertFile, a.Config.TLS.KeyFile)
+			if err != nil {
+				return err
+			}
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+		scheme = "https"
+		client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+
+	url := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(a.Config.Port)) + "/healthz"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check: %s", resp.Status)
+	}
+	return nil
+}
+
+// newServer returns the HTTP server with timeouts of config
+func (a *Application) newServer(h http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           h,
+		ReadTimeout:       a.Config.ReadTimeout,
+		ReadHeaderTimeout: a.Config.ReadTimeout,
+		WriteTimeout:      a.Config.WriteTimeout,
+		IdleTimeout:       a.Config.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(a.logger().Handler(), slog.LevelWarn),
 	}
 }
 
-func (a *Application) Run() error {
+// router returns the router of API. Background tasks, e.g. reloading of the
+// conversion table, run until the context is done
+func (a *Application) router(ctx context.Context, logger *slog.Logger) (http.Handler, error) {
+	features := a.Config.Features
+	calcOptions := handler.CalcOptions{
+		Limits:         a.Config.Limits,
+		Timeout:        a.Config.CalcTimeout,
+		Body:           handler.BodyOptions{MaxSize: a.Config.MaxBodySize, SingleObject: true},
+		LogExpressions: a.Config.LogExpressions,
+		// Results of authenticated requests are not cached by shared caches
+		HTTPCache: handler.HTTPCacheOptions{MaxAge: a.Config.Cache.MaxAge, Private: a.Config.APIKeysFile != ""},
+	}
+	if features.Functions {
+		calcOptions.Functions = functions.NewStore(a.Config.FunctionLimits)
+	}
+	if features.Metrics {
+		calcOptions.Metrics = metrics.New()
+	}
+	if a.Config.Cache.Size > 0 {
+		calcOptions.Cache = cache.New[models.Result](a.Config.Cache.Size, a.Config.Cache.TTL)
+	}
+
+	checks := map[string]handler.ReadinessCheck{
+		"server": func(context.Context) error {
+			if a.shuttingDown.Load() {
+				return errShuttingDown
+			}
+			return nil
+		},
+	}
+
+	// Load the table of conversions
+	if a.Config.RatesFile != "" {
+		watcher, err := conversion.NewWatcher(a.Config.RatesFile, conversion.DefaultInterval)
+		if err != nil {
+			return nil, fmt.Errorf("load conversion table: %w", err)
+		}
+		go watcher.Run(ctx)
+		calcOptions.Units = watcher.Table
+		checks["conversion"] = func(context.Context) error {
+			if watcher.Table() == nil {
+				return errNoConversionTable
+			}
+			return nil
+		}
+	}
+
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware(logger))
+	if features.Metrics {
+		r.Use(calcOptions.Metrics.Middleware)
+	}
 	r.Use(middleware.Recoverer)
 
 	if a.Debug {
-		r.Get("/swagger/*", httpSwagger.Handler(
-		// httpSwagger.URL(fmt.Sprintf("http://localhost:%d/swagger/doc.json", a.Config.Port)),
-		))
+		r.Get("/swagger/*", httpSwagger.Handler())
+	}
+
+	if features.Metrics {
+		r.Get("/metrics", calcOptions.Metrics.Handler())
 	}
+	r.Get("/healthz", handler.HealthHandler)
+	r.Get("/readyz", handler.NewReadyHandler(checks))
+	r.Get("/version", handler.VersionHandler)
+
+	// Requests to API are authenticated when the file of API keys is set
+	var keys *apikeys.Store
+	if a.Config.APIKeysFile != "" {
+		store, err := apikeys.NewStore(a.Config.APIKeysFile, apikeys.DefaultInterval)
+		if err != nil {
+			return nil, fmt.Errorf("load API keys: %w", err)
+		}
+		go store.Run(ctx)
+		keys = store
+	}
+	evaluate := apikeys.RequireScope(apikeys.ScopeEvaluate, handler.ErrorHandler)
+	admin := apikeys.RequireScope(apikeys.ScopeAdmin, handler.ErrorHandler)
+
+	// Groups of endpoints have independent limits of requests
+	apiLimit := rateLimit(a.Config.RateLimit.API, ratelimit.ClientKey)
+	numericLimit := rateLimit(a.Config.RateLimit.Numeric, ratelimit.ClientKey)
 
 	r.Route("/api", func(r chi.Router) {
 		r.Route("/v1", func(r chi.Router) {
-			r.Post("/calculate", handler.CalcHandler)
+			// Failed attempts of authentication are limited by IP
+			if keys != nil {
+				r.Use(rateLimit(a.Config.RateLimit.Auth, ratelimit.IPKey))
+			}
+			r.Use(apikeys.Middleware(keys, handler.ErrorHandler))
+
+			r.Group(func(r chi.Router) {
+				r.Use(apiLimit)
+				r.With(evaluate).Post("/calculate", handler.NewCalcHandler(calcOptions))
+				r.With(evaluate).Get("/calculate", handler.NewCalcQueryHandler(calcOptions))
+				r.With(evaluate).Method(http.MethodGet, "/ws", handler.NewWebSocketHandler(handler.WebSocketOptions{
+					Calc:           calcOptions,
+					MaxMessageSize: a.Config.WebSocket.MaxMessageSize,
+					Rate:           a.Config.WebSocket.Rate.Rate,
+					Burst:          a.Config.WebSocket.Rate.Burst,
+					IdleTimeout:    a.Config.WebSocket.IdleTimeout,
+					AllowedOrigins: a.Config.WebSocket.AllowedOrigins,
+					// Shutdown of the server does not close hijacked connections
+					Done: ctx.Done(),
+				}))
+
+				if features.Functions {
+					r.With(evaluate).Get("/functions", handler.NewListFunctionsHandler(calcOptions.Functions))
+					r.With(admin).Put("/functions/{name}", handler.NewDefineFunctionHandler(calcOptions.Functions, calcOptions.Body))
+					r.With(admin).Delete("/functions/{name}", handler.NewDeleteFunctionHandler(calcOptions.Functions))
+				}
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(numericLimit, evaluate)
+				if features.Numeric {
+					r.Post("/integrate", handler.NewIntegrateHandler(calcOptions))
+					r.Post("/sum", handler.NewSumHandler(calcOptions))
+				}
+				if features.Plot {
+					r.Post("/plot", handler.NewPlotHandler(calcOptions))
+				}
+			})
 		})
 	})
 
-	// mux := http.NewServeMux()
-	// mux.HandleFunc("/api/v1/calculate", handler.CalcHandler)
+	return r, nil
+}
 
-	log.Printf("Ordinary-calc has started without errors on http://127.0.0.1:%d", a.Config.Port)
-	err := http.ListenAndServe(":"+fmt.Sprint(a.Config.Port), r)
+// rateLimit returns the middleware limiting requests of every client
+// identified by the key by the rate. Requests are not limited when the rate
+// is zero
+func rateLimit(rate config.Rate, key ratelimit.KeyFunc) func(http.Handler) http.Handler {
+	var limiter *ratelimit.Limiter
+	if rate.Rate > 0 {
+		limiter = ratelimit.New(rate.Rate, rate.Burst)
+	}
+	return ratelimit.Middleware(limiter, key, handler.ErrorHandler)
+}
 
-	log.Fatal(err)
-	return nil
+// logger returns the logger of application or the default logger
+func (a *Application) logger() *slog.Logger {
+	if a.Logger != nil {
+		return a.Logger
+	}
+	return slog.Default()
 }
@@ -0,0 +1,668 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
+	"github.com/Irurnnen/ordinary-calc/internal/certificates/certtest"
+	"github.com/Irurnnen/ordinary-calc/internal/config"
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/handler"
+	"github.com/Irurnnen/ordinary-calc/internal/logging"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+)
+
+// newTestApplication returns the application with the example config which
+// listens on a random port and writes logs to the buffer
+func newTestApplication(t *testing.T) (*Application, *bytes.Buffer) {
+	t.Helper()
+
+	cfg := config.NewConfigExample()
+	cfg.Port = 0
+	cfg.ShutdownTimeout = 5 * time.Second
+	var logs bytes.Buffer
+	return &Application{Config: *cfg, Logger: logging.New(&logs, slog.LevelInfo)}, &logs
+}
+
+// serve starts the application on a random port. It returns the address of
+// server and the channel with the result of Serve
+func serve(t *testing.T, ctx context.Context, app *Application) (string, <-chan error) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: unexcepted error %q", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Serve(ctx, listener)
+	}()
+	return "http://" + listener.Addr().String(), done
+}
+
+// wait returns the result of Serve or fails when the server does not stop
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+
+	// The server waits 5 seconds for connections on which no request has
+	// been sent, and the client could dial them in advance, so they are closed
+	http.DefaultClient.CloseIdleConnections()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("server has not stopped")
+		return nil
+	}
+}
+
+func TestRunShutdown(t *testing.T) {
+	app, logs := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run: unexcepted error %q", err)
+	}
+	if !strings.Contains(logs.String(), "Ordinary-calc has stopped") {
+		t.Errorf("excepted log of stop, got %s", logs.String())
+	}
+}
+
+func TestRunPortInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Listen: unexcepted error %q", err)
+	}
+	defer listener.Close()
+
+	app, _ := newTestApplication(t)
+	app.Config.Port = listener.Addr().(*net.TCPAddr).Port
+	if err := app.Run(context.Background()); err == nil {
+		t.Errorf("Run: excepted error for port in use")
+	}
+}
+
+func TestServeInvalidRatesFile(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.Config.RatesFile = "not-existing-rates.json"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: unexcepted error %q", err)
+	}
+	if err := app.Serve(context.Background(), listener); err == nil {
+		t.Errorf("Serve: excepted error for invalid rates file")
+	}
+	if _, err := listener.Accept(); err == nil {
+		t.Errorf("excepted listener is closed")
+	}
+}
+
+func TestServeRequests(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+
+	resp, err := http.Post(address+"/api/v1/calculate", "application/json", strings.NewReader(`{"expression": "2 + 2 * 2"}`))
+	if err != nil {
+		t.Fatalf("Post: unexcepted error %q", err)
+	}
+	var result models.Result
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || result.Result == nil || *result.Result != 6 {
+		t.Errorf("excepted 200 with result 6, got %d with %+v", resp.StatusCode, result)
+	}
+
+	cancel()
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Serve: unexcepted error %q", err)
+	}
+
+	// New connections are not accepted after shutdown
+	if _, err := http.Get(address + "/api/v1/functions"); err == nil {
+		t.Errorf("excepted error of request after shutdown")
+	}
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+
+	// The request is in flight until the whole body is written
+	body, writer := io.Pipe()
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Post(address+"/api/v1/calculate", "application/json", body)
+		if err != nil {
+			t.Errorf("Post: unexcepted error %q", err)
+			close(responses)
+			return
+		}
+		responses <- resp
+	}()
+	writer.Write([]byte(`{"expression": `))
+	time.Sleep(50 * time.Millisecond)
+
+	// Shutdown waits for the request
+	cancel()
+	select {
+	case err := <-done:
+		t.Fatalf("server has stopped before the end of request with %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	writer.Write([]byte(`"6 * 7"}`))
+	writer.Close()
+	resp, ok := <-responses
+	if !ok {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	var result models.Result
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK || result.Result == nil || *result.Result != 42 {
+		t.Errorf("excepted 200 with result 42, got %d with %+v", resp.StatusCode, result)
+	}
+
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Serve: unexcepted error %q", err)
+	}
+}
+
+func TestServeProbes(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+	defer func() {
+		cancel()
+		wait(t, done)
+	}()
+
+	cases := []struct {
+		path           string
+		exceptedStatus int
+	}{
+		{"/healthz", http.StatusOK},
+		{"/readyz", http.StatusOK},
+		{"/version", http.StatusOK},
+	}
+	for _, tt := range cases {
+		resp, err := http.Get(address + tt.path)
+		if err != nil {
+			t.Fatalf("Get %s: unexcepted error %q", tt.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.exceptedStatus {
+			t.Errorf("%s: excepted status code %d, got %d", tt.path, tt.exceptedStatus, resp.StatusCode)
+		}
+	}
+
+	if err := app.Healthcheck(context.Background()); err == nil {
+		t.Errorf("Healthcheck: excepted error, the server does not listen on the port of config")
+	}
+	app.Config.Port = portOf(t, address)
+	if err := app.Healthcheck(context.Background()); err != nil {
+		t.Errorf("Healthcheck: unexcepted error %q", err)
+	}
+
+	// The server is not ready while it is shutting down
+	app.shuttingDown.Store(true)
+	resp, err := http.Get(address + "/readyz")
+	if err != nil {
+		t.Fatalf("Get /readyz: unexcepted error %q", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("/readyz: excepted status code %d while shutting down, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+}
+
+// portOf returns the port of the server address
+func portOf(t *testing.T, address string) int {
+	t.Helper()
+
+	_, port, err := net.SplitHostPort(address[strings.Index(address, "://")+3:])
+	if err != nil {
+		t.Fatalf("SplitHostPort: unexcepted error %q", err)
+	}
+	number, _ := strconv.Atoi(port)
+	return number
+}
+
+func TestServeFeatures(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.Config.Features = config.Features{Functions: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+	defer func() {
+		cancel()
+		wait(t, done)
+	}()
+
+	cases := []struct {
+		method         string
+		path           string
+		exceptedStatus int
+	}{
+		{http.MethodGet, "/api/v1/functions", http.StatusOK},
+		{http.MethodGet, "/metrics", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/integrate", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/sum", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/plot", http.StatusNotFound},
+	}
+	for _, tt := range cases {
+		req, _ := http.NewRequest(tt.method, address+tt.path, strings.NewReader("{}"))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: unexcepted error %q", tt.method, tt.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.exceptedStatus {
+			t.Errorf("%s %s: excepted status code %d, got %d", tt.method, tt.path, tt.exceptedStatus, resp.StatusCode)
+		}
+	}
+}
+
+func TestServeMutualTLS(t *testing.T) {
+	authority := certtest.NewAuthority(t, "Test CA")
+	dir := t.TempDir()
+	certFile, keyFile := authority.Issue(t, "server").Write(t, dir)
+	clientCAFile := filepath.Join(dir, "ca.pem")
+	certtest.WriteFile(t, clientCAFile, authority.PEM)
+
+	app, _ := newTestApplication(t)
+	app.Config.TLS = config.TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile}
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+	address = strings.Replace(address, "http://", "https://", 1)
+	defer func() {
+		cancel()
+		wait(t, done)
+	}()
+
+	cases := []struct {
+		name         string
+		certificates []tls.Certificate
+		exceptedOK   bool
+	}{
+		{
+			name:         "Client certificate",
+			certificates: []tls.Certificate{authority.Issue(t, "client").TLSCertificate(t)},
+			exceptedOK:   true,
+		},
+		{
+			name: "Without client certificate",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      authority.Pool(),
+				Certificates: tt.certificates,
+			}}}
+			resp, err := client.Get(address + "/healthz")
+			if err == nil {
+				resp.Body.Close()
+			}
+			if tt.exceptedOK && (err != nil || resp.StatusCode != http.StatusOK) {
+				t.Errorf("Get: excepted 200, got error %v", err)
+			}
+			if !tt.exceptedOK && err == nil {
+				t.Errorf("Get: excepted error of handshake")
+			}
+		})
+	}
+
+	app.Config.Port = portOf(t, address)
+	if err := app.Healthcheck(context.Background()); err != nil {
+		t.Errorf("Healthcheck: unexcepted error %q", err)
+	}
+}
+
+func TestServeInvalidCertificate(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.Config.TLS = config.TLS{CertFile: "not-existing-cert.pem", KeyFile: "not-existing-key.pem"}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: unexcepted error %q", err)
+	}
+	if err := app.Serve(context.Background(), listener); err == nil {
+		t.Errorf("Serve: excepted error for invalid certificate")
+	}
+}
+
+func TestServeRateLimit(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.Config.RateLimit = config.RateLimit{
+		API:     config.Rate{Rate: 0.001, Burst: 1},
+		Numeric: config.Rate{Rate: 0.001, Burst: 1},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+	defer func() {
+		cancel()
+		wait(t, done)
+	}()
+
+	post := func(path, body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(address+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Post %s: unexcepted error %q", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("/api/v1/calculate", `{"expression": "1 + 1"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("excepted status code %d of the first request, got %d", http.StatusOK, resp.StatusCode)
+	}
+	resp := post("/api/v1/calculate", `{"expression": "1 + 1"}`)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("excepted status code %d with Retry-After, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	// Numeric endpoints have the independent limit
+	if resp := post("/api/v1/sum", `{"expression": "n", "variable": "n", "from": 1, "to": 3}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("excepted status code %d of numeric endpoint, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestServeAPIKeys(t *testing.T) {
+	var file apikeys.File
+	now := time.Now()
+	evaluateKey, _, _ := file.Create("evaluate", "team", []string{apikeys.ScopeEvaluate}, now)
+	adminKey, _, _ := file.Create("admin", "team", []string{apikeys.ScopeAdmin}, now)
+	otherKey, _, _ := file.Create("other", "", []string{apikeys.ScopeAdmin}, now)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := file.Write(path); err != nil {
+		t.Fatalf("Write: unexcepted error %q", err)
+	}
+
+	app, _ := newTestApplication(t)
+	app.Config.APIKeysFile = path
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+	defer func() {
+		cancel()
+		wait(t, done)
+	}()
+
+	cases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		headers        map[string]string
+		exceptedStatus int
+	}{
+		{
+			name:           "Without key",
+			method:         http.MethodPost,
+			path:           "/api/v1/calculate",
+			body:           `{"expression": "1 + 1"}`,
+			exceptedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Evaluate key",
+			method:         http.MethodPost,
+			path:           "/api/v1/calculate",
+			body:           `{"expression": "1 + 1"}`,
+			headers:        map[string]string{"X-API-Key": evaluateKey},
+			exceptedStatus: http.StatusOK,
+		},
+		{
+			name:           "Bearer evaluate key",
+			method:         http.MethodPost,
+			path:           "/api/v1/sum",
+			body:           `{"expression": "n", "variable": "n", "from": 1, "to": 3}`,
+			headers:        map[string]string{"Authorization": "Bearer " + evaluateKey},
+			exceptedStatus: http.StatusOK,
+		},
+		{
+			name:           "Evaluate key defines function",
+			method:         http.MethodPut,
+			path:           "/api/v1/functions/double",
+			body:           `{"parameters": ["x"], "expression": "2 * x"}`,
+			headers:        map[string]string{"X-API-Key": evaluateKey},
+			exceptedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Admin key defines function",
+			method:         http.MethodPut,
+			path:           "/api/v1/functions/double",
+			body:           `{"parameters": ["x"], "expression": "2 * x"}`,
+			headers:        map[string]string{"X-API-Key": adminKey},
+			exceptedStatus: http.StatusOK,
+		},
+		{
+			name:           "Key of namespace calls function",
+			method:         http.MethodPost,
+			path:           "/api/v1/calculate",
+			body:           `{"expression": "double(2)"}`,
+			headers:        map[string]string{"X-API-Key": evaluateKey},
+			exceptedStatus: http.StatusOK,
+		},
+		{
+			name:           "Key of other namespace deletes function",
+			method:         http.MethodDelete,
+			path:           "/api/v1/functions/double",
+			headers:        map[string]string{"X-API-Key": otherKey, "X-Namespace": "team"},
+			exceptedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Probes without key",
+			method:         http.MethodGet,
+			path:           "/healthz",
+			exceptedStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, address+tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: unexcepted error %q", tt.method, tt.path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.exceptedStatus {
+				t.Errorf("%s %s: excepted status code %d, got %d", tt.method, tt.path, tt.exceptedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	// Browsers send the key in the subprotocol of WebSocket
+	wsConfig, _ := websocket.NewConfig(strings.Replace(address, "http://", "ws://", 1)+"/api/v1/ws", address)
+	for _, tt := range []struct {
+		name      string
+		protocols []string
+		excepted  bool
+	}{
+		{"WebSocket without key", []string{handler.WebSocketProtocol}, false},
+		{"WebSocket key in subprotocol", []string{handler.WebSocketProtocol, apikeys.ProtocolPrefix + evaluateKey}, true},
+	} {
+		wsConfig.Protocol = tt.protocols
+		conn, err := websocket.DialConfig(wsConfig)
+		if (err == nil) != tt.excepted {
+			t.Errorf("%s: excepted connection %v, got error %v", tt.name, tt.excepted, err)
+		}
+		if err == nil {
+			conn.Close()
+		}
+	}
+}
+
+func TestServeAuthRateLimit(t *testing.T) {
+	var file apikeys.File
+	key, _, _ := file.Create("evaluate", "", []string{apikeys.ScopeEvaluate}, time.Now())
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := file.Write(path); err != nil {
+		t.Fatalf("Write: unexcepted error %q", err)
+	}
+
+	app, _ := newTestApplication(t)
+	app.Config.APIKeysFile = path
+	app.Config.RateLimit.Auth = config.Rate{Rate: 0.001, Burst: 2}
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+	defer func() {
+		cancel()
+		wait(t, done)
+	}()
+
+	post := func(key string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, address+"/api/v1/calculate", strings.NewReader(`{"expression": "1 + 1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Post: unexcepted error %q", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Failed attempts take tokens of the IP
+	if resp := post("ock_0000000000000000_guess"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("excepted status code %d of invalid key, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	if resp := post(key); resp.StatusCode != http.StatusOK {
+		t.Fatalf("excepted status code %d of valid key, got %d", http.StatusOK, resp.StatusCode)
+	}
+	resp := post("ock_0000000000000000_guess")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("excepted status code %d with Retry-After, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+}
+
+func TestServeCache(t *testing.T) {
+	cases := []struct {
+		name          string
+		size          int
+		exceptedCache []string
+	}{
+		{"Enabled cache", 10, []string{"MISS", "HIT"}},
+		{"Disabled cache", 0, []string{"", ""}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newTestApplication(t)
+			app.Config.Cache.Size = tt.size
+			ctx, cancel := context.WithCancel(context.Background())
+			address, done := serve(t, ctx, app)
+			defer func() {
+				cancel()
+				wait(t, done)
+			}()
+
+			for i, expression := range []string{"2 + 2", "2+2"} {
+				resp, err := http.Post(address+"/api/v1/calculate", "application/json", strings.NewReader(`{"expression": "`+expression+`"}`))
+				if err != nil {
+					t.Fatalf("Post: unexcepted error %q", err)
+				}
+				resp.Body.Close()
+				if got := resp.Header.Get("X-Cache"); resp.StatusCode != http.StatusOK || got != tt.exceptedCache[i] {
+					t.Errorf("request %d: excepted 200 with X-Cache %q, got %d with %q", i, tt.exceptedCache[i], resp.StatusCode, got)
+				}
+			}
+		})
+	}
+}
+
+func TestServeConditionalRequests(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+	defer func() {
+		cancel()
+		wait(t, done)
+	}()
+
+	get := func(etag string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, address+"/api/v1/calculate?expression=2%2B2", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Get: unexcepted error %q", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" || resp.Header.Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("excepted 200 with ETag and Cache-Control, got %d with %v", resp.StatusCode, resp.Header)
+	}
+	if resp := get(etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("excepted status code %d, got %d", http.StatusNotModified, resp.StatusCode)
+	}
+}
+
+func TestServeWebSocket(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	address, done := serve(t, ctx, app)
+
+	conn, err := websocket.Dial(strings.Replace(address, "http://", "ws://", 1)+"/api/v1/ws", "", address)
+	if err != nil {
+		cancel()
+		wait(t, done)
+		t.Fatalf("Dial: unexcepted error %q", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var response models.Message
+	if err := websocket.JSON.Send(conn, forms.Message{ID: 1, Expression: forms.Expression{Expression: "2 + 2"}}); err != nil {
+		t.Fatalf("Send: unexcepted error %q", err)
+	}
+	if err := websocket.JSON.Receive(conn, &response); err != nil {
+		t.Fatalf("Receive: unexcepted error %q", err)
+	}
+	if response.ID != 1 || response.Result == nil || response.Result.Result == nil || *response.Result.Result != 4 {
+		t.Errorf("excepted the result 4 of message 1, got %+v", response)
+	}
+
+	// Shutdown closes the open connection
+	cancel()
+	if err := wait(t, done); err != nil {
+		t.Errorf("Serve: unexcepted error %q", err)
+	}
+	if err := websocket.JSON.Receive(conn, &response); err == nil {
+		t.Errorf("excepted the connection is closed, got %+v", response)
+	}
+}
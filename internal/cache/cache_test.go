@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	type operation struct {
+		// after is the time since the previous operation
+		after time.Duration
+		// add puts the value of key when it is set, otherwise the value is got
+		add           bool
+		key           string
+		value         int
+		exceptedValue int
+		exceptedOK    bool
+	}
+	cases := []struct {
+		name       string
+		size       int
+		ttl        time.Duration
+		operations []operation
+	}{
+		{
+			name: "Get added value",
+			size: 2,
+			operations: []operation{
+				{key: "a", exceptedOK: false},
+				{add: true, key: "a", value: 1},
+				{key: "a", exceptedValue: 1, exceptedOK: true},
+				{add: true, key: "a", value: 2},
+				{key: "a", exceptedValue: 2, exceptedOK: true},
+			},
+		},
+		{
+			name: "Evict least recently used",
+			size: 2,
+			operations: []operation{
+				{add: true, key: "a", value: 1},
+				{add: true, key: "b", value: 2},
+				{key: "a", exceptedValue: 1, exceptedOK: true},
+				{add: true, key: "c", value: 3},
+				{key: "b", exceptedOK: false},
+				{key: "a", exceptedValue: 1, exceptedOK: true},
+				{key: "c", exceptedValue: 3, exceptedOK: true},
+			},
+		},
+		{
+			name: "Expire after TTL",
+			size: 2,
+			ttl:  time.Minute,
+			operations: []operation{
+				{add: true, key: "a", value: 1},
+				{after: 59 * time.Second, key: "a", exceptedValue: 1, exceptedOK: true},
+				{after: time.Second, key: "a", exceptedOK: false},
+				{add: true, key: "a", value: 2},
+				{after: 30 * time.Second, key: "a", exceptedValue: 2, exceptedOK: true},
+			},
+		},
+		{
+			name: "Without TTL",
+			size: 1,
+			operations: []operation{
+				{add: true, key: "a", value: 1},
+				{after: 24 * time.Hour, key: "a", exceptedValue: 1, exceptedOK: true},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+			cache := New[int](tt.size, tt.ttl)
+			cache.now = func() time.Time { return now }
+
+			for i, op := range tt.operations {
+				now = now.Add(op.after)
+				if op.add {
+					cache.Add(op.key, op.value)
+					continue
+				}
+				value, ok := cache.Get(op.key)
+				if value != op.exceptedValue || ok != op.exceptedOK {
+					t.Errorf("operation %d: excepted %d, %v, got %d, %v", i, op.exceptedValue, op.exceptedOK, value, ok)
+				}
+			}
+			if cache.Len() > tt.size {
+				t.Errorf("excepted at most %d values, got %d", tt.size, cache.Len())
+			}
+		})
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	cache := New[int](10, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				key := strconv.Itoa((i + j) % 20)
+				cache.Add(key, j)
+				cache.Get(key)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cache.Len() != 10 {
+		t.Errorf("excepted 10 values, got %d", cache.Len())
+	}
+}
@@ -0,0 +1,160 @@
+// Package certtest generates certificates for tests of TLS servers and clients
+package certtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Authority is the self-signed certificate authority
+type Authority struct {
+	Certificate *x509.Certificate
+	// PEM is the certificate of authority in PEM format
+	PEM []byte
+
+	key *ecdsa.PrivateKey
+}
+
+// Pair is the certificate with its private key in PEM format
+type Pair struct {
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// NewAuthority returns the new self-signed certificate authority
+func NewAuthority(t testing.TB, name string) *Authority {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(t),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: unexcepted error %q", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: unexcepted error %q", err)
+	}
+	return &Authority{
+		Certificate: certificate,
+		PEM:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:         key,
+	}
+}
+
+// Issue returns the certificate signed by the authority which is valid for
+// servers and clients on localhost and 127.0.0.1
+func (a *Authority) Issue(t testing.TB, name string) Pair {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(t),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.Certificate, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: unexcepted error %q", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: unexcepted error %q", err)
+	}
+	return Pair{
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// Pool returns the pool with the certificate of authority
+func (a *Authority) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.Certificate)
+	return pool
+}
+
+// TLSCertificate returns the pair for the config of TLS client or server
+func (p Pair) TLSCertificate(t testing.TB) tls.Certificate {
+	t.Helper()
+
+	certificate, err := tls.X509KeyPair(p.CertPEM, p.KeyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair: unexcepted error %q", err)
+	}
+	return certificate
+}
+
+// Write writes the pair to the files cert.pem and key.pem in the directory and
+// returns their paths
+func (p Pair) Write(t testing.TB, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	WriteFile(t, certFile, p.CertPEM)
+	WriteFile(t, keyFile, p.KeyPEM)
+	return certFile, keyFile
+}
+
+// WriteFile writes the data to the file. The time of modification is moved
+// forward, so watchers see the change even on file systems with coarse time
+func WriteFile(t testing.TB, path string, data []byte) {
+	t.Helper()
+
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime().Add(time.Second)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: unexcepted error %q", err)
+	}
+	if !modTime.IsZero() {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Chtimes: unexcepted error %q", err)
+		}
+	}
+}
+
+func newKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: unexcepted error %q", err)
+	}
+	return key
+}
+
+func serialNumber(t testing.TB) *big.Int {
+	t.Helper()
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatalf("rand.Int: unexcepted error %q", err)
+	}
+	return serial
+}
@@ -0,0 +1,131 @@
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/watch"
+)
+
+// DefaultInterval is the period of checking files of certificates for changes
+const DefaultInterval = watch.DefaultInterval
+
+// ErrNoCertificates is returned for the file of client authorities without certificates
+var ErrNoCertificates = errors.New("no PEM certificates found")
+
+// Watcher keeps the certificate of server and authorities of client
+// certificates up to date with files on disk, so certificates are renewed
+// without restart of the server
+type Watcher struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	certificate atomic.Pointer[tls.Certificate]
+	clientCAs   atomic.Pointer[x509.CertPool]
+
+	// watchers reload the certificate with the key and the client
+	// authorities independently
+	watchers []*watch.Watcher
+}
+
+// NewWatcher loads the certificate with the key and the client authorities
+// when clientCAFile is not empty. Files are reloaded by Run when they change
+func NewWatcher(certFile, keyFile, clientCAFile string, interval time.Duration) (*Watcher, error) {
+	w := &Watcher{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+	watcher, err := watch.New("certificate", interval, w.reloadCertificate, certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	w.watchers = append(w.watchers, watcher)
+	if clientCAFile != "" {
+		watcher, err := watch.New("client authorities", interval, w.reloadClientCAs, clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		w.watchers = append(w.watchers, watcher)
+	}
+	return w, nil
+}
+
+// Certificate returns the last successfully loaded certificate of server
+func (w *Watcher) Certificate() *tls.Certificate {
+	return w.certificate.Load()
+}
+
+// ClientCAs returns the last successfully loaded client authorities or nil
+// when they are not configured
+func (w *Watcher) ClientCAs() *x509.CertPool {
+	return w.clientCAs.Load()
+}
+
+// TLSConfig returns the config of server which takes the current
+// certificates on every handshake. Clients must present the certificate
+// signed by the client authorities when they are configured
+func (w *Watcher) TLSConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return w.Certificate(), nil
+		},
+	}
+	if w.clientCAFile == "" {
+		return config
+	}
+
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		handshake := config.Clone()
+		handshake.ClientCAs = w.ClientCAs()
+		handshake.GetConfigForClient = nil
+		return handshake, nil
+	}
+	return config
+}
+
+// Run checks files for changes until the context is done. Previous
+// certificates are kept if changed files could not be loaded
+func (w *Watcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, watcher := range w.watchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watcher.Run(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Watcher) reloadCertificate() error {
+	certificate, err := tls.LoadX509KeyPair(w.certFile, w.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate %s with key %s: %w", w.certFile, w.keyFile, err)
+	}
+	w.certificate.Store(&certificate)
+	return nil
+}
+
+func (w *Watcher) reloadClientCAs() error {
+	data, err := os.ReadFile(w.clientCAFile)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("load client authorities %s: %w", w.clientCAFile, ErrNoCertificates)
+	}
+	w.clientCAs.Store(pool)
+	return nil
+}
@@ -0,0 +1,203 @@
+package certificates
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/certificates/certtest"
+)
+
+func TestNewWatcherErrors(t *testing.T) {
+	authority := certtest.NewAuthority(t, "Test CA")
+	first, second := authority.Issue(t, "first"), authority.Issue(t, "second")
+
+	cases := []struct {
+		name     string
+		cert     []byte
+		key      []byte
+		clientCA []byte
+		// exceptedErr is checked by errors.Is when it is not nil
+		exceptedErr error
+	}{
+		{
+			name: "Key of other certificate",
+			cert: first.CertPEM,
+			key:  second.KeyPEM,
+		},
+		{
+			name: "Not PEM certificate",
+			cert: []byte("certificate"),
+			key:  first.KeyPEM,
+		},
+		{
+			name:        "Client authorities without certificates",
+			cert:        first.CertPEM,
+			key:         first.KeyPEM,
+			clientCA:    []byte("authority"),
+			exceptedErr: ErrNoCertificates,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			certFile, keyFile := certtest.Pair{CertPEM: tt.cert, KeyPEM: tt.key}.Write(t, dir)
+			var clientCAFile string
+			if tt.clientCA != nil {
+				clientCAFile = filepath.Join(dir, "ca.pem")
+				certtest.WriteFile(t, clientCAFile, tt.clientCA)
+			}
+
+			_, err := NewWatcher(certFile, keyFile, clientCAFile, DefaultInterval)
+			if err == nil {
+				t.Fatalf("NewWatcher: excepted error")
+			}
+			if tt.exceptedErr != nil && !errors.Is(err, tt.exceptedErr) {
+				t.Errorf("excepted error %q, got %q", tt.exceptedErr, err)
+			}
+		})
+	}
+
+	if _, err := NewWatcher(filepath.Join(t.TempDir(), "cert.pem"), "key.pem", "", DefaultInterval); err == nil {
+		t.Errorf("NewWatcher: excepted error for missing files")
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	authority := certtest.NewAuthority(t, "Test CA")
+	dir := t.TempDir()
+	certFile, keyFile := authority.Issue(t, "first").Write(t, dir)
+
+	watcher, err := NewWatcher(certFile, keyFile, "", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewWatcher: unexcepted error %q", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	// Certificate is not replaced until the key matches it
+	first := watcher.Certificate()
+	second := authority.Issue(t, "second")
+	certtest.WriteFile(t, certFile, second.CertPEM)
+	time.Sleep(50 * time.Millisecond)
+	if watcher.Certificate() != first {
+		t.Fatalf("Watcher: certificate is replaced by the certificate with other key")
+	}
+
+	certtest.WriteFile(t, keyFile, second.KeyPEM)
+	deadline := time.Now().Add(time.Second)
+	for watcher.Certificate() == first && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := watcher.Certificate(); !bytes.Equal(got.Certificate[0], second.TLSCertificate(t).Certificate[0]) {
+		t.Errorf("Watcher: excepted the second certificate after reload")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	authority := certtest.NewAuthority(t, "Test CA")
+	server := authority.Issue(t, "server")
+	client := authority.Issue(t, "client")
+	other := certtest.NewAuthority(t, "Other CA").Issue(t, "other")
+
+	dir := t.TempDir()
+	certFile, keyFile := server.Write(t, dir)
+	clientCAFile := filepath.Join(dir, "ca.pem")
+	certtest.WriteFile(t, clientCAFile, authority.PEM)
+
+	cases := []struct {
+		name         string
+		clientCAFile string
+		certificates []tls.Certificate
+		exceptedOK   bool
+	}{
+		{
+			name:       "TLS",
+			exceptedOK: true,
+		},
+		{
+			name:         "Mutual TLS",
+			clientCAFile: clientCAFile,
+			certificates: []tls.Certificate{client.TLSCertificate(t)},
+			exceptedOK:   true,
+		},
+		{
+			name:         "Mutual TLS without client certificate",
+			clientCAFile: clientCAFile,
+		},
+		{
+			name:         "Mutual TLS with certificate of other authority",
+			clientCAFile: clientCAFile,
+			certificates: []tls.Certificate{other.TLSCertificate(t)},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher, err := NewWatcher(certFile, keyFile, tt.clientCAFile, DefaultInterval)
+			if err != nil {
+				t.Fatalf("NewWatcher: unexcepted error %q", err)
+			}
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			ts.Listener = tls.NewListener(ts.Listener, watcher.TLSConfig())
+			ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+			ts.Start()
+			defer ts.Close()
+
+			httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      authority.Pool(),
+				Certificates: tt.certificates,
+			}}}
+			resp, err := httpClient.Get("https://" + ts.Listener.Addr().String())
+			if err == nil {
+				resp.Body.Close()
+			}
+			if tt.exceptedOK && err != nil {
+				t.Errorf("Get: unexcepted error %q", err)
+			}
+			if !tt.exceptedOK && err == nil {
+				t.Errorf("Get: excepted error of handshake")
+			}
+		})
+	}
+}
+
+func TestClientCAsReload(t *testing.T) {
+	first, second := certtest.NewAuthority(t, "First CA"), certtest.NewAuthority(t, "Second CA")
+	dir := t.TempDir()
+	certFile, keyFile := first.Issue(t, "server").Write(t, dir)
+	clientCAFile := filepath.Join(dir, "ca.pem")
+	certtest.WriteFile(t, clientCAFile, first.PEM)
+
+	watcher, err := NewWatcher(certFile, keyFile, clientCAFile, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewWatcher: unexcepted error %q", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	pool := watcher.ClientCAs()
+	certtest.WriteFile(t, clientCAFile, second.PEM)
+	deadline := time.Now().Add(time.Second)
+	for watcher.ClientCAs() == pool && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Certificates of the second authority are accepted after reload
+	client := second.Issue(t, "client").TLSCertificate(t)
+	leaf, _ := x509.ParseCertificate(client.Certificate[0])
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: watcher.ClientCAs(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	if err != nil {
+		t.Errorf("Verify: unexcepted error %q after reload of client authorities", err)
+	}
+}
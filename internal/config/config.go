@@ -1,28 +1,368 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
-	"strconv"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+// DefaultPort is used when the port of HTTP server is not set
+const DefaultPort = 8080
+
+// DefaultCalcTimeout is used when the timeout of calculation is not set
+const DefaultCalcTimeout = 5 * time.Second
+
+// DefaultMaxBodySize is used when the maximum size of request body is not set
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultLimits are used when the limits of expressions are not set
+var DefaultLimits = calc.Options{
+	MaxLength: 10000,
+	MaxTokens: 5000,
+	MaxDepth:  100,
+	MaxSteps:  100000,
+	// Integration and summation evaluate the expression many times
+	MaxIterations: 100000,
+}
+
+// DefaultFunctionLimits are used when the limits of user-defined functions
+// are not set
+var DefaultFunctionLimits = functions.Limits{
+	MaxFunctions:  100,
+	MaxNamespaces: 1000,
+}
+
+// Default timeouts of the HTTP server
+const (
+	DefaultReadTimeout     = 10 * time.Second
+	DefaultWriteTimeout    = 30 * time.Second
+	DefaultIdleTimeout     = 60 * time.Second
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+// Default size of the cache of results, the time of keeping them and the
+// time they are fresh in caches of clients
+const (
+	DefaultCacheSize   = 1000
+	DefaultCacheTTL    = time.Minute
+	DefaultCacheMaxAge = time.Minute
+)
+
+// Default limits of every WebSocket connection
+const (
+	DefaultWebSocketIdleTimeout    = 60 * time.Second
+	DefaultWebSocketMaxMessageSize = 64 << 10
 )
 
 type Config struct {
+	// Host is the address of interface the server listens on. The server
+	// listens on all interfaces when it is empty
+	Host string
 	Port int
+	// RatesFile is the path to the JSON or YAML file with currency rates and
+	// custom units. Conversions are disabled when it is empty
+	RatesFile string
+	// CalcTimeout limits the time of calculation of a single expression
+	CalcTimeout time.Duration
+	// MaxBodySize is the maximum size of request body in bytes
+	MaxBodySize int64
+	// Limits are the limits of expressions, zero disables the limit
+	Limits calc.Options
+	// FunctionLimits are the limits of user-defined functions kept in
+	// memory, zero disables the limit
+	FunctionLimits functions.Limits
+	// LogLevel is the minimum level of logged records
+	LogLevel slog.Level
+	// LogExpressions adds the text of expressions to the logs of calculations
+	LogExpressions bool
+	// TracingEndpoint is the URL of OTLP/HTTP collector receiving spans.
+	// Spans are not exported when it is empty
+	TracingEndpoint string
+	// ReadTimeout limits the time of reading the whole request
+	ReadTimeout time.Duration
+	// WriteTimeout limits the time from the end of reading the request
+	// headers to the end of writing the response
+	WriteTimeout time.Duration
+	// IdleTimeout limits the time of waiting for the next request on keep-alive connections
+	IdleTimeout time.Duration
+	// ShutdownTimeout limits the time of waiting for in-flight requests on shutdown
+	ShutdownTimeout time.Duration
+	// TLS configures HTTPS. The server uses plain HTTP when it is not set
+	TLS TLS
+	// Features enable optional parts of API
+	Features Features
+	// RateLimit limits requests of every client to API
+	RateLimit RateLimit
+	// APIKeysFile is the JSON file with hashed API keys managed by the apikey
+	// command. Requests to API are not authenticated when it is empty
+	APIKeysFile string
+	// Cache keeps results of repeated expressions
+	Cache Cache
+	// WebSocket limits every connection to the WebSocket endpoint
+	WebSocket WebSocket
+}
+
+// TLS are the files of certificates in PEM format
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the file of certificates of authorities verifying the
+	// certificates of clients. Certificates of clients are not requested when
+	// it is empty
+	ClientCAFile string
 }
 
+// Enabled reports whether the server uses HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// Features enable optional parts of API
+type Features struct {
+	// Metrics enables the /metrics endpoint
+	Metrics bool
+	// Functions enables the API of user-defined functions
+	Functions bool
+	// Numeric enables numerical integration and summation
+	Numeric bool
+	// Plot enables sampling of expressions for plots
+	Plot bool
+}
+
+// Rate is the rate of requests per second with the burst of requests which
+// could be done at once. Zero rate disables the limit
+type Rate struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimit are independent limits of groups of endpoints, so expensive
+// requests do not exhaust the limit of cheap ones
+type RateLimit struct {
+	// Auth limits requests of every IP before authentication by API keys, so
+	// failed attempts to guess keys are limited too
+	Auth Rate
+	// API limits calculation of expressions and user-defined functions
+	API Rate
+	// Numeric limits integration, summation and plots
+	Numeric Rate
+}
+
+// Cache is the LRU cache of results of calculation. Zero size disables the
+// cache, zero TTL keeps results until they are evicted
+type Cache struct {
+	Size int
+	TTL  time.Duration
+	// MaxAge is the time results of GET requests are fresh in caches of
+	// clients and proxies. Zero makes them revalidate results on every request
+	MaxAge time.Duration
+}
+
+// WebSocket are the limits of every connection. Messages are limited by
+// the Rate independently of the limits of requests, zero IdleTimeout keeps
+// idle connections open
+type WebSocket struct {
+	Rate        Rate
+	IdleTimeout time.Duration
+	// MaxMessageSize is the maximum size of message in bytes
+	MaxMessageSize int
+	// AllowedOrigins are origins of pages which could open connections in
+	// addition to the origin of the server, "*" allows any origin
+	AllowedOrigins []string
+}
+
+// NewConfigExample returns the default config
 func NewConfigExample() *Config {
 	return &Config{
-		Port: 8080,
+		Port:            DefaultPort,
+		CalcTimeout:     DefaultCalcTimeout,
+		MaxBodySize:     DefaultMaxBodySize,
+		Limits:          DefaultLimits,
+		FunctionLimits:  DefaultFunctionLimits,
+		LogLevel:        slog.LevelInfo,
+		ReadTimeout:     DefaultReadTimeout,
+		WriteTimeout:    DefaultWriteTimeout,
+		IdleTimeout:     DefaultIdleTimeout,
+		ShutdownTimeout: DefaultShutdownTimeout,
+		Features: Features{
+			Metrics:   true,
+			Functions: true,
+			Numeric:   true,
+			Plot:      true,
+		},
+		RateLimit: RateLimit{
+			Auth:    Rate{Rate: 20, Burst: 40},
+			API:     Rate{Rate: 10, Burst: 20},
+			Numeric: Rate{Rate: 1, Burst: 5},
+		},
+		Cache: Cache{Size: DefaultCacheSize, TTL: DefaultCacheTTL, MaxAge: DefaultCacheMaxAge},
+		WebSocket: WebSocket{
+			Rate:           Rate{Rate: 10, Burst: 20},
+			IdleTimeout:    DefaultWebSocketIdleTimeout,
+			MaxMessageSize: DefaultWebSocketMaxMessageSize,
+		},
 	}
 }
 
-func NewConfigFromEnv() *Config {
-	port, err := strconv.Atoi(os.Getenv("PORT"))
+// Validate returns the error describing all invalid values of config
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", describe(key), fmt.Sprintf(format, args...)))
+	}
+
+	if c.Host != "" && net.ParseIP(c.Host) == nil {
+		if _, _, err := net.SplitHostPort(c.Host); err == nil {
+			invalid("host", "must not contain the port, got %q", c.Host)
+		}
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		invalid("port", "must be between 1 and 65535, got %d", c.Port)
+	}
+	if c.RatesFile != "" {
+		if err := checkFile(c.RatesFile); err != nil {
+			invalid("rates_file", "%s", err)
+		}
+	}
+
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"timeouts.calc", c.CalcTimeout},
+		{"timeouts.read", c.ReadTimeout},
+		{"timeouts.write", c.WriteTimeout},
+		{"timeouts.idle", c.IdleTimeout},
+		{"timeouts.shutdown", c.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			invalid(timeout.key, "must be positive, got %s", timeout.value)
+		}
+	}
+	if c.CalcTimeout > 0 && c.WriteTimeout > 0 && c.WriteTimeout <= c.CalcTimeout {
+		invalid("timeouts.write", "must be longer than the timeout of calculation %s, got %s", c.CalcTimeout, c.WriteTimeout)
+	}
+
+	if c.MaxBodySize <= 0 {
+		invalid("limits.max_body_size", "must be positive, got %d", c.MaxBodySize)
+	}
+	limits := []struct {
+		key   string
+		value int
+	}{
+		{"limits.max_length", c.Limits.MaxLength},
+		{"limits.max_tokens", c.Limits.MaxTokens},
+		{"limits.max_depth", c.Limits.MaxDepth},
+		{"limits.max_steps", c.Limits.MaxSteps},
+		{"limits.max_iterations", c.Limits.MaxIterations},
+		{"functions.max_per_namespace", c.FunctionLimits.MaxFunctions},
+		{"functions.max_namespaces", c.FunctionLimits.MaxNamespaces},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			invalid(limit.key, "must not be negative, got %d", limit.value)
+		}
+	}
+
+	rates := []struct {
+		key  string
+		rate Rate
+	}{
+		{"rate_limit.auth", c.RateLimit.Auth},
+		{"rate_limit.api", c.RateLimit.API},
+		{"rate_limit.numeric", c.RateLimit.Numeric},
+		{"websocket.rate_limit", c.WebSocket.Rate},
+	}
+	for _, rate := range rates {
+		if rate.rate.Rate < 0 {
+			invalid(rate.key+".rate", "must not be negative, got %g", rate.rate.Rate)
+		}
+		if rate.rate.Rate > 0 && rate.rate.Burst < 1 {
+			invalid(rate.key+".burst", "must be at least 1, got %d", rate.rate.Burst)
+		}
+	}
+
+	if c.Cache.Size < 0 {
+		invalid("cache.size", "must not be negative, got %d", c.Cache.Size)
+	}
+	if c.Cache.TTL < 0 {
+		invalid("cache.ttl", "must not be negative, got %s", c.Cache.TTL)
+	}
+	if c.Cache.MaxAge < 0 {
+		invalid("cache.max_age", "must not be negative, got %s", c.Cache.MaxAge)
+	}
+
+	if c.WebSocket.IdleTimeout < 0 {
+		invalid("websocket.idle_timeout", "must not be negative, got %s", c.WebSocket.IdleTimeout)
+	}
+	if c.WebSocket.MaxMessageSize <= 0 {
+		invalid("websocket.max_message_size", "must be positive, got %d", c.WebSocket.MaxMessageSize)
+	}
+	for _, origin := range c.WebSocket.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if origin != "*" && (err != nil || u.Scheme == "" || u.Host == "" || u.Path != "") {
+			invalid("websocket.allowed_origins", "must be origins like https://example.com or *, got %q", origin)
+		}
+	}
+
+	if c.APIKeysFile != "" {
+		if err := checkFile(c.APIKeysFile); err != nil {
+			invalid("auth.keys_file", "%s", err)
+		}
+	}
+
+	if c.TracingEndpoint != "" {
+		u, err := url.Parse(c.TracingEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("tracing.endpoint", "must be the http or https URL, got %q", c.TracingEndpoint)
+		}
+	}
+
+	switch {
+	case c.TLS.CertFile == "" && c.TLS.KeyFile != "":
+		invalid("tls.cert_file", "must be set with the key file")
+	case c.TLS.CertFile != "" && c.TLS.KeyFile == "":
+		invalid("tls.key_file", "must be set with the certificate file")
+	}
+	if c.TLS.ClientCAFile != "" && !c.TLS.Enabled() {
+		invalid("tls.client_ca_file", "requires the certificate and the key of server")
+	}
+	tlsFiles := []struct {
+		key  string
+		path string
+	}{
+		{"tls.cert_file", c.TLS.CertFile},
+		{"tls.key_file", c.TLS.KeyFile},
+		{"tls.client_ca_file", c.TLS.ClientCAFile},
+	}
+	for _, file := range tlsFiles {
+		if file.path == "" {
+			continue
+		}
+		if err := checkFile(file.path); err != nil {
+			invalid(file.key, "%s", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkFile returns the error when the path is not the regular file
+func checkFile(path string) error {
+	info, err := os.Stat(path)
 	if err != nil {
-		log.Fatalf("Fatal error while getting config from env: PORT:%s", os.Getenv("PORT"))
-		return NewConfigExample()
+		return err
 	}
-	return &Config{
-		Port: port,
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
 	}
+	return nil
 }
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name string
+		// files are written to the temporary directory, "{dir}" in args, env
+		// and files is replaced with its path
+		files map[string]string
+		args  []string
+		env   map[string]string
+		// excepted changes the default config
+		excepted       func(c *Config)
+		exceptedErrors []string
+	}{
+		{
+			name:     "Defaults",
+			excepted: func(c *Config) {},
+		},
+		{
+			name: "YAML file",
+			files: map[string]string{"config.yaml": `
+host: 127.0.0.1
+port: 9090
+timeouts:
+  calc: 2s
+  shutdown: 1m
+limits:
+  max_body_size: 4096
+  max_depth: 0
+log:
+  level: debug
+  expressions: true
+features:
+  plot: false
+`},
+			args: []string{"-config", "{dir}/config.yaml", "-numeric-rate-limit", "0.5"},
+			excepted: func(c *Config) {
+				c.Host, c.Port = "127.0.0.1", 9090
+				c.CalcTimeout, c.ShutdownTimeout = 2*time.Second, time.Minute
+				c.MaxBodySize, c.Limits.MaxDepth = 4096, 0
+				c.LogLevel, c.LogExpressions = slog.LevelDebug, true
+				c.Features.Plot = false
+				c.RateLimit.Numeric.Rate = 0.5
+			},
+		},
+		{
+			name:     "Example file",
+			args:     []string{"-config", "../../config.example.yaml"},
+			excepted: func(c *Config) { c.Host = "0.0.0.0" },
+		},
+		{
+			name:  "JSON file from env",
+			files: map[string]string{"config.json": `{"port": 9090, "limits": {"max_body_size": 2048}, "tracing": {"endpoint": "http://collector:4318"}}`},
+			env:   map[string]string{"CONFIG_FILE": "{dir}/config.json", "CACHE_TTL": "30s"},
+			excepted: func(c *Config) {
+				c.Port, c.MaxBodySize, c.TracingEndpoint = 9090, 2048, "http://collector:4318"
+				c.Cache.TTL = 30 * time.Second
+			},
+		},
+		{
+			name:  "Env overrides file",
+			files: map[string]string{"config.yaml": "port: 9090\nlog:\n  level: warn\n"},
+			env:   map[string]string{"CONFIG_FILE": "{dir}/config.yaml", "PORT": "9091", "FEATURE_METRICS": "false"},
+			excepted: func(c *Config) {
+				c.Port, c.LogLevel, c.Features.Metrics = 9091, slog.LevelWarn, false
+			},
+		},
+		{
+			name:  "Flags override env",
+			files: map[string]string{"config.yaml": "port: 9090\n"},
+			args:  []string{"-config={dir}/config.yaml", "-port", "9092", "-log-expressions", "-max-steps=10"},
+			env:   map[string]string{"PORT": "9091", "LOG_EXPRESSIONS": "false", "MAX_STEPS": "20"},
+			excepted: func(c *Config) {
+				c.Port, c.LogExpressions, c.Limits.MaxSteps = 9092, true, 10
+			},
+		},
+		{
+			name: "WebSocket origins",
+			env:  map[string]string{"WS_ALLOWED_ORIGINS": "https://calc.example.com, http://localhost:3000,"},
+			excepted: func(c *Config) {
+				c.WebSocket.AllowedOrigins = []string{"https://calc.example.com", "http://localhost:3000"}
+			},
+		},
+		{
+			name:     "Empty env is ignored",
+			env:      map[string]string{"PORT": "", "CALC_TIMEOUT": ""},
+			excepted: func(c *Config) {},
+		},
+		{
+			name: "TLS",
+			files: map[string]string{
+				"server.crt": "certificate",
+				"server.key": "key",
+				"ca.crt":     "authority",
+			},
+			env: map[string]string{"TLS_CERT_FILE": "{dir}/server.crt", "TLS_KEY_FILE": "{dir}/server.key", "TLS_CLIENT_CA_FILE": "{dir}/ca.crt"},
+			excepted: func(c *Config) {
+				c.TLS = TLS{CertFile: "{dir}/server.crt", KeyFile: "{dir}/server.key", ClientCAFile: "{dir}/ca.crt"}
+			},
+		},
+		{
+			name:           "Invalid values of env and flags",
+			args:           []string{"-calc-timeout", "soon"},
+			env:            map[string]string{"PORT": "http", "FEATURE_PLOT": "maybe"},
+			exceptedErrors: []string{`env PORT: invalid integer "http"`, `env FEATURE_PLOT: invalid boolean "maybe"`, `flag -calc-timeout: invalid duration "soon"`},
+		},
+		{
+			name:           "Unknown key of file",
+			files:          map[string]string{"config.yaml": "port: 9090\ntimeouts:\n  calculation: 1s\n"},
+			args:           []string{"-config", "{dir}/config.yaml"},
+			exceptedErrors: []string{`unknown key "timeouts.calculation"`},
+		},
+		{
+			name:           "Invalid value of file",
+			files:          map[string]string{"config.json": `{"limits": {"max_tokens": "many"}}`},
+			args:           []string{"-config", "{dir}/config.json"},
+			exceptedErrors: []string{`limits.max_tokens: invalid integer "many"`},
+		},
+		{
+			name:           "Unknown format of file",
+			files:          map[string]string{"config.toml": "port = 9090"},
+			args:           []string{"-config", "{dir}/config.toml"},
+			exceptedErrors: []string{ErrUnknownFormat.Error()},
+		},
+		{
+			name:           "Missing file",
+			args:           []string{"-config", "{dir}/config.yaml"},
+			exceptedErrors: []string{"config file"},
+		},
+		{
+			name:           "Unknown flag",
+			args:           []string{"-verbose"},
+			exceptedErrors: []string{"flag provided but not defined: -verbose"},
+		},
+		{
+			name:           "Unexpected argument",
+			args:           []string{"-port", "9090", "serve"},
+			exceptedErrors: []string{`unexpected argument "serve"`},
+		},
+		{
+			name: "Invalid config",
+			env:  map[string]string{"PORT": "70000", "WRITE_TIMEOUT": "5s", "CALC_TIMEOUT": "10s", "MAX_DEPTH": "-1", "TLS_KEY_FILE": "server.key"},
+			exceptedErrors: []string{
+				"port (env PORT, flag -port): must be between 1 and 65535, got 70000",
+				"timeouts.write (env WRITE_TIMEOUT, flag -write-timeout): must be longer than the timeout of calculation 10s, got 5s",
+				"limits.max_depth (env MAX_DEPTH, flag -max-depth): must not be negative, got -1",
+				"tls.cert_file (env TLS_CERT_FILE, flag -tls-cert-file): must be set with the key file",
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			expand := func(s string) string { return strings.ReplaceAll(s, "{dir}", dir) }
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatalf("WriteFile: unexcepted error %q", err)
+				}
+			}
+			args := make([]string, len(tt.args))
+			for i, arg := range tt.args {
+				args[i] = expand(arg)
+			}
+			getenv := func(name string) string { return expand(tt.env[name]) }
+
+			got, err := Load(args, getenv)
+			if tt.exceptedErrors != nil {
+				if err == nil {
+					t.Fatalf("excepted error, got config %+v", got)
+				}
+				for _, excepted := range tt.exceptedErrors {
+					if !strings.Contains(err.Error(), excepted) {
+						t.Errorf("excepted error containing %q, got %q", excepted, err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: unexcepted error %q", err)
+			}
+			excepted := NewConfigExample()
+			tt.excepted(excepted)
+			excepted.TLS = TLS{CertFile: expand(excepted.TLS.CertFile), KeyFile: expand(excepted.TLS.KeyFile), ClientCAFile: expand(excepted.TLS.ClientCAFile)}
+			if !reflect.DeepEqual(got, excepted) {
+				t.Errorf("excepted %+v, got %+v", excepted, got)
+			}
+		})
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	_, err := Load([]string{"-h"}, func(string) string { return "" })
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("excepted flag.ErrHelp, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		name          string
+		change        func(c *Config)
+		exceptedError string
+	}{
+		{
+			name:   "Default config",
+			change: func(c *Config) {},
+		},
+		{
+			name:   "Host",
+			change: func(c *Config) { c.Host = "localhost" },
+		},
+		{
+			name:          "Host with port",
+			change:        func(c *Config) { c.Host = "localhost:8080" },
+			exceptedError: "host (env HOST, flag -host): must not contain the port",
+		},
+		{
+			name:          "Zero timeout",
+			change:        func(c *Config) { c.IdleTimeout = 0 },
+			exceptedError: "timeouts.idle (env IDLE_TIMEOUT, flag -idle-timeout): must be positive, got 0s",
+		},
+		{
+			name:          "Zero body size",
+			change:        func(c *Config) { c.MaxBodySize = 0 },
+			exceptedError: "limits.max_body_size (env MAX_BODY_SIZE, flag -max-body-size): must be positive, got 0",
+		},
+		{
+			name:          "Tracing endpoint without scheme",
+			change:        func(c *Config) { c.TracingEndpoint = "collector:4318" },
+			exceptedError: `tracing.endpoint (env TRACING_ENDPOINT, flag -tracing-endpoint): must be the http or https URL, got "collector:4318"`,
+		},
+		{
+			name:          "Missing rates file",
+			change:        func(c *Config) { c.RatesFile = filepath.Join(dir, "rates.json") },
+			exceptedError: "rates_file (env RATES_FILE, flag -rates-file): stat",
+		},
+		{
+			name:          "Directory instead of certificate",
+			change:        func(c *Config) { c.TLS = TLS{CertFile: dir, KeyFile: dir} },
+			exceptedError: "tls.cert_file (env TLS_CERT_FILE, flag -tls-cert-file): " + dir + " is a directory",
+		},
+		{
+			name:          "Missing API keys file",
+			change:        func(c *Config) { c.APIKeysFile = filepath.Join(dir, "keys.json") },
+			exceptedError: "auth.keys_file (env API_KEYS_FILE, flag -api-keys-file): stat",
+		},
+		{
+			name:          "Negative rate",
+			change:        func(c *Config) { c.RateLimit.API.Rate = -1 },
+			exceptedError: "rate_limit.api.rate (env RATE_LIMIT, flag -rate-limit): must not be negative, got -1",
+		},
+		{
+			name:          "Rate without burst",
+			change:        func(c *Config) { c.RateLimit.Numeric.Burst = 0 },
+			exceptedError: "rate_limit.numeric.burst (env NUMERIC_RATE_LIMIT_BURST, flag -numeric-rate-limit-burst): must be at least 1, got 0",
+		},
+		{
+			name:          "Auth rate without burst",
+			change:        func(c *Config) { c.RateLimit.Auth.Burst = 0 },
+			exceptedError: "rate_limit.auth.burst (env AUTH_RATE_LIMIT_BURST, flag -auth-rate-limit-burst): must be at least 1, got 0",
+		},
+		{
+			name:   "Disabled rate limit without burst",
+			change: func(c *Config) { c.RateLimit.API = Rate{} },
+		},
+		{
+			name:          "Negative limit of functions",
+			change:        func(c *Config) { c.FunctionLimits.MaxFunctions = -1 },
+			exceptedError: "functions.max_per_namespace (env MAX_FUNCTIONS, flag -max-functions): must not be negative, got -1",
+		},
+		{
+			name:          "Negative cache size",
+			change:        func(c *Config) { c.Cache.Size = -1 },
+			exceptedError: "cache.size (env CACHE_SIZE, flag -cache-size): must not be negative, got -1",
+		},
+		{
+			name:          "Negative max age",
+			change:        func(c *Config) { c.Cache.MaxAge = -time.Second },
+			exceptedError: "cache.max_age (env CACHE_MAX_AGE, flag -cache-max-age): must not be negative, got -1s",
+		},
+		{
+			name:   "Disabled cache",
+			change: func(c *Config) { c.Cache = Cache{} },
+		},
+		{
+			name:          "WebSocket rate without burst",
+			change:        func(c *Config) { c.WebSocket.Rate.Burst = 0 },
+			exceptedError: "websocket.rate_limit.burst (env WS_RATE_LIMIT_BURST, flag -ws-rate-limit-burst): must be at least 1, got 0",
+		},
+		{
+			name:          "Zero WebSocket message size",
+			change:        func(c *Config) { c.WebSocket.MaxMessageSize = 0 },
+			exceptedError: "websocket.max_message_size (env WS_MAX_MESSAGE_SIZE, flag -ws-max-message-size): must be positive, got 0",
+		},
+		{
+			name:   "Any WebSocket origin",
+			change: func(c *Config) { c.WebSocket.AllowedOrigins = []string{"*"} },
+		},
+		{
+			name:          "WebSocket origin without scheme",
+			change:        func(c *Config) { c.WebSocket.AllowedOrigins = []string{"calc.example.com"} },
+			exceptedError: "websocket.allowed_origins (env WS_ALLOWED_ORIGINS, flag -ws-allowed-origins): must be origins like https://example.com or *, got \"calc.example.com\"",
+		},
+		{
+			name:          "Client authorities without certificate",
+			change:        func(c *Config) { c.TLS.ClientCAFile = dir },
+			exceptedError: "tls.client_ca_file (env TLS_CLIENT_CA_FILE, flag -tls-client-ca-file): requires the certificate and the key of server",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfigExample()
+			tt.change(cfg)
+			err := cfg.Validate()
+			if tt.exceptedError == "" {
+				if err != nil {
+					t.Errorf("Validate: unexcepted error %q", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.exceptedError) {
+				t.Errorf("excepted error containing %q, got %v", tt.exceptedError, err)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var buf bytes.Buffer
+	Usage(&buf)
+	for _, o := range options {
+		if !strings.Contains(buf.String(), "-"+o.flag) || !strings.Contains(buf.String(), "(env "+o.env+")") {
+			t.Errorf("excepted usage of flag -%s with env %s", o.flag, o.env)
+		}
+	}
+}
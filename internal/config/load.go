@@ -0,0 +1,350 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigFile is the environment variable with the path to the config file.
+// The flag -config overrides it
+const EnvConfigFile = "CONFIG_FILE"
+
+// ErrUnknownFormat is returned for the config file with unknown extension
+var ErrUnknownFormat = errors.New("unknown format of config file, expected .json, .yaml or .yml")
+
+// option is the value of config which could be set by the key of config
+// file, by the environment variable and by the flag
+type option struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	// set parses the value and sets it to the config
+	set func(c *Config, value string) error
+	// boolean options could be set by the flag without the value
+	boolean bool
+}
+
+var options = []option{
+	{key: "host", env: "HOST", flag: "host", usage: "address of interface to listen on, all interfaces by default", set: value(parseString, func(c *Config) *string { return &c.Host })},
+	{key: "port", env: "PORT", flag: "port", usage: "port to listen on", set: value(parseInt, func(c *Config) *int { return &c.Port })},
+	{key: "rates_file", env: "RATES_FILE", flag: "rates-file", usage: "JSON or YAML file with currency rates and custom units", set: value(parseString, func(c *Config) *string { return &c.RatesFile })},
+
+	{key: "timeouts.calc", env: "CALC_TIMEOUT", flag: "calc-timeout", usage: "maximum time of calculation of a single expression", set: value(parseDuration, func(c *Config) *time.Duration { return &c.CalcTimeout })},
+	{key: "timeouts.read", env: "READ_TIMEOUT", flag: "read-timeout", usage: "maximum time of reading the request", set: value(parseDuration, func(c *Config) *time.Duration { return &c.ReadTimeout })},
+	{key: "timeouts.write", env: "WRITE_TIMEOUT", flag: "write-timeout", usage: "maximum time of writing the response, longer than the timeout of calculation", set: value(parseDuration, func(c *Config) *time.Duration { return &c.WriteTimeout })},
+	{key: "timeouts.idle", env: "IDLE_TIMEOUT", flag: "idle-timeout", usage: "maximum time of waiting for the next request on keep-alive connections", set: value(parseDuration, func(c *Config) *time.Duration { return &c.IdleTimeout })},
+	{key: "timeouts.shutdown", env: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "maximum time of waiting for in-flight requests on shutdown", set: value(parseDuration, func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+
+	{key: "limits.max_body_size", env: "MAX_BODY_SIZE", flag: "max-body-size", usage: "maximum size of request body in bytes", set: value(parseInt64, func(c *Config) *int64 { return &c.MaxBodySize })},
+	{key: "limits.max_length", env: "MAX_LENGTH", flag: "max-length", usage: "maximum length of expression in bytes, 0 disables the limit", set: value(parseInt, func(c *Config) *int { return &c.Limits.MaxLength })},
+	{key: "limits.max_tokens", env: "MAX_TOKENS", flag: "max-tokens", usage: "maximum number of tokens of expression, 0 disables the limit", set: value(parseInt, func(c *Config) *int { return &c.Limits.MaxTokens })},
+	{key: "limits.max_depth", env: "MAX_DEPTH", flag: "max-depth", usage: "maximum nesting of brackets, 0 disables the limit", set: value(parseInt, func(c *Config) *int { return &c.Limits.MaxDepth })},
+	{key: "limits.max_steps", env: "MAX_STEPS", flag: "max-steps", usage: "maximum number of evaluated tokens, 0 disables the limit", set: value(parseInt, func(c *Config) *int { return &c.Limits.MaxSteps })},
+	{key: "limits.max_iterations", env: "MAX_ITERATIONS", flag: "max-iterations", usage: "maximum number of evaluations of expression by integration, summation and plotting, 0 disables the limit", set: value(parseInt, func(c *Config) *int { return &c.Limits.MaxIterations })},
+
+	{key: "functions.max_per_namespace", env: "MAX_FUNCTIONS", flag: "max-functions", usage: "maximum number of user-defined functions of every namespace, 0 disables the limit", set: value(parseInt, func(c *Config) *int { return &c.FunctionLimits.MaxFunctions })},
+	{key: "functions.max_namespaces", env: "MAX_NAMESPACES", flag: "max-namespaces", usage: "maximum number of namespaces of user-defined functions, 0 disables the limit", set: value(parseInt, func(c *Config) *int { return &c.FunctionLimits.MaxNamespaces })},
+
+	{key: "rate_limit.auth.rate", env: "AUTH_RATE_LIMIT", flag: "auth-rate-limit", usage: "requests per second of every IP to API before authentication by API keys, 0 disables the limit", set: value(parseFloat, func(c *Config) *float64 { return &c.RateLimit.Auth.Rate })},
+	{key: "rate_limit.auth.burst", env: "AUTH_RATE_LIMIT_BURST", flag: "auth-rate-limit-burst", usage: "requests of every IP to API at once before authentication by API keys", set: value(parseInt, func(c *Config) *int { return &c.RateLimit.Auth.Burst })},
+	{key: "rate_limit.api.rate", env: "RATE_LIMIT", flag: "rate-limit", usage: "requests per second of every client to calculation and functions, 0 disables the limit", set: value(parseFloat, func(c *Config) *float64 { return &c.RateLimit.API.Rate })},
+	{key: "rate_limit.api.burst", env: "RATE_LIMIT_BURST", flag: "rate-limit-burst", usage: "requests of every client to calculation and functions at once", set: value(parseInt, func(c *Config) *int { return &c.RateLimit.API.Burst })},
+	{key: "rate_limit.numeric.rate", env: "NUMERIC_RATE_LIMIT", flag: "numeric-rate-limit", usage: "requests per second of every client to integration, summation and plots, 0 disables the limit", set: value(parseFloat, func(c *Config) *float64 { return &c.RateLimit.Numeric.Rate })},
+	{key: "rate_limit.numeric.burst", env: "NUMERIC_RATE_LIMIT_BURST", flag: "numeric-rate-limit-burst", usage: "requests of every client to integration, summation and plots at once", set: value(parseInt, func(c *Config) *int { return &c.RateLimit.Numeric.Burst })},
+
+	{key: "cache.size", env: "CACHE_SIZE", flag: "cache-size", usage: "number of cached results of calculation, 0 disables the cache", set: value(parseInt, func(c *Config) *int { return &c.Cache.Size })},
+	{key: "cache.ttl", env: "CACHE_TTL", flag: "cache-ttl", usage: "time of keeping cached results, 0 keeps them until they are evicted", set: value(parseDuration, func(c *Config) *time.Duration { return &c.Cache.TTL })},
+	{key: "cache.max_age", env: "CACHE_MAX_AGE", flag: "cache-max-age", usage: "time results of GET requests are fresh in caches of clients and proxies, 0 makes them revalidate results", set: value(parseDuration, func(c *Config) *time.Duration { return &c.Cache.MaxAge })},
+
+	{key: "websocket.rate_limit.rate", env: "WS_RATE_LIMIT", flag: "ws-rate-limit", usage: "messages per second of every WebSocket connection, 0 disables the limit", set: value(parseFloat, func(c *Config) *float64 { return &c.WebSocket.Rate.Rate })},
+	{key: "websocket.rate_limit.burst", env: "WS_RATE_LIMIT_BURST", flag: "ws-rate-limit-burst", usage: "messages of every WebSocket connection at once", set: value(parseInt, func(c *Config) *int { return &c.WebSocket.Rate.Burst })},
+	{key: "websocket.idle_timeout", env: "WS_IDLE_TIMEOUT", flag: "ws-idle-timeout", usage: "time of closing WebSocket connections without messages, 0 keeps them open", set: value(parseDuration, func(c *Config) *time.Duration { return &c.WebSocket.IdleTimeout })},
+	{key: "websocket.max_message_size", env: "WS_MAX_MESSAGE_SIZE", flag: "ws-max-message-size", usage: "maximum size of WebSocket message in bytes", set: value(parseInt, func(c *Config) *int { return &c.WebSocket.MaxMessageSize })},
+	{key: "websocket.allowed_origins", env: "WS_ALLOWED_ORIGINS", flag: "ws-allowed-origins", usage: "comma-separated origins of pages which could open WebSocket connections besides the origin of server, * allows any", set: value(parseList, func(c *Config) *[]string { return &c.WebSocket.AllowedOrigins })},
+
+	{key: "auth.keys_file", env: "API_KEYS_FILE", flag: "api-keys-file", usage: "JSON file of API keys, enables authentication of API requests", set: value(parseString, func(c *Config) *string { return &c.APIKeysFile })},
+
+	{key: "log.level", env: "LOG_LEVEL", flag: "log-level", usage: "minimum level of logs: debug, info, warn or error", set: value(logging.ParseLevel, func(c *Config) *slog.Level { return &c.LogLevel })},
+	{key: "log.expressions", env: "LOG_EXPRESSIONS", flag: "log-expressions", usage: "add the text of expressions to the logs", set: value(parseBool, func(c *Config) *bool { return &c.LogExpressions }), boolean: true},
+	{key: "tracing.endpoint", env: "TRACING_ENDPOINT", flag: "tracing-endpoint", usage: "URL of OTLP/HTTP collector of traces", set: value(parseString, func(c *Config) *string { return &c.TracingEndpoint })},
+
+	{key: "tls.cert_file", env: "TLS_CERT_FILE", flag: "tls-cert-file", usage: "PEM file with the certificate of server, enables HTTPS", set: value(parseString, func(c *Config) *string { return &c.TLS.CertFile })},
+	{key: "tls.key_file", env: "TLS_KEY_FILE", flag: "tls-key-file", usage: "PEM file with the private key of server", set: value(parseString, func(c *Config) *string { return &c.TLS.KeyFile })},
+	{key: "tls.client_ca_file", env: "TLS_CLIENT_CA_FILE", flag: "tls-client-ca-file", usage: "PEM file with authorities of client certificates, enables mutual TLS", set: value(parseString, func(c *Config) *string { return &c.TLS.ClientCAFile })},
+
+	{key: "features.metrics", env: "FEATURE_METRICS", flag: "feature-metrics", usage: "enable the /metrics endpoint", set: value(parseBool, func(c *Config) *bool { return &c.Features.Metrics }), boolean: true},
+	{key: "features.functions", env: "FEATURE_FUNCTIONS", flag: "feature-functions", usage: "enable user-defined functions", set: value(parseBool, func(c *Config) *bool { return &c.Features.Functions }), boolean: true},
+	{key: "features.numeric", env: "FEATURE_NUMERIC", flag: "feature-numeric", usage: "enable numerical integration and summation", set: value(parseBool, func(c *Config) *bool { return &c.Features.Numeric }), boolean: true},
+	{key: "features.plot", env: "FEATURE_PLOT", flag: "feature-plot", usage: "enable sampling of expressions for plots", set: value(parseBool, func(c *Config) *bool { return &c.Features.Plot }), boolean: true},
+}
+
+// value returns the setter of the field of config
+func value[T any](parse func(string) (T, error), field func(c *Config) *T) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := parse(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func parseString(value string) (string, error) {
+	return value, nil
+}
+
+func parseInt(value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+	return number, nil
+}
+
+func parseInt64(value string) (int64, error) {
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+	return number, nil
+}
+
+func parseFloat(value string) (float64, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return number, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected e.g. 500ms or 5s", value)
+	}
+	return duration, nil
+}
+
+// parseList splits the comma-separated list, empty items are skipped
+func parseList(value string) ([]string, error) {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+func parseBool(value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean %q, expected true or false", value)
+	}
+	return b, nil
+}
+
+// lookup returns the option by the key of config file
+func lookup(key string) (option, bool) {
+	i := slices.IndexFunc(options, func(o option) bool { return o.key == key })
+	if i < 0 {
+		return option{}, false
+	}
+	return options[i], true
+}
+
+// describe returns the key of config file with the environment variable and
+// the flag, so the error is clear whatever layer set the value
+func describe(key string) string {
+	o, ok := lookup(key)
+	if !ok {
+		return key
+	}
+	return fmt.Sprintf("%s (env %s, flag -%s)", o.key, o.env, o.flag)
+}
+
+// setting is the value of option given by the flag
+type setting struct {
+	option option
+	value  string
+}
+
+// Load returns the config merged from layers, every layer overrides the
+// previous one:
+//
+//  1. default values, see NewConfigExample
+//  2. the YAML or JSON file from the flag -config or the environment variable CONFIG_FILE
+//  3. environment variables
+//  4. flags of command line
+//
+// Empty environment variables are ignored. The error describes all invalid
+// values. flag.ErrHelp is returned for the flag -h
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	flags := newFlagSet()
+	path := flags.String("config", "", "YAML or JSON config file, overrides "+EnvConfigFile)
+	var settings []setting
+	for _, o := range options {
+		add := func(value string) error {
+			settings = append(settings, setting{option: o, value: value})
+			return nil
+		}
+		if o.boolean {
+			flags.BoolFunc(o.flag, o.usage, add)
+		} else {
+			flags.Func(o.flag, o.usage, add)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	cfg := NewConfigExample()
+	if *path == "" {
+		*path = getenv(EnvConfigFile)
+	}
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, o := range options {
+		if value := getenv(o.env); value != "" {
+			if err := o.set(cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", o.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if err := s.option.set(cfg, s.value); err != nil {
+			errs = append(errs, fmt.Errorf("flag -%s: %w", s.option.flag, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Usage writes the description of flags
+func Usage(w io.Writer) {
+	flags := newFlagSet()
+	flags.String("config", "", "YAML or JSON config file, overrides "+EnvConfigFile)
+	for _, o := range options {
+		usage := fmt.Sprintf("%s (env %s)", o.usage, o.env)
+		if o.boolean {
+			flags.BoolFunc(o.flag, usage, nil)
+		} else {
+			flags.Func(o.flag, usage, nil)
+		}
+	}
+	flags.SetOutput(w)
+	fmt.Fprintf(w, "Usage of %s:\n", flags.Name())
+	flags.PrintDefaults()
+}
+
+// newFlagSet returns the set of flags which returns errors instead of printing them
+func newFlagSet() *flag.FlagSet {
+	flags := flag.NewFlagSet("ordinary-calc", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// loadFile sets values from the YAML or JSON config file. Nested objects are
+// sections of the keys of options, e.g. timeouts.calc
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var values map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: %w", path, ErrUnknownFormat)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	flat := make(map[string]any)
+	if err := flatten("", values, flat); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		o, ok := lookup(key)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key %q", key))
+			continue
+		}
+		if flat[key] == nil {
+			continue
+		}
+		if err := o.set(c, fmt.Sprint(flat[key])); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// flatten puts scalar values of nested objects to the map by keys joined with dots
+func flatten(prefix string, values map[string]any, flat map[string]any) error {
+	for name, v := range values {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flatten(key, v, flat); err != nil {
+				return err
+			}
+		case []any:
+			return fmt.Errorf("%s: expected scalar value, got list", key)
+		default:
+			flat[key] = v
+		}
+	}
+	return nil
+}
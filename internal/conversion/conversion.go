@@ -0,0 +1,74 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownFormat = errors.New("unknown format of conversion table, expected .json, .yaml or .yml")
+
+// File is the content of the file with the conversion table, e.g.
+//
+//	timestamp: 2026-10-19T12:00:00Z
+//	base: USD
+//	rates:
+//	  EUR: 0.92
+//	units:
+//	  furlong: 201.168 m
+type File struct {
+	// Timestamp is the time when the rates were actual. The time of the last
+	// modification of the file is used if it is not set
+	Timestamp time.Time          `json:"timestamp" yaml:"timestamp"`
+	Base      string             `json:"base" yaml:"base"`
+	Rates     map[string]float64 `json:"rates" yaml:"rates"`
+	Units     map[string]string  `json:"units" yaml:"units"`
+}
+
+// Load reads the conversion table from the JSON or YAML file
+func Load(path string) (*calc.UnitTable, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&file)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("conversion table %s: %w", path, err)
+	}
+
+	if file.Timestamp.IsZero() {
+		file.Timestamp = info.ModTime().UTC()
+	}
+
+	table, err := calc.NewUnitTable(file.Base, file.Rates, file.Units, file.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("conversion table %s: %w", path, err)
+	}
+	return table, nil
+}
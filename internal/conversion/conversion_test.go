@@ -0,0 +1,168 @@
+package conversion
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+const tableJSON = `{
+	"timestamp": "2026-10-19T12:00:00Z",
+	"base": "USD",
+	"rates": {"EUR": 0.5},
+	"units": {"furlong": "201.168 m"}
+}`
+
+const tableYAML = `
+timestamp: 2026-10-19T12:00:00Z
+base: USD
+rates:
+  EUR: 0.5
+units:
+  furlong: 201.168 m
+`
+
+// writeFile writes the content to the file in temporary directory of the test
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("error while writing %s: %s", path, err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "JSON",
+			file:    "rates.json",
+			content: tableJSON,
+		},
+		{
+			name:    "YAML",
+			file:    "rates.yaml",
+			content: tableYAML,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := Load(writeFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("Load: unexcepted error %q", err)
+			}
+
+			if !table.Timestamp.Equal(timestamp) {
+				t.Errorf("Load: got timestamp %s, excepted %s", table.Timestamp, timestamp)
+			}
+			result, err := table.CalcQuantity("100 USD in EUR")
+			if err != nil || result.Value != 50 {
+				t.Errorf("CalcQuantity: got %f and error %v, excepted 50", result.Value, err)
+			}
+			if _, ok := table.LookupUnit("furlong"); !ok {
+				t.Errorf("LookupUnit: custom unit furlong is not found")
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		expectedErr error
+	}{
+		{
+			name:        "Unknown format",
+			file:        "rates.txt",
+			content:     "USD 1",
+			expectedErr: ErrUnknownFormat,
+		},
+		{
+			name:        "Invalid rate",
+			file:        "rates.json",
+			content:     `{"base": "USD", "rates": {"EUR": 0}}`,
+			expectedErr: calc.ErrInvalidRate,
+		},
+		{
+			name:        "Built-in unit",
+			file:        "rates.yml",
+			content:     "units:\n  km: 1000 m\n",
+			expectedErr: calc.ErrUnitExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeFile(t, tt.file, tt.content))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Load: got error %q, excepted %q", err, tt.expectedErr)
+			}
+		})
+	}
+
+	// Unknown fields are errors too
+	if _, err := Load(writeFile(t, "rates.json", `{"base": "USD", "rate": {"EUR": 0.5}}`)); err == nil {
+		t.Errorf("Load: excepted error for unknown field")
+	}
+}
+
+func TestLoadWithoutTimestamp(t *testing.T) {
+	path := writeFile(t, "rates.json", `{"base": "USD", "rates": {"EUR": 0.5}}`)
+	info, _ := os.Stat(path)
+
+	table, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: unexcepted error %q", err)
+	}
+	if !table.Timestamp.Equal(info.ModTime()) {
+		t.Errorf("Load: got timestamp %s, excepted time of modification %s", table.Timestamp, info.ModTime())
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	path := writeFile(t, "rates.json", `{"base": "USD", "rates": {"EUR": 0.5}}`)
+
+	watcher, err := NewWatcher(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewWatcher: unexcepted error %q", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	// Broken file does not replace the table
+	first := watcher.Table()
+	if err := os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": -1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if watcher.Table() != first {
+		t.Fatalf("Watcher: table is replaced by the broken file")
+	}
+
+	// Changed file is reloaded
+	if err := os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": 0.25, "GBP": 0.2}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for watcher.Table() == first && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	result, err := watcher.Table().CalcQuantity("100 USD in EUR")
+	if err != nil || result.Value != 25 {
+		t.Errorf("CalcQuantity after reload: got %f and error %v, excepted 25", result.Value, err)
+	}
+}
@@ -0,0 +1,52 @@
+package conversion
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/watch"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+// DefaultInterval is the period of checking the file for changes
+const DefaultInterval = watch.DefaultInterval
+
+// Watcher keeps the conversion table up to date with the file on disk
+type Watcher struct {
+	path    string
+	table   atomic.Pointer[calc.UnitTable]
+	watcher *watch.Watcher
+}
+
+// NewWatcher loads the conversion table from the file. The table is reloaded
+// by Run when the file changes
+func NewWatcher(path string, interval time.Duration) (*Watcher, error) {
+	w := &Watcher{path: path}
+	watcher, err := watch.New("conversion table", interval, w.reload, path)
+	if err != nil {
+		return nil, err
+	}
+	w.watcher = watcher
+	return w, nil
+}
+
+// Table returns the last successfully loaded conversion table
+func (w *Watcher) Table() *calc.UnitTable {
+	return w.table.Load()
+}
+
+// Run checks the file for changes until the context is done. The previous
+// table is kept if the changed file could not be loaded
+func (w *Watcher) Run(ctx context.Context) {
+	w.watcher.Run(ctx)
+}
+
+func (w *Watcher) reload() error {
+	table, err := Load(w.path)
+	if err != nil {
+		return err
+	}
+	w.table.Store(table)
+	return nil
+}
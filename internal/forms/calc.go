@@ -1,5 +1,24 @@
 package forms
 
+// Modes of calculation
+const (
+	ModeReal    = "real"
+	ModeComplex = "complex"
+)
+
 type Expression struct {
 	Expression string `json:"expression" example:"2+2*2"`
+	// Mode is the mode of calculation: real (by default) or complex
+	Mode string `json:"mode,omitempty" example:"real" enums:"real,complex"`
+	// Variables binds names used in the expression to numbers, arrays of
+	// numbers or arrays of rows of matrix. They are not available in complex mode
+	Variables map[string]any `json:"variables,omitempty" swaggertype:"object"`
+}
+
+// Message is the message of WebSocket client with the expression
+type Message struct {
+	// ID is returned in the response to the message, so responses to stale
+	// messages could be dropped
+	ID int64 `json:"id" example:"1"`
+	Expression
 }
@@ -2,4 +2,24 @@ package forms
 
 type HTTPError struct {
 	Error string `json:"error" example:"example error"`
+	// Code is the stable code of the error which does not depend on the message
+	Code string `json:"code,omitempty" example:"UNPAIRED_BRACKET"`
+	// Position is the number of characters of the expression before the wrong part
+	Position *int `json:"position,omitempty" example:"4"`
+	// Details has data of the error, e.g. units of incompatible operands
+	Details map[string]any `json:"details,omitempty" swaggertype:"object"`
+}
+
+// Problem is the error in the format of RFC 7807 returned when
+// application/problem+json is accepted
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Unprocessable Entity"`
+	Status   int    `json:"status" example:"422"`
+	Detail   string `json:"detail" example:"Expression has unpaired brackets"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/calculate"`
+	// Code, Position and Details are the same as in HTTPError
+	Code     string         `json:"code,omitempty" example:"UNPAIRED_BRACKET"`
+	Position *int           `json:"position,omitempty" example:"4"`
+	Details  map[string]any `json:"details,omitempty" swaggertype:"object"`
 }
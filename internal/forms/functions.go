@@ -0,0 +1,6 @@
+package forms
+
+type Function struct {
+	Parameters []string `json:"parameters" example:"x,y"`
+	Expression string   `json:"expression" example:"x^2 + y"`
+}
@@ -0,0 +1,16 @@
+package forms
+
+type Integral struct {
+	Expression string  `json:"expression" example:"x^2"`
+	Variable   string  `json:"variable" example:"x"`
+	From       float64 `json:"from" example:"0"`
+	To         float64 `json:"to" example:"3"`
+	Tolerance  float64 `json:"tolerance,omitempty" example:"0.000001"`
+}
+
+type Sum struct {
+	Expression string `json:"expression" example:"100/(1+0.05)^i"`
+	Variable   string `json:"variable" example:"i"`
+	From       int    `json:"from" example:"1"`
+	To         int    `json:"to" example:"10"`
+}
@@ -0,0 +1,9 @@
+package forms
+
+type Plot struct {
+	Expression string  `json:"expression" example:"1/x"`
+	Variable   string  `json:"variable" example:"x"`
+	From       float64 `json:"from" example:"-1"`
+	To         float64 `json:"to" example:"1"`
+	Points     int     `json:"points" example:"101"`
+}
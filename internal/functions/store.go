@@ -0,0 +1,121 @@
+package functions
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+// DefaultNamespace is used for requests without namespace
+const DefaultNamespace = "default"
+
+var (
+	ErrTooManyFunctions  = errors.New("too many functions in namespace")
+	ErrTooManyNamespaces = errors.New("too many namespaces")
+)
+
+// Limits bound the memory of the store, zero disables the limit
+type Limits struct {
+	// MaxFunctions is the maximum number of functions of every namespace
+	MaxFunctions int
+	// MaxNamespaces is the maximum number of namespaces with functions
+	MaxNamespaces int
+}
+
+// Definition is a user-defined function with its name
+type Definition struct {
+	Name       string
+	Parameters []string
+	Expression string
+}
+
+// Store keeps user-defined functions separately for every namespace. It is
+// safe for concurrent use
+type Store struct {
+	mu         sync.RWMutex
+	limits     Limits
+	namespaces map[string]calc.Functions
+	// versions are incremented by every change of the namespace
+	versions map[string]uint64
+}
+
+// NewStore returns the empty store with the limits
+func NewStore(limits Limits) *Store {
+	return &Store{limits: limits, namespaces: make(map[string]calc.Functions), versions: make(map[string]uint64)}
+}
+
+// Define compiles the function and binds it to the name in the namespace.
+// Redefinition of the function is not limited, new functions and namespaces
+// over the limits return ErrTooManyFunctions and ErrTooManyNamespaces
+func (s *Store) Define(namespace, name string, parameters []string, expression string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	functions, ok := s.namespaces[namespace]
+	if !ok {
+		if s.limits.MaxNamespaces > 0 && len(s.namespaces) >= s.limits.MaxNamespaces {
+			return ErrTooManyNamespaces
+		}
+		functions = calc.Functions{}
+	}
+	if _, defined := functions[name]; !defined && s.limits.MaxFunctions > 0 && len(functions) >= s.limits.MaxFunctions {
+		return ErrTooManyFunctions
+	}
+	if err := functions.Define(name, parameters, expression); err != nil {
+		return err
+	}
+
+	s.namespaces[namespace] = functions
+	s.versions[namespace]++
+	return nil
+}
+
+// Delete removes the function from the namespace
+func (s *Store) Delete(namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.namespaces[namespace].Delete(name); err != nil {
+		return err
+	}
+	s.versions[namespace]++
+	return nil
+}
+
+// List returns the functions of the namespace sorted by name
+func (s *Store) List(namespace string) []Definition {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	functions := s.namespaces[namespace]
+	definitions := make([]Definition, 0, len(functions))
+	for _, name := range slices.Sorted(maps.Keys(functions)) {
+		definitions = append(definitions, Definition{
+			Name:       name,
+			Parameters: functions[name].Parameters,
+			Expression: functions[name].Expression,
+		})
+	}
+	return definitions
+}
+
+// Functions returns the copy of functions of the namespace, which is not
+// changed by following definitions
+func (s *Store) Functions(namespace string) calc.Functions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return maps.Clone(s.namespaces[namespace])
+}
+
+// Version returns the number of changes of the namespace, so results
+// calculated with its functions could be cached until the next change
+func (s *Store) Version(namespace string) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.versions[namespace]
+}
@@ -0,0 +1,99 @@
+package functions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStoreNamespaces(t *testing.T) {
+	store := NewStore(Limits{})
+	if err := store.Define("a", "f", []string{"x"}, "x + 1"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+	if err := store.Define("b", "f", []string{"x"}, "x + 2"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+
+	if got := store.List("a"); len(got) != 1 || got[0].Expression != "x + 1" {
+		t.Errorf("List(a): got %+v", got)
+	}
+	if got := store.List("c"); len(got) != 0 {
+		t.Errorf("List(c): excepted no functions, got %+v", got)
+	}
+
+	// Snapshot is not changed by following definitions
+	snapshot := store.Functions("a")
+	if err := store.Define("a", "g", nil, "1"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+	if len(snapshot) != 1 {
+		t.Errorf("Functions(a): excepted 1 function in snapshot, got %d", len(snapshot))
+	}
+
+	if err := store.Delete("b", "f"); err != nil {
+		t.Errorf("Delete: unexcepted error %q", err)
+	}
+	if got := store.List("a"); len(got) != 2 {
+		t.Errorf("List(a): excepted 2 functions after deletion in other namespace, got %+v", got)
+	}
+}
+
+func TestStoreVersion(t *testing.T) {
+	store := NewStore(Limits{})
+	if got := store.Version("a"); got != 0 {
+		t.Errorf("excepted version 0 of empty namespace, got %d", got)
+	}
+
+	store.Define("a", "f", []string{"x"}, "x + 1")
+	store.Define("a", "f", []string{"x"}, "x + 2")
+	if got := store.Version("a"); got != 2 {
+		t.Errorf("excepted version 2 after two definitions, got %d", got)
+	}
+
+	// Failed changes do not change the version
+	store.Define("a", "g", []string{"x"}, "x +")
+	store.Delete("a", "g")
+	if got := store.Version("a"); got != 2 {
+		t.Errorf("excepted version 2 after failed changes, got %d", got)
+	}
+
+	store.Delete("a", "f")
+	if got, other := store.Version("a"), store.Version("b"); got != 3 || other != 0 {
+		t.Errorf("excepted versions 3 and 0, got %d and %d", got, other)
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	store := NewStore(Limits{MaxFunctions: 2, MaxNamespaces: 2})
+	store.Define("a", "f", []string{"x"}, "x + 1")
+	store.Define("a", "g", []string{"x"}, "x + 2")
+	store.Define("b", "f", []string{"x"}, "x + 3")
+
+	cases := []struct {
+		name        string
+		namespace   string
+		function    string
+		expectedErr error
+	}{
+		{name: "New function over the limit", namespace: "a", function: "h", expectedErr: ErrTooManyFunctions},
+		{name: "New namespace over the limit", namespace: "c", function: "f", expectedErr: ErrTooManyNamespaces},
+		{name: "Redefinition", namespace: "a", function: "g", expectedErr: nil},
+		{name: "New function under the limit", namespace: "b", function: "g", expectedErr: nil},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := store.Define(testCase.namespace, testCase.function, []string{"x"}, "x")
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("excepted error %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+
+	// Deleted functions free the place for new ones
+	if err := store.Delete("a", "f"); err != nil {
+		t.Fatalf("Delete: unexcepted error %q", err)
+	}
+	if err := store.Define("a", "h", nil, "1"); err != nil {
+		t.Errorf("Define: unexcepted error %q after deletion", err)
+	}
+}
@@ -1,72 +1,469 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
+	"github.com/Irurnnen/ordinary-calc/internal/cache"
+	"github.com/Irurnnen/ordinary-calc/internal/config"
 	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+	"github.com/Irurnnen/ordinary-calc/internal/i18n"
+	"github.com/Irurnnen/ordinary-calc/internal/logging"
+	"github.com/Irurnnen/ordinary-calc/internal/metrics"
 	"github.com/Irurnnen/ordinary-calc/internal/models"
 	"github.com/Irurnnen/ordinary-calc/pkg/calc"
 )
 
-// CalcHandler godoc
+// CalcOptions configures the handler of calculation
+type CalcOptions struct {
+	// Units returns the table of currencies and custom units. Only built-in
+	// units are available when it is nil or returns nil
+	Units func() *calc.UnitTable
+	// Functions keeps user-defined functions of namespaces. Only built-in
+	// functions are available when it is nil
+	Functions *functions.Store
+	// Limits limits resources used by calculation of a single expression
+	Limits calc.Options
+	// Timeout limits the time of calculation. There is no timeout when it is zero
+	Timeout time.Duration
+	// Body configures decoding of request body
+	Body BodyOptions
+	// LogExpressions adds the text of expressions to the logs of calculations.
+	// Only hashes of expressions are logged by default, because expressions
+	// could contain sensitive numbers
+	LogExpressions bool
+	// Metrics are updated by every calculation when they are not nil
+	Metrics *metrics.Metrics
+	// Cache keeps results of repeated expressions. Results are not cached
+	// when it is nil
+	Cache *cache.Cache[models.Result]
+	// HTTPCache configures caching of results of GET requests by clients and proxies
+	HTTPCache HTTPCacheOptions
+}
+
+// HTTPCacheOptions configures the Cache-Control header of results of GET
+// requests
+type HTTPCacheOptions struct {
+	// MaxAge is the time the result is fresh. Clients revalidate the result
+	// by ETag on every request when it is zero
+	MaxAge time.Duration
+	// Private forbids caching of results by shared caches, e.g. CDN. It is
+	// used when requests are authenticated
+	Private bool
+}
+
+// CacheControl returns the value of the Cache-Control header
+func (o HTTPCacheOptions) CacheControl() string {
+	visibility := "public"
+	if o.Private {
+		visibility = "private"
+	}
+	if o.MaxAge <= 0 {
+		return visibility + ", no-cache"
+	}
+	return visibility + ", max-age=" + strconv.Itoa(int(o.MaxAge.Seconds()))
+}
+
+// CacheHeader is the header of response telling whether the result was taken
+// from the cache: HIT or MISS. It is not set when the cache is disabled
+const CacheHeader = "X-Cache"
+
+// CalcHandler calculates expressions with default options
+var CalcHandler = NewCalcHandler(CalcOptions{
+	Limits: config.DefaultLimits,
+	Body:   BodyOptions{MaxSize: config.DefaultMaxBodySize, SingleObject: true},
+})
+
+// NewCalcHandler godoc
 //
 //	@Summary		Calculate expression
-//	@Description	get answer by expression
+//	@Description	get answer by expression. Expression could contain units of measurement and end with conversion to the unit, e.g. "3 h * 60 km/h in km". Currencies and custom units are available when the conversion table is configured. Matrices are written by rows, e.g. "[1,2;3,4]", and could be used with functions transpose, det, inv and dot. Matrix results have the rows in the matrix field without the result field. Statistics functions sum, prod, mean, median, variance, stdev and percentile take numbers and matrices. Variables could be numbers, arrays or arrays of rows. Functions defined in the namespace could be called. In complex mode the result has real and imaginary parts, the imaginary unit is written as i or j
 //	@Tags			Calculator
-//	@Param			Expression	body	forms.Expression	true	"Expression"
+//	@Param			X-Namespace		header	string				false	"Namespace of user-defined functions without API key"				default(default)
+//	@Param			Accept-Language	header	string				false	"Language of error messages"										default(en)
+//	@Param			lang			query	string				false	"Language of error messages, takes precedence over Accept-Language"	Enums(en, ru)
+//	@Param			Expression		body	forms.Expression	true	"Expression"
 //	@Accept			json
-//	@Produce		json
+//	@Produce		json,application/problem+json
 //	@Success		200	{object}	models.Result
+//	@Header			200	{string}	X-Cache	"HIT when the result is taken from the cache, MISS otherwise. It is not set when the cache is disabled"
 //	@Success		400	{object}	forms.HTTPError
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		413	{object}	forms.HTTPError
+//	@Failure		415	{object}	forms.HTTPError
 //	@Failure		422	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
 //	@Failure		500	{object}	forms.HTTPError
+//	@Failure		503	{object}	forms.HTTPError
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Router			/calculate [post]
-func CalcHandler(w http.ResponseWriter, r *http.Request) {
-	// Get data from request
-	var expression forms.Expression
+func NewCalcHandler(options CalcOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get data from request
+		var expression forms.Expression
+		if err := DecodeJSON(w, r, &expression, options.Body); err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		var key string
+		if options.Cache != nil {
+			key = cacheKey(r, expression, options)
+		}
+		response, hit, err := calculateCached(r, expression, key, options)
+		setCacheHeader(w, hit, options)
+		if err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+		JSON(w, response)
+	}
+}
+
+// NewCalcQueryHandler godoc
+//
+//	@Summary		Calculate expression from query
+//	@Description	get answer by expression from the query, so the result could be cached by browsers and proxies. The ETag of result is derived from the normalized expression, the mode, the version of functions of the namespace and the time of rates, so expressions which differ only in spaces have the same ETag. The mode is validated before the If-None-Match header, and request with the matching tag gets 304 without calculation. The Vary header lists Accept, Accept-Language, Authorization, X-API-Key and X-Namespace, because errors and access depend on them. Variables are only available in POST requests
+//	@Tags			Calculator
+//	@Param			expression		query	string	true	"Expression"															example(2 + 2)
+//	@Param			mode			query	string	false	"Mode of calculation"													Enums(real, complex)
+//	@Param			If-None-Match	header	string	false	"ETag of cached result"
+//	@Param			X-Namespace		header	string	false	"Namespace of user-defined functions without API key"					default(default)
+//	@Param			Accept-Language	header	string	false	"Language of error messages"											default(en)
+//	@Param			lang			query	string	false	"Language of error messages, takes precedence over Accept-Language"	Enums(en, ru)
+//	@Produce		json,application/problem+json
+//	@Success		200	{object}	models.Result
+//	@Header			200	{string}	ETag			"Tag of the result for conditional requests"
+//	@Header			200	{string}	Cache-Control	"Caching of the result by clients and proxies, private when API keys are enabled"
+//	@Header			200	{string}	X-Cache			"HIT when the result is taken from the cache, MISS otherwise. It is not set when the cache is disabled"
+//	@Success		304
+//	@Failure		400	{object}	forms.HTTPError
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		422	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Failure		500	{object}	forms.HTTPError
+//	@Failure		503	{object}	forms.HTTPError
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/calculate [get]
+func NewCalcQueryHandler(options CalcOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		expression := forms.Expression{Expression: query.Get("expression"), Mode: query.Get("mode")}
+
+		h := w.Header()
+		h.Set("Vary", strings.Join(varyHeaders(options), ", "))
+		if err := validateExpression(expression); err != nil {
+			h.Set("Cache-Control", "no-store")
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		// The result is not calculated when the client has it
+		key := cacheKey(r, expression, options)
+		etag := ETag(key)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			h.Set("ETag", etag)
+			h.Set("Cache-Control", options.HTTPCache.CacheControl())
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		response, hit, err := calculateCached(r, expression, key, options)
+		setCacheHeader(w, hit, options)
+		if err != nil {
+			h.Set("Cache-Control", "no-store")
+			ErrorHandler(w, r, err)
+			return
+		}
+		h.Set("ETag", etag)
+		h.Set("Cache-Control", options.HTTPCache.CacheControl())
+		JSON(w, response)
+	}
+}
 
-	err := json.NewDecoder(r.Body).Decode(&expression)
+// varyHeaders returns the headers of request which affect the response of
+// GET request. The format and the language of errors depend on Accept and
+// Accept-Language, the access depends on API keys
+func varyHeaders(options CalcOptions) []string {
+	headers := []string{"Accept", "Accept-Language", "Authorization", apikeys.Header}
+	if options.Functions != nil {
+		headers = append(headers, NamespaceHeader)
+	}
+	return headers
+}
+
+// calculateCached returns the result of expression from the cache by the key
+// or calculates it. It reports whether the result is taken from the cache
+func calculateCached(r *http.Request, expression forms.Expression, key string, options CalcOptions) (models.Result, bool, error) {
+	start := time.Now()
+	if options.Cache != nil {
+		response, hit := options.Cache.Get(key)
+		options.Metrics.CacheLookup(hit)
+		if hit {
+			observeCalculation(r, expression, options, time.Since(start), nil)
+			return response, true, nil
+		}
+	}
+
+	response, err := calculate(r, expression, options)
+	observeCalculation(r, expression, options, time.Since(start), err)
 	if err != nil {
-		ErrorJSONHandler(w, http.StatusBadRequest, forms.HTTPError{Error: "Provided data is invalid"})
+		return models.Result{}, false, err
+	}
+	// Errors are not cached, because some of them depend on the request,
+	// e.g. the timeout
+	if options.Cache != nil {
+		options.Cache.Add(key, response)
+	}
+	return response, false, nil
+}
+
+// setCacheHeader sets the X-Cache header when the cache is enabled
+func setCacheHeader(w http.ResponseWriter, hit bool, options CalcOptions) {
+	if options.Cache == nil {
 		return
 	}
+	if hit {
+		w.Header().Set(CacheHeader, "HIT")
+	} else {
+		w.Header().Set(CacheHeader, "MISS")
+	}
+}
+
+// ETag returns the strong entity tag of the result with the cache key
+func ETag(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header has the entity tag.
+// Weak tags are compared as strong ones, as RFC 9110 requires for
+// If-None-Match. The wildcard is not matched, because the result of
+// expression is not known before calculation
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// calculate returns the result of expression from the request
+func calculate(r *http.Request, expression forms.Expression, options CalcOptions) (models.Result, error) {
+	ctx, cancel := calcContext(r, options)
+	defer cancel()
+
+	if err := validateExpression(expression); err != nil {
+		return models.Result{}, err
+	}
+
+	// Calculate the expression with complex numbers
+	if expression.Mode == forms.ModeComplex {
+		result, err := calc.CalcComplexContext(ctx, expression.Expression, options.Limits)
+		if err != nil {
+			return models.Result{}, err
+		}
+
+		re, im := real(result), imag(result)
+		return models.Result{Result: &re, Re: &re, Im: &im}, nil
+	}
+
+	// Get values of variables
+	values, _ := variableValues(expression.Variables)
+
+	// Get user-defined functions of the namespace
+	scope := calc.Scope{Values: values}
+	if options.Functions != nil {
+		scope.Functions = options.Functions.Functions(namespace(r))
+	}
+
+	// Get the table of conversions
+	var table *calc.UnitTable
+	if options.Units != nil {
+		table = options.Units()
+	}
 
 	// Calculate the expression
-	result, err := calc.Calc(expression.Expression)
-
-	// Process errors
-	switch err {
-	case nil:
-		break
-	case calc.ErrExtraCharacters:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression has extra characters"})
-		return
-	case calc.ErrUnpairedBracket:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression has unpaired brackets"})
-		return
-	case calc.ErrWrongBracketOrder:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression has wrong bracket order"})
-		return
-	case calc.ErrMultipleOperands:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression has multiple operands"})
-		return
-	case calc.ErrMultipleNumbers:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression has multiple sequential numbers"})
-		return
-	case calc.ErrZeroByDivision:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression has zero by division"})
-		return
-	case calc.ErrExtraOperands:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression has operand at the beginning or at the end"})
-		return
-	case calc.ErrEmptyExpression:
-		ErrorJSONHandler(w, http.StatusUnprocessableEntity, forms.HTTPError{Error: "Expression is empty"})
-		return
+	var result calc.Quantity
+	var err error
+	if table != nil {
+		result, err = table.CalcQuantityContext(ctx, expression.Expression, scope, options.Limits)
+	} else {
+		result, err = calc.CalcQuantityContext(ctx, expression.Expression, scope, options.Limits)
+	}
+	if err != nil {
+		return models.Result{}, err
+	}
+
+	response := models.Result{Unit: result.Unit, Matrix: result.Matrix}
+	if result.Matrix == nil {
+		response.Result = &result.Value
+	}
+	if table != nil {
+		response.RatesTimestamp = &table.Timestamp
+	}
+	return response, nil
+}
+
+// validateExpression checks the mode and the variables of expression
+func validateExpression(expression forms.Expression) error {
+	switch expression.Mode {
+	case "", forms.ModeReal:
+	case forms.ModeComplex:
+		if len(expression.Variables) != 0 {
+			return errComplexVariables
+		}
 	default:
-		ErrorJSONHandler(w, http.StatusInternalServerError, forms.HTTPError{Error: "Internal server error"})
-		return
+		return errUnknownMode
+	}
+	if _, ok := variableValues(expression.Variables); !ok {
+		return errInvalidVariables
+	}
+	return nil
+}
+
+// calcContext returns the context of calculation, which is done when the
+// client is gone or the time of calculation is over
+func calcContext(r *http.Request, options CalcOptions) (context.Context, context.CancelFunc) {
+	if options.Timeout > 0 {
+		return context.WithTimeout(r.Context(), options.Timeout)
 	}
+	return context.WithCancel(r.Context())
+}
+
+// cacheKey returns the key of the result of expression. The expression is
+// normalized by the tokenizer, so expressions which differ only in spaces
+// share the key. The key also has the mode, the variables, the version of
+// functions of the namespace and the time of rates, so the result is not
+// taken from the cache after they change
+func cacheKey(r *http.Request, expression forms.Expression, options CalcOptions) string {
+	mode := expression.Mode
+	if mode == "" {
+		mode = forms.ModeReal
+	}
+	// Keys of maps are sorted by encoding/json, so equal variables are encoded equally
+	variables, _ := json.Marshal(expression.Variables)
+
+	parts := []string{strings.Join(calc.ParseExpression(expression.Expression), " "), mode, string(variables)}
+	if options.Functions != nil {
+		namespace := namespace(r)
+		parts = append(parts, namespace, strconv.FormatUint(options.Functions.Version(namespace), 10))
+	}
+	if options.Units != nil {
+		if table := options.Units(); table != nil {
+			parts = append(parts, strconv.FormatInt(table.Timestamp.UnixNano(), 10))
+		}
+	}
+	// Parts are encoded as the JSON array, so they could not be mixed up
+	key, _ := json.Marshal(parts)
+	return string(key)
+}
+
+// observeCalculation logs the calculation with the hash of expression, its
+// latency and outcome, and updates metrics of calculations. The expression is
+// logged only when it is enabled
+func observeCalculation(r *http.Request, expression forms.Expression, options CalcOptions, latency time.Duration, err error) {
+	mode := expression.Mode
+	if mode == "" {
+		mode = forms.ModeReal
+	}
+	attrs := []slog.Attr{
+		slog.String("expression_hash", logging.Hash(expression.Expression)),
+		slog.Int("expression_length", len(expression.Expression)),
+		slog.String("mode", mode),
+		logging.Latency(latency),
+	}
+	if options.LogExpressions {
+		attrs = append(attrs, slog.String("expression", expression.Expression))
+	}
+
+	level := slog.LevelInfo
+	if err == nil {
+		attrs = append(attrs, slog.String("outcome", "success"))
+	} else {
+		status, response := NewHTTPError(err, i18n.DefaultLanguage)
+		attrs = append(attrs, slog.String("outcome", "error"), slog.String("code", response.Code))
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelWarn
+		}
+		options.Metrics.CalcError(response.Code)
+	}
+	if options.Metrics != nil {
+		options.Metrics.ObserveExpression(len(expression.Expression), len(calc.ParseExpression(expression.Expression)))
+	}
+	logging.FromContext(r.Context()).LogAttrs(r.Context(), level, "calculation", attrs...)
+}
+
+// variableValues returns values of variables decoded from JSON: numbers,
+// arrays of numbers as vectors and arrays of arrays as matrices
+func variableValues(variables map[string]any) (calc.Values, bool) {
+	values := make(calc.Values, len(variables))
+	for name, variable := range variables {
+		switch variable := variable.(type) {
+		case float64:
+			values[name] = calc.NewNumber(variable)
+		case []any:
+			rows, ok := matrixRows(variable)
+			if !ok {
+				return nil, false
+			}
+			value, err := calc.NewMatrix(rows)
+			if err != nil {
+				return nil, false
+			}
+			values[name] = value
+		default:
+			return nil, false
+		}
+	}
+	return values, true
+}
 
-	JSON(w, models.Result{Result: result})
+// matrixRows returns the rows of matrix decoded from JSON. Array of numbers is a single row
+func matrixRows(array []any) ([][]float64, bool) {
+	if row, ok := numbers(array); ok {
+		return [][]float64{row}, true
+	}
+
+	rows := make([][]float64, len(array))
+	for i, element := range array {
+		elements, ok := element.([]any)
+		if !ok {
+			return nil, false
+		}
+		if rows[i], ok = numbers(elements); !ok {
+			return nil, false
+		}
+	}
+	return rows, true
+}
+
+// numbers returns the numbers decoded from JSON array
+func numbers(array []any) ([]float64, bool) {
+	result := make([]float64, len(array))
+	for i, element := range array {
+		number, ok := element.(float64)
+		if !ok {
+			return nil, false
+		}
+		result[i] = number
+	}
+	return result, true
 }
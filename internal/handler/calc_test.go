@@ -2,13 +2,25 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Irurnnen/ordinary-calc/internal/cache"
+	"github.com/Irurnnen/ordinary-calc/internal/config"
 	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+	"github.com/Irurnnen/ordinary-calc/internal/logging"
+	"github.com/Irurnnen/ordinary-calc/internal/metrics"
 	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
 )
 
 func TestCalcHandlerErrors(t *testing.T) {
@@ -102,6 +114,14 @@ func TestCalcHandlerErrors(t *testing.T) {
 				exceptedError: "Expression has operand at the beginning or at the end",
 			},
 		},
+		{
+			name: "Expression outside of the domain",
+			args: args{
+				expression:    "(2 - 4) ^ 0.5",
+				exceptedCode:  422,
+				exceptedError: "Expression is outside of the domain",
+			},
+		},
 		{
 			name: "Expression with multiple operands",
 			args: args{
@@ -262,9 +282,859 @@ func TestCalcHandlerErrors(t *testing.T) {
 			if err != nil {
 				t.Errorf("error while decode json: %s", recorder.Body.String())
 			}
-			if result.Result != tt.args.exceptedResult {
-				t.Errorf("excepted error %f, got %f", tt.args.exceptedResult, result.Result)
+			if scalar(result) != tt.args.exceptedResult {
+				t.Errorf("excepted error %f, got %f", tt.args.exceptedResult, scalar(result))
+			}
+		})
+	}
+}
+
+func TestCalcHandlerUnits(t *testing.T) {
+	tests := []struct {
+		name           string
+		expression     string
+		exceptedCode   int
+		exceptedResult models.Result
+		exceptedError  string
+	}{
+		{
+			name:           "Expression without units",
+			expression:     "2+2*2",
+			exceptedCode:   200,
+			exceptedResult: models.Result{Result: number(6)},
+		},
+		{
+			name:           "Expression with conversion",
+			expression:     "3 h * 60 km/h in km",
+			exceptedCode:   200,
+			exceptedResult: models.Result{Result: number(180), Unit: "km"},
+		},
+		{
+			name:           "Expression with units",
+			expression:     "5 km + 300 m",
+			exceptedCode:   200,
+			exceptedResult: models.Result{Result: number(5300), Unit: "m"},
+		},
+		{
+			name:          "Expression with incompatible units",
+			expression:    "5 km + 3 h",
+			exceptedCode:  422,
+			exceptedError: "Expression has incompatible units: m and s",
+		},
+		{
+			name:          "Expression with invalid conversion",
+			expression:    "5 km in m in km",
+			exceptedCode:  422,
+			exceptedError: "Expression has invalid unit conversion",
+		},
+		{
+			name:           "Matrix",
+			expression:     "transpose([1, 2] m) in cm",
+			exceptedCode:   200,
+			exceptedResult: models.Result{Unit: "cm", Matrix: [][]float64{{100}, {200}}},
+		},
+		{
+			name:          "Matrices with incompatible shapes",
+			expression:    "[1, 2] + [1, 2, 3]",
+			exceptedCode:  422,
+			exceptedError: "Expression has incompatible shapes in +: 1x2 and 1x3",
+		},
+		{
+			name:          "Singular matrix",
+			expression:    "inv([1, 1; 1, 1])",
+			exceptedCode:  422,
+			exceptedError: "Matrix is singular",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(forms.Expression{Expression: tt.expression})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			CalcHandler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+
+			if tt.exceptedError != "" {
+				checkResultBody(t, recorder, 0, tt.exceptedError)
+				return
+			}
+
+			// Check body result
+			var result models.Result
+			err := json.NewDecoder(recorder.Body).Decode(&result)
+			if err != nil {
+				t.Errorf("error while decode json: %s", recorder.Body.String())
+			}
+			if !reflect.DeepEqual(result, tt.exceptedResult) {
+				t.Errorf("excepted result %+v, got %+v", tt.exceptedResult, result)
+			}
+		})
+	}
+}
+
+func TestCalcHandlerConversions(t *testing.T) {
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	table, err := calc.NewUnitTable("USD", map[string]float64{"EUR": 0.5}, nil, timestamp)
+	if err != nil {
+		t.Fatalf("NewUnitTable: unexcepted error %q", err)
+	}
+	handler := NewCalcHandler(CalcOptions{Units: func() *calc.UnitTable { return table }})
+
+	// Data preparation
+	body, _ := json.Marshal(forms.Expression{Expression: "100 USD in EUR"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	// Create recorder
+	recorder := httptest.NewRecorder()
+
+	// Run handler
+	handler(recorder, req)
+
+	// Check http code
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("excepted status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	// Check body result
+	var result models.Result
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("error while decode json: %s", recorder.Body.String())
+	}
+	if scalar(result) != 50 || result.Unit != "EUR" {
+		t.Errorf("excepted result 50 EUR, got %f %s", scalar(result), result.Unit)
+	}
+	if result.RatesTimestamp == nil || !result.RatesTimestamp.Equal(timestamp) {
+		t.Errorf("excepted rates timestamp %s, got %v", timestamp, result.RatesTimestamp)
+	}
+}
+
+func TestCalcHandlerComplex(t *testing.T) {
+	tests := []struct {
+		name          string
+		expression    forms.Expression
+		exceptedCode  int
+		exceptedRe    float64
+		exceptedIm    float64
+		exceptedError string
+	}{
+		{
+			name:         "Product",
+			expression:   forms.Expression{Expression: "(3+4i)*(1-2i)", Mode: forms.ModeComplex},
+			exceptedCode: 200,
+			exceptedRe:   11,
+			exceptedIm:   -2,
+		},
+		{
+			name:         "Square root of negative number",
+			expression:   forms.Expression{Expression: "sqrt(-4)", Mode: forms.ModeComplex},
+			exceptedCode: 200,
+			exceptedIm:   2,
+		},
+		{
+			name:          "Wrong number of arguments",
+			expression:    forms.Expression{Expression: "sqrt(1, 2)", Mode: forms.ModeComplex},
+			exceptedCode:  422,
+			exceptedError: "Function has wrong number of arguments",
+		},
+		{
+			name:          "Unknown mode",
+			expression:    forms.Expression{Expression: "2 + 2", Mode: "quaternion"},
+			exceptedCode:  400,
+			exceptedError: "Provided mode is unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(tt.expression)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			CalcHandler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+
+			if tt.exceptedError != "" {
+				checkResultBody(t, recorder, 0, tt.exceptedError)
+				return
+			}
+
+			// Check body result
+			var result models.Result
+			if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil {
+				t.Fatalf("error while decode json: %s", recorder.Body.String())
+			}
+			if result.Re == nil || result.Im == nil {
+				t.Fatalf("excepted re and im in result, got %+v", result)
+			}
+			if *result.Re != tt.exceptedRe || *result.Im != tt.exceptedIm {
+				t.Errorf("excepted result %v%+vi, got %v%+vi", tt.exceptedRe, tt.exceptedIm, *result.Re, *result.Im)
+			}
+		})
+	}
+}
+
+func TestCalcHandlerVariables(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		exceptedCode   int
+		exceptedResult models.Result
+		exceptedError  string
+	}{
+		{
+			name:           "Array",
+			body:           `{"expression": "percentile(50, sales) + bonus", "variables": {"sales": [3, 1, 2], "bonus": 10}}`,
+			exceptedCode:   200,
+			exceptedResult: models.Result{Result: number(12)},
+		},
+		{
+			name:           "Matrix",
+			body:           `{"expression": "a * transpose(a)", "variables": {"a": [[1, 2], [3, 4]]}}`,
+			exceptedCode:   200,
+			exceptedResult: models.Result{Matrix: [][]float64{{5, 11}, {11, 25}}},
+		},
+		{
+			name:          "Rows of different length",
+			body:          `{"expression": "sum(a)", "variables": {"a": [[1, 2], [3]]}}`,
+			exceptedCode:  400,
+			exceptedError: "Provided variables are invalid",
+		},
+		{
+			name:          "String",
+			body:          `{"expression": "sum(a)", "variables": {"a": "1"}}`,
+			exceptedCode:  400,
+			exceptedError: "Provided variables are invalid",
+		},
+		{
+			name:          "Invalid name",
+			body:          `{"expression": "1", "variables": {"1a": 1}}`,
+			exceptedCode:  422,
+			exceptedError: "Variable name is invalid",
+		},
+		{
+			name:          "Complex mode",
+			body:          `{"expression": "a", "mode": "complex", "variables": {"a": 1}}`,
+			exceptedCode:  400,
+			exceptedError: "Variables are not supported in complex mode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			CalcHandler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+
+			if tt.exceptedError != "" {
+				checkResultBody(t, recorder, 0, tt.exceptedError)
+				return
+			}
+
+			// Check body result
+			var result models.Result
+			if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil {
+				t.Fatalf("error while decode json: %s", recorder.Body.String())
+			}
+			if !reflect.DeepEqual(result, tt.exceptedResult) {
+				t.Errorf("excepted result %+v, got %+v", tt.exceptedResult, result)
 			}
 		})
 	}
 }
+
+func TestCalcHandlerLimits(t *testing.T) {
+	handler := NewCalcHandler(CalcOptions{Limits: calc.Options{MaxLength: 30, MaxTokens: 15, MaxDepth: 2, MaxSteps: 5}})
+
+	cases := []struct {
+		name          string
+		expression    string
+		cancel        bool
+		exceptedCode  int
+		exceptedError string
+	}{
+		{
+			name:          "Too long expression",
+			expression:    "1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1",
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Expression is too long",
+		},
+		{
+			name:          "Too many tokens",
+			expression:    "1+1+1+1+1+1+1+1+1",
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Expression has too many tokens",
+		},
+		{
+			name:          "Too deep nesting",
+			expression:    "((( 1 )))",
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Expression has too deep nesting of brackets",
+		},
+		{
+			name:          "Too many steps",
+			expression:    "1 + 2 + 3 + 4",
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Calculation exceeds step limit",
+		},
+		{
+			name:          "Canceled request",
+			expression:    "1 + 2",
+			cancel:        true,
+			exceptedCode:  http.StatusServiceUnavailable,
+			exceptedError: "Calculation takes too long",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(forms.Expression{Expression: tt.expression})
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			handler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+
+			// Check body error
+			var httpError forms.HTTPError
+			err := json.NewDecoder(recorder.Body).Decode(&httpError)
+			if err != nil {
+				t.Errorf("error while decode json: %s", recorder.Body.String())
+			}
+			if httpError.Error != tt.exceptedError {
+				t.Errorf("excepted error %s, got %s", tt.exceptedError, httpError.Error)
+			}
+		})
+	}
+}
+
+func TestCalcHandlerBody(t *testing.T) {
+	cases := []struct {
+		name          string
+		body          string
+		contentType   string
+		options       BodyOptions
+		exceptedCode  int
+		exceptedError string
+	}{
+		{
+			name:         "Content type with charset",
+			body:         `{"expression": "2+2"}`,
+			contentType:  "application/json; charset=utf-8",
+			options:      BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Without content type",
+			body:         `{"expression": "2+2"}`,
+			options:      BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:          "Form content type",
+			body:          `expression=2+2`,
+			contentType:   "application/x-www-form-urlencoded",
+			options:       BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode:  http.StatusUnsupportedMediaType,
+			exceptedError: "Content type must be application/json",
+		},
+		{
+			name:          "Too large body",
+			body:          `{"expression": "` + strings.Repeat("1+", 100) + `1"}`,
+			contentType:   "application/json",
+			options:       BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode:  http.StatusRequestEntityTooLarge,
+			exceptedError: "Request body is too large",
+		},
+		{
+			name:          "Unknown field",
+			body:          `{"expression": "2+2", "precision": 2}`,
+			contentType:   "application/json",
+			options:       BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode:  http.StatusBadRequest,
+			exceptedError: "Provided data has unknown fields",
+		},
+		{
+			name:          "Several objects",
+			body:          `{"expression": "2+2"} {"expression": "3+3"}`,
+			contentType:   "application/json",
+			options:       BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode:  http.StatusBadRequest,
+			exceptedError: "Provided data must be a single JSON object",
+		},
+		{
+			name:          "Garbage after object",
+			body:          `{"expression": "2+2"} garbage`,
+			contentType:   "application/json",
+			options:       BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode:  http.StatusBadRequest,
+			exceptedError: "Provided data must be a single JSON object",
+		},
+		{
+			name:          "Too large data after object",
+			body:          `{"expression": "2+2"} ` + strings.Repeat(" ", 100) + `{}`,
+			contentType:   "application/json",
+			options:       BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode:  http.StatusRequestEntityTooLarge,
+			exceptedError: "Request body is too large",
+		},
+		{
+			name:         "Several objects without single object check",
+			body:         `{"expression": "2+2"} {"expression": "3+3"}`,
+			contentType:  "application/json",
+			options:      BodyOptions{MaxSize: 100},
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:          "Invalid JSON",
+			body:          `{"expression": 2+2}`,
+			contentType:   "application/json",
+			options:       BodyOptions{MaxSize: 100, SingleObject: true},
+			exceptedCode:  http.StatusBadRequest,
+			exceptedError: "Provided data is invalid",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			handler := NewCalcHandler(CalcOptions{Body: tt.options})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			handler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+			if tt.exceptedCode == http.StatusOK {
+				return
+			}
+
+			// Check body error
+			var httpError forms.HTTPError
+			err := json.NewDecoder(recorder.Body).Decode(&httpError)
+			if err != nil {
+				t.Errorf("error while decode json: %s", recorder.Body.String())
+			}
+			if httpError.Error != tt.exceptedError {
+				t.Errorf("excepted error %s, got %s", tt.exceptedError, httpError.Error)
+			}
+		})
+	}
+}
+
+func TestCalcHandlerLogs(t *testing.T) {
+	cases := []struct {
+		name            string
+		expression      string
+		logExpressions  bool
+		exceptedOutcome string
+		exceptedCode    string
+	}{
+		{
+			name:            "Successful calculation",
+			expression:      "123456 * 789",
+			exceptedOutcome: "success",
+		},
+		{
+			name:            "Failed calculation",
+			expression:      "123456 / 0",
+			exceptedOutcome: "error",
+			exceptedCode:    "DIVISION_BY_ZERO",
+		},
+		{
+			name:            "Logging of expressions is enabled",
+			expression:      "123456 * 789",
+			logExpressions:  true,
+			exceptedOutcome: "success",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			var output bytes.Buffer
+			handler := logging.Middleware(logging.New(&output, slog.LevelInfo))(NewCalcHandler(CalcOptions{
+				Limits:         config.DefaultLimits,
+				LogExpressions: tt.logExpressions,
+			}))
+			body, _ := json.Marshal(forms.Expression{Expression: tt.expression})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			handler.ServeHTTP(recorder, req)
+
+			// Find the record of calculation
+			var record map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+				var r map[string]any
+				if err := json.Unmarshal([]byte(line), &r); err != nil {
+					t.Fatalf("record is not JSON: %s", line)
+				}
+				if r["msg"] == "calculation" {
+					record = r
+				}
+			}
+			if record == nil {
+				t.Fatalf("record of calculation is not found: %s", output.String())
+			}
+
+			// Check the record
+			if record["expression_hash"] != logging.Hash(tt.expression) || record["outcome"] != tt.exceptedOutcome {
+				t.Errorf("excepted hash %s and outcome %s, got %v", logging.Hash(tt.expression), tt.exceptedOutcome, record)
+			}
+			if code, _ := record["code"].(string); code != tt.exceptedCode {
+				t.Errorf("excepted code %q, got %q", tt.exceptedCode, code)
+			}
+			if record["request_id"] != recorder.Header().Get(logging.RequestIDHeader) {
+				t.Errorf("excepted request_id %q, got %v", recorder.Header().Get(logging.RequestIDHeader), record["request_id"])
+			}
+			if _, ok := record["latency_ms"]; !ok {
+				t.Errorf("record has no latency: %v", record)
+			}
+			if logged := strings.Contains(output.String(), "123456"); logged != tt.logExpressions {
+				t.Errorf("excepted expression in logs %v, got %v: %s", tt.logExpressions, logged, output.String())
+			}
+		})
+	}
+}
+
+func TestCalcHandlerMetrics(t *testing.T) {
+	m := metrics.New()
+	handler := NewCalcHandler(CalcOptions{Limits: config.DefaultLimits, Metrics: m})
+
+	for _, expression := range []string{"1 / 0", "2 + 2", "(1 + 2"} {
+		body, _ := json.Marshal(forms.Expression{Expression: expression})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		handler(httptest.NewRecorder(), req)
+	}
+
+	var output strings.Builder
+	m.Registry.Write(&output)
+	for _, line := range []string{
+		`ordinary_calc_calculation_errors_total{code="DIVISION_BY_ZERO"} 1`,
+		`ordinary_calc_calculation_errors_total{code="UNPAIRED_BRACKET"} 1`,
+		`ordinary_calc_expression_length_bytes_count 3`,
+		`ordinary_calc_expression_tokens_bucket{le="4"} 3`,
+		`ordinary_calc_expression_tokens_sum 10`,
+	} {
+		if !strings.Contains(output.String(), line+"\n") {
+			t.Errorf("excepted line %q in metrics:\n%s", line, output.String())
+		}
+	}
+}
+
+func TestCalcHandlerCache(t *testing.T) {
+	m := metrics.New()
+	store := functions.NewStore(functions.Limits{})
+	store.Define(functions.DefaultNamespace, "f", []string{"x"}, "x + 1")
+	handler := NewCalcHandler(CalcOptions{
+		Limits:    config.DefaultLimits,
+		Functions: store,
+		Metrics:   m,
+		Cache:     cache.New[models.Result](10, time.Minute),
+	})
+
+	cases := []struct {
+		name           string
+		expression     forms.Expression
+		namespace      string
+		define         string
+		exceptedCache  string
+		exceptedResult float64
+	}{
+		{
+			name:           "First request",
+			expression:     forms.Expression{Expression: "2 + 2"},
+			exceptedCache:  "MISS",
+			exceptedResult: 4,
+		},
+		{
+			name:           "Same tokens",
+			expression:     forms.Expression{Expression: "2+2"},
+			exceptedCache:  "HIT",
+			exceptedResult: 4,
+		},
+		{
+			name:           "Other mode",
+			expression:     forms.Expression{Expression: "2+2", Mode: forms.ModeComplex},
+			exceptedCache:  "MISS",
+			exceptedResult: 4,
+		},
+		{
+			name:           "Variables",
+			expression:     forms.Expression{Expression: "x * 2", Variables: map[string]any{"x": 2.0}},
+			exceptedCache:  "MISS",
+			exceptedResult: 4,
+		},
+		{
+			name:           "Other variables",
+			expression:     forms.Expression{Expression: "x * 2", Variables: map[string]any{"x": 3.0}},
+			exceptedCache:  "MISS",
+			exceptedResult: 6,
+		},
+		{
+			name:           "Function",
+			expression:     forms.Expression{Expression: "f(1)"},
+			exceptedCache:  "MISS",
+			exceptedResult: 2,
+		},
+		{
+			name:           "Function in other namespace",
+			expression:     forms.Expression{Expression: "f(1)"},
+			namespace:      "other",
+			exceptedCache:  "MISS",
+			exceptedResult: 2,
+		},
+		{
+			name:           "Redefined function",
+			expression:     forms.Expression{Expression: "f(1)"},
+			define:         "x + 2",
+			exceptedCache:  "MISS",
+			exceptedResult: 3,
+		},
+		{
+			name:           "Function after redefinition",
+			expression:     forms.Expression{Expression: "f( 1 )"},
+			exceptedCache:  "HIT",
+			exceptedResult: 3,
+		},
+	}
+	store.Define("other", "f", []string{"x"}, "x + 1")
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.define != "" {
+				store.Define(functions.DefaultNamespace, "f", []string{"x"}, tt.define)
+			}
+			body, _ := json.Marshal(tt.expression)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.namespace != "" {
+				req.Header.Set(NamespaceHeader, tt.namespace)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, req)
+
+			var result models.Result
+			json.NewDecoder(recorder.Body).Decode(&result)
+			if recorder.Code != http.StatusOK || scalar(result) != tt.exceptedResult {
+				t.Errorf("excepted result %g, got %d %+v", tt.exceptedResult, recorder.Code, result)
+			}
+			if got := recorder.Header().Get(CacheHeader); got != tt.exceptedCache {
+				t.Errorf("excepted %s %s, got %q", CacheHeader, tt.exceptedCache, got)
+			}
+		})
+	}
+
+	// Errors are not cached
+	for range 2 {
+		body, _ := json.Marshal(forms.Expression{Expression: "1 / 0"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		if got := recorder.Header().Get(CacheHeader); recorder.Code == http.StatusOK || got != "MISS" {
+			t.Errorf("excepted error with %s MISS, got %d %q", CacheHeader, recorder.Code, got)
+		}
+	}
+
+	var output strings.Builder
+	m.Registry.Write(&output)
+	for _, line := range []string{
+		`ordinary_calc_cache_requests_total{result="hit"} 2`,
+		`ordinary_calc_cache_requests_total{result="miss"} 9`,
+	} {
+		if !strings.Contains(output.String(), line+"\n") {
+			t.Errorf("excepted line %q in metrics:\n%s", line, output.String())
+		}
+	}
+}
+
+func TestCalcQueryHandler(t *testing.T) {
+	store := functions.NewStore(functions.Limits{})
+	store.Define(functions.DefaultNamespace, "f", []string{"x"}, "x + 1")
+	handler := NewCalcQueryHandler(CalcOptions{
+		Limits:    config.DefaultLimits,
+		Functions: store,
+		HTTPCache: HTTPCacheOptions{MaxAge: time.Minute},
+	})
+	get := func(query url.Values, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/calculate?"+query.Encode(), nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		return recorder
+	}
+
+	first := get(url.Values{"expression": {"2 + 2"}}, nil)
+	etag := first.Header().Get("ETag")
+	var result models.Result
+	json.NewDecoder(first.Body).Decode(&result)
+	if first.Code != http.StatusOK || scalar(result) != 4 || etag == "" {
+		t.Fatalf("excepted result 4 with ETag, got %d %+v with %q", first.Code, result, etag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("excepted Cache-Control %q, got %q", "public, max-age=60", got)
+	}
+	if got, excepted := first.Header().Get("Vary"), "Accept, Accept-Language, Authorization, X-API-Key, X-Namespace"; got != excepted {
+		t.Errorf("excepted Vary %q, got %q", excepted, got)
+	}
+
+	cases := []struct {
+		name           string
+		query          url.Values
+		headers        map[string]string
+		define         string
+		exceptedStatus int
+		// exceptedSameETag compares the ETag with the ETag of the first request
+		exceptedSameETag bool
+	}{
+		{
+			name:             "Same tokens",
+			query:            url.Values{"expression": {"2+2"}},
+			headers:          map[string]string{"If-None-Match": etag},
+			exceptedStatus:   http.StatusNotModified,
+			exceptedSameETag: true,
+		},
+		{
+			name:             "Weak tag in list",
+			query:            url.Values{"expression": {"2 + 2"}},
+			headers:          map[string]string{"If-None-Match": `"other", W/` + etag},
+			exceptedStatus:   http.StatusNotModified,
+			exceptedSameETag: true,
+		},
+		{
+			name:             "Other tag",
+			query:            url.Values{"expression": {"2 + 2"}},
+			headers:          map[string]string{"If-None-Match": `"other"`},
+			exceptedStatus:   http.StatusOK,
+			exceptedSameETag: true,
+		},
+		{
+			name:             "Wildcard",
+			query:            url.Values{"expression": {"2 + 2"}},
+			headers:          map[string]string{"If-None-Match": "*"},
+			exceptedStatus:   http.StatusOK,
+			exceptedSameETag: true,
+		},
+		{
+			name:           "Other mode",
+			query:          url.Values{"expression": {"2 + 2"}, "mode": {forms.ModeComplex}},
+			headers:        map[string]string{"If-None-Match": etag},
+			exceptedStatus: http.StatusOK,
+		},
+		{
+			name:           "Other namespace",
+			query:          url.Values{"expression": {"2 + 2"}},
+			headers:        map[string]string{"If-None-Match": etag, NamespaceHeader: "other"},
+			exceptedStatus: http.StatusOK,
+		},
+		{
+			name:           "Redefined function",
+			query:          url.Values{"expression": {"2 + 2"}},
+			headers:        map[string]string{"If-None-Match": etag},
+			define:         "x + 2",
+			exceptedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unknown mode with matching tag",
+			query:          url.Values{"expression": {"2 + 2"}, "mode": {"unknown"}},
+			headers:        map[string]string{"If-None-Match": etag},
+			exceptedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid expression",
+			query:          url.Values{"expression": {"2 +"}},
+			exceptedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.define != "" {
+				store.Define(functions.DefaultNamespace, "f", []string{"x"}, tt.define)
+			}
+			recorder := get(tt.query, tt.headers)
+			if recorder.Code != tt.exceptedStatus {
+				t.Fatalf("excepted status code %d, got %d: %s", tt.exceptedStatus, recorder.Code, recorder.Body.String())
+			}
+
+			got := recorder.Header().Get("ETag")
+			switch {
+			case recorder.Code == http.StatusNotModified:
+				if got != etag || recorder.Body.Len() != 0 {
+					t.Errorf("excepted ETag %s without body, got %s with %q", etag, got, recorder.Body.String())
+				}
+			case recorder.Code != http.StatusOK:
+				if got != "" || recorder.Header().Get("Cache-Control") != "no-store" {
+					t.Errorf("excepted error without ETag and with Cache-Control no-store, got %q and %q", got, recorder.Header().Get("Cache-Control"))
+				}
+			case tt.exceptedSameETag != (got == etag):
+				t.Errorf("excepted the same ETag %v, got %s and %s", tt.exceptedSameETag, etag, got)
+			}
+		})
+	}
+}
+
+func TestHTTPCacheOptionsCacheControl(t *testing.T) {
+	cases := []struct {
+		options  HTTPCacheOptions
+		excepted string
+	}{
+		{HTTPCacheOptions{MaxAge: time.Minute}, "public, max-age=60"},
+		{HTTPCacheOptions{MaxAge: time.Hour, Private: true}, "private, max-age=3600"},
+		{HTTPCacheOptions{}, "public, no-cache"},
+		{HTTPCacheOptions{Private: true}, "private, no-cache"},
+	}
+	for _, tt := range cases {
+		if got := tt.options.CacheControl(); got != tt.excepted {
+			t.Errorf("%+v: excepted %q, got %q", tt.options, tt.excepted, got)
+		}
+	}
+}
@@ -2,28 +2,102 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 )
 
-func ErrorJSONHandler(w http.ResponseWriter, errorCode int, jsonObj any) {
-	w.WriteHeader(errorCode)
+// BodyOptions configures decoding of JSON request body
+type BodyOptions struct {
+	// MaxSize is the maximum size of body in bytes. There is no limit when it is zero
+	MaxSize int64
+	// SingleObject rejects bodies with data after the JSON object
+	SingleObject bool
+}
 
-	JSON(w, jsonObj)
+func ErrorJSONHandler(w http.ResponseWriter, errorCode int, jsonObj any) {
+	writeJSON(w, errorCode, "application/json; charset=utf-8", jsonObj)
 }
 
 func JSON(w http.ResponseWriter, jsonObj any) {
+	writeJSON(w, http.StatusOK, "application/json; charset=utf-8", jsonObj)
+}
+
+// writeJSON writes the object as JSON with the status code and the content type
+func writeJSON(w http.ResponseWriter, status int, contentType string, jsonObj any) {
 	h := w.Header()
 
 	// Delete the Content-Length header in order to be sure that the
 	// entire message reaches the user
 	h.Del("Content-Length")
 
-	// Set type of response is JSON
-	h.Set("Content-Type", "application/json; charset=utf-8")
+	// Set type of response, headers could not be changed after the status code
+	h.Set("Content-Type", contentType)
+	w.WriteHeader(status)
 
 	err := json.NewEncoder(w).Encode(jsonObj)
 	if err != nil {
 		fmt.Fprint(w, "\nError while encode to json\n")
 	}
 }
+
+// DecodeJSON decodes the JSON body of request to v. Content type must be JSON
+// or not set, fields unknown to v are not allowed. The returned error could be
+// written by ErrorHandler
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v any, options BodyOptions) error {
+	// Requests without the content type are accepted for compatibility with
+	// clients which did not send it before the check was added
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return errUnsupportedMediaType
+		}
+	}
+
+	body := r.Body
+	if options.MaxSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, options.MaxSize)
+	}
+	return decodeJSON(body, v, options.SingleObject)
+}
+
+// decodeJSON decodes the JSON object without unknown fields. Data after the
+// object is an error when singleObject is set
+func decodeJSON(body io.Reader, v any, singleObject bool) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	var maxBytesError *http.MaxBytesError
+	err := decoder.Decode(v)
+	if err == nil && singleObject {
+		// Anything after the object is an error, even another object
+		if _, err = decoder.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil || !errors.As(err, &maxBytesError) {
+			err = errMultipleObjects
+		}
+	}
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &maxBytesError):
+		return errBodyTooLarge
+	case errors.Is(err, errMultipleObjects):
+		return errMultipleObjects
+	case isUnknownFieldError(err):
+		return errUnknownFields
+	default:
+		return errInvalidData
+	}
+}
+
+// isUnknownFieldError returns the true if the error is returned by the decoder
+// with disallowed unknown fields. The decoder has no type of this error, so
+// the text of error is checked, and the test pins it
+func isUnknownFieldError(err error) bool {
+	return strings.HasPrefix(err.Error(), "json: unknown field ")
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+)
+
+func TestIsUnknownFieldError(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		excepted bool
+	}{
+		{"Unknown field", `{"expression": "2+2", "precision": 2}`, true},
+		{"Invalid JSON", `{"expression": `, false},
+		{"Wrong type", `{"expression": 2}`, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				Expression string `json:"expression"`
+			}
+			decoder := json.NewDecoder(strings.NewReader(tt.data))
+			decoder.DisallowUnknownFields()
+			err := decoder.Decode(&v)
+			if err == nil {
+				t.Fatal("Decode: excepted error")
+			}
+			if got := isUnknownFieldError(err); got != tt.excepted {
+				t.Errorf("isUnknownFieldError(%q): excepted %v, got %v", err, tt.excepted, got)
+			}
+		})
+	}
+}
+
+func TestHandlersBody(t *testing.T) {
+	body := BodyOptions{MaxSize: 100, SingleObject: true}
+	options := CalcOptions{Body: body}
+	handlers := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"Integrate", NewIntegrateHandler(options), `{"expression": "x", "variable": "x", "from": 0, "to": 1`},
+		{"Sum", NewSumHandler(options), `{"expression": "i", "variable": "i", "from": 1, "to": 10`},
+		{"Plot", NewPlotHandler(options), `{"expression": "x", "variable": "x", "from": 0, "to": 1, "points": 2`},
+		{"Define function", NewDefineFunctionHandler(functions.NewStore(functions.Limits{}), body), `{"parameters": ["x"], "expression": "x"`},
+	}
+	cases := []struct {
+		name string
+		// suffix closes the object of handler
+		suffix        string
+		contentType   string
+		exceptedCode  int
+		exceptedError string
+	}{
+		{
+			name:          "Unknown field",
+			suffix:        `, "junk": 1}`,
+			contentType:   "application/json",
+			exceptedCode:  http.StatusBadRequest,
+			exceptedError: "Provided data has unknown fields",
+		},
+		{
+			name:          "Text content type",
+			suffix:        `}`,
+			contentType:   "text/plain",
+			exceptedCode:  http.StatusUnsupportedMediaType,
+			exceptedError: "Content type must be application/json",
+		},
+		{
+			name:          "Too large body",
+			suffix:        `, "junk": "` + strings.Repeat("a", 100) + `"}`,
+			contentType:   "application/json",
+			exceptedCode:  http.StatusRequestEntityTooLarge,
+			exceptedError: "Request body is too large",
+		},
+		{
+			name:          "Several objects",
+			suffix:        `} {}`,
+			contentType:   "application/json",
+			exceptedCode:  http.StatusBadRequest,
+			exceptedError: "Provided data must be a single JSON object",
+		},
+	}
+	for _, h := range handlers {
+		for _, tt := range cases {
+			t.Run(h.name+"/"+tt.name, func(t *testing.T) {
+				// Data preparation
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(h.body+tt.suffix))
+				req.Header.Set("Content-Type", tt.contentType)
+				// Create recorder
+				recorder := httptest.NewRecorder()
+
+				// Run handler
+				h.handler(recorder, req)
+
+				// Check http code and error
+				if recorder.Code != tt.exceptedCode {
+					t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+				}
+				var httpError forms.HTTPError
+				if err := json.NewDecoder(recorder.Body).Decode(&httpError); err != nil {
+					t.Fatalf("error while decode json: %s", recorder.Body.String())
+				}
+				if httpError.Error != tt.exceptedError {
+					t.Errorf("excepted error %s, got %s", tt.exceptedError, httpError.Error)
+				}
+			})
+		}
+	}
+}
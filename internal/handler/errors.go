@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+	"github.com/Irurnnen/ordinary-calc/internal/i18n"
+	"github.com/Irurnnen/ordinary-calc/internal/ratelimit"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+// ProblemContentType is the content type of errors in the format of RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// request errors
+var errInvalidData = errors.New("provided data is invalid")
+var errUnknownFields = errors.New("provided data has unknown fields")
+var errMultipleObjects = errors.New("body has data after JSON object")
+var errBodyTooLarge = errors.New("request body is too large")
+var errMessageTooLarge = errors.New("message is too large")
+var errUnsupportedMediaType = errors.New("content type is not supported")
+var errUnknownMode = errors.New("mode is unknown")
+var errInvalidVariables = errors.New("variables are invalid")
+var errComplexVariables = errors.New("variables are not supported in complex mode")
+
+// errorKind is the status code and the stable code of response with the error.
+// The message is taken from the catalog of response language by the code
+type errorKind struct {
+	status int
+	code   string
+}
+
+// internalError is the kind of errors which are not registered
+var internalError = errorKind{http.StatusInternalServerError, "INTERNAL_ERROR"}
+
+// errorRegistry binds errors to their kinds. Errors wrapping them, e.g.
+// calc.PositionError, have the same kind
+var errorRegistry = []struct {
+	err  error
+	kind errorKind
+}{
+	// Request errors
+	{errInvalidData, errorKind{http.StatusBadRequest, "INVALID_DATA"}},
+	{errUnknownFields, errorKind{http.StatusBadRequest, "UNKNOWN_FIELDS"}},
+	{errMultipleObjects, errorKind{http.StatusBadRequest, "MULTIPLE_OBJECTS"}},
+	{errBodyTooLarge, errorKind{http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE"}},
+	{errMessageTooLarge, errorKind{http.StatusRequestEntityTooLarge, "MESSAGE_TOO_LARGE"}},
+	{errUnsupportedMediaType, errorKind{http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"}},
+	{errUnknownMode, errorKind{http.StatusBadRequest, "UNKNOWN_MODE"}},
+	{errInvalidVariables, errorKind{http.StatusBadRequest, "INVALID_VARIABLES"}},
+	{errComplexVariables, errorKind{http.StatusBadRequest, "COMPLEX_VARIABLES"}},
+
+	// Expression errors
+	{calc.ErrExtraCharacters, errorKind{http.StatusUnprocessableEntity, "EXTRA_CHARACTERS"}},
+	{calc.ErrUnpairedBracket, errorKind{http.StatusUnprocessableEntity, "UNPAIRED_BRACKET"}},
+	{calc.ErrWrongBracketOrder, errorKind{http.StatusUnprocessableEntity, "WRONG_BRACKET_ORDER"}},
+	{calc.ErrMultipleOperands, errorKind{http.StatusUnprocessableEntity, "MULTIPLE_OPERANDS"}},
+	{calc.ErrMultipleNumbers, errorKind{http.StatusUnprocessableEntity, "MULTIPLE_NUMBERS"}},
+	{calc.ErrZeroByDivision, errorKind{http.StatusUnprocessableEntity, "DIVISION_BY_ZERO"}},
+	{calc.ErrExtraOperands, errorKind{http.StatusUnprocessableEntity, "EXTRA_OPERANDS"}},
+	{calc.ErrEmptyExpression, errorKind{http.StatusUnprocessableEntity, "EMPTY_EXPRESSION"}},
+	{calc.ErrParseFloat, errorKind{http.StatusUnprocessableEntity, "INVALID_NUMBER"}},
+	{calc.ErrDomain, errorKind{http.StatusUnprocessableEntity, "DOMAIN_ERROR"}},
+	{calc.ErrOverflow, errorKind{http.StatusUnprocessableEntity, "OVERFLOW"}},
+	{calc.ErrUnexpectedComma, errorKind{http.StatusUnprocessableEntity, "UNEXPECTED_COMMA"}},
+	{calc.ErrArguments, errorKind{http.StatusUnprocessableEntity, "WRONG_ARGUMENTS"}},
+	{calc.ErrInvalidConversion, errorKind{http.StatusUnprocessableEntity, "INVALID_CONVERSION"}},
+	{calc.ErrInvalidMatrix, errorKind{http.StatusUnprocessableEntity, "INVALID_MATRIX"}},
+	{calc.ErrSingularMatrix, errorKind{http.StatusUnprocessableEntity, "SINGULAR_MATRIX"}},
+	{calc.ErrNotNumber, errorKind{http.StatusUnprocessableEntity, "NOT_A_NUMBER"}},
+
+	// Calculation errors
+	{calc.ErrInvalidVariable, errorKind{http.StatusUnprocessableEntity, "INVALID_VARIABLE"}},
+	{calc.ErrIterationLimit, errorKind{http.StatusUnprocessableEntity, "ITERATION_LIMIT"}},
+	{calc.ErrInvalidRange, errorKind{http.StatusUnprocessableEntity, "INVALID_RANGE"}},
+
+	// Limit errors
+	{calc.ErrExpressionTooLong, errorKind{http.StatusUnprocessableEntity, "EXPRESSION_TOO_LONG"}},
+	{calc.ErrTooManyTokens, errorKind{http.StatusUnprocessableEntity, "TOO_MANY_TOKENS"}},
+	{calc.ErrTooDeep, errorKind{http.StatusUnprocessableEntity, "TOO_DEEP"}},
+	{calc.ErrStepLimit, errorKind{http.StatusUnprocessableEntity, "STEP_LIMIT"}},
+	{context.DeadlineExceeded, errorKind{http.StatusServiceUnavailable, "TIMEOUT"}},
+	{context.Canceled, errorKind{http.StatusServiceUnavailable, "TIMEOUT"}},
+	{ratelimit.ErrRateLimited, errorKind{http.StatusTooManyRequests, "RATE_LIMITED"}},
+
+	// Authentication errors
+	{apikeys.ErrUnauthorized, errorKind{http.StatusUnauthorized, "UNAUTHORIZED"}},
+
+	// Function errors
+	{calc.ErrInvalidFunctionName, errorKind{http.StatusUnprocessableEntity, "INVALID_FUNCTION_NAME"}},
+	{calc.ErrRecursion, errorKind{http.StatusUnprocessableEntity, "RECURSION"}},
+	{calc.ErrUnknownFunction, errorKind{http.StatusNotFound, "UNKNOWN_FUNCTION"}},
+	{calc.ErrFunctionInUse, errorKind{http.StatusConflict, "FUNCTION_IN_USE"}},
+	{functions.ErrTooManyFunctions, errorKind{http.StatusUnprocessableEntity, "TOO_MANY_FUNCTIONS"}},
+	{functions.ErrTooManyNamespaces, errorKind{http.StatusUnprocessableEntity, "TOO_MANY_NAMESPACES"}},
+}
+
+// NewHTTPError returns the status code and the response for the error with
+// the message in the language
+func NewHTTPError(err error, language string) (int, forms.HTTPError) {
+	var response forms.HTTPError
+	kind := internalError
+
+	var dimensionError *calc.DimensionError
+	var shapeError *calc.ShapeError
+	var scopeError *apikeys.ScopeError
+	switch {
+	case errors.As(err, &dimensionError):
+		left, right := dimensionError.Left.Unit(), dimensionError.Right.Unit()
+		kind = errorKind{http.StatusUnprocessableEntity, "INCOMPATIBLE_UNITS"}
+		response.Details = map[string]any{"operation": dimensionError.Operation, "left": left, "right": right}
+	case errors.As(err, &shapeError):
+		shapes := make([]string, len(shapeError.Shapes))
+		for i, shape := range shapeError.Shapes {
+			shapes[i] = shape.String()
+		}
+		kind = errorKind{http.StatusUnprocessableEntity, "INCOMPATIBLE_SHAPES"}
+		response.Details = map[string]any{"operation": shapeError.Operation, "shapes": shapes}
+	case errors.As(err, &scopeError):
+		kind = errorKind{http.StatusForbidden, "FORBIDDEN"}
+		response.Details = map[string]any{"scope": scopeError.Scope}
+	default:
+		for _, registered := range errorRegistry {
+			if errors.Is(err, registered.err) {
+				kind = registered.kind
+				break
+			}
+		}
+	}
+
+	var positionError *calc.PositionError
+	if errors.As(err, &positionError) {
+		position := positionError.Position
+		response.Position = &position
+	}
+
+	response.Error, response.Code = i18n.Message(language, kind.code, response.Details), kind.code
+	return kind.status, response
+}
+
+// ErrorHandler writes the error with the suitable status code. The error is
+// written in the format of RFC 7807 when the client prefers it to JSON. The
+// message is in the language requested by the client
+func ErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	language := i18n.Language(r)
+	status, response := NewHTTPError(err, language)
+	w.Header().Set("Content-Language", language)
+	if !prefersProblem(r) {
+		ErrorJSONHandler(w, status, response)
+		return
+	}
+
+	problem := forms.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   response.Error,
+		Instance: r.URL.Path,
+		Code:     response.Code,
+		Position: response.Position,
+		Details:  response.Details,
+	}
+	writeJSON(w, status, ProblemContentType, problem)
+}
+
+// prefersProblem returns the true if the Accept header of request prefers
+// application/problem+json to application/json otherwise false
+func prefersProblem(r *http.Request) bool {
+	qualities := acceptQualities(r)
+	return qualities[ProblemContentType] > qualities["application/json"]
+}
+
+// acceptQualities returns qualities of media types listed in the Accept
+// header of request. Media types which are not listed have zero quality,
+// wildcards are not expanded
+func acceptQualities(r *http.Request) map[string]float64 {
+	qualities := make(map[string]float64)
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, parameters, err := mime.ParseMediaType(accepted)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := parameters["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		qualities[mediaType] = max(qualities[mediaType], quality)
+	}
+	return qualities
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/i18n"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+func TestNewHTTPError(t *testing.T) {
+	_, dimensionError := calc.CalcQuantity("5 m + 2 s")
+
+	cases := []struct {
+		name           string
+		err            error
+		exceptedStatus int
+		exceptedCode   string
+		exceptedDetail map[string]any
+	}{
+		{
+			name:           "Sentinel error",
+			err:            calc.ErrZeroByDivision,
+			exceptedStatus: http.StatusUnprocessableEntity,
+			exceptedCode:   "DIVISION_BY_ZERO",
+		},
+		{
+			name:           "Wrapped error",
+			err:            fmt.Errorf("unit %q: %w", "x", calc.ErrUnknownFunction),
+			exceptedStatus: http.StatusNotFound,
+			exceptedCode:   "UNKNOWN_FUNCTION",
+		},
+		{
+			name:           "Error with details",
+			err:            dimensionError,
+			exceptedStatus: http.StatusUnprocessableEntity,
+			exceptedCode:   "INCOMPATIBLE_UNITS",
+			exceptedDetail: map[string]any{"operation": "+", "left": "m", "right": "s"},
+		},
+		{
+			name:           "Request error",
+			err:            errBodyTooLarge,
+			exceptedStatus: http.StatusRequestEntityTooLarge,
+			exceptedCode:   "BODY_TOO_LARGE",
+		},
+		{
+			name:           "Unknown error",
+			err:            errors.New("unknown error"),
+			exceptedStatus: http.StatusInternalServerError,
+			exceptedCode:   "INTERNAL_ERROR",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := NewHTTPError(tt.err, i18n.DefaultLanguage)
+			if status != tt.exceptedStatus || response.Code != tt.exceptedCode {
+				t.Errorf("excepted %d %s, got %d %s", tt.exceptedStatus, tt.exceptedCode, status, response.Code)
+			}
+			if !reflect.DeepEqual(response.Details, tt.exceptedDetail) {
+				t.Errorf("excepted details %v, got %v", tt.exceptedDetail, response.Details)
+			}
+		})
+	}
+}
+
+func TestNewHTTPErrorNumbers(t *testing.T) {
+	cases := []struct {
+		name         string
+		expression   string
+		exceptedCode string
+	}{
+		{"Several points", "1.2.3", "INVALID_NUMBER"},
+		{"Single point", ".", "INVALID_NUMBER"},
+		{"Too large fraction", strings.Repeat("9", 320) + ".5", "OVERFLOW"},
+		{"Too many digits", strings.Repeat("9", 400), "OVERFLOW"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, realErr := calc.CalcQuantity(tt.expression)
+			_, complexErr := calc.CalcComplex(tt.expression)
+			for mode, err := range map[string]error{forms.ModeReal: realErr, forms.ModeComplex: complexErr} {
+				status, response := NewHTTPError(err, i18n.DefaultLanguage)
+				if status != http.StatusUnprocessableEntity || response.Code != tt.exceptedCode {
+					t.Errorf("%s mode: excepted %d %s, got %d %s (%v)", mode, http.StatusUnprocessableEntity, tt.exceptedCode, status, response.Code, err)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorRegistry(t *testing.T) {
+	for _, registered := range errorRegistry {
+		if registered.kind.code == "" || registered.kind.status < 400 {
+			t.Errorf("error %q has incomplete kind %+v", registered.err, registered.kind)
+		}
+	}
+}
+
+func TestErrorCodesAreTranslated(t *testing.T) {
+	codes := []string{internalError.code, "INCOMPATIBLE_UNITS", "INCOMPATIBLE_SHAPES", "FORBIDDEN"}
+	for _, registered := range errorRegistry {
+		codes = append(codes, registered.kind.code)
+	}
+
+	for _, language := range i18n.Languages() {
+		for _, code := range codes {
+			if i18n.Message(language, code, nil) == "" {
+				t.Errorf("catalog %s has no message for code %s", language, code)
+			}
+		}
+	}
+}
+
+func TestErrorHandlerLanguage(t *testing.T) {
+	cases := []struct {
+		name            string
+		target          string
+		acceptLanguage  string
+		exceptedLang    string
+		exceptedMessage string
+	}{
+		{
+			name:            "Default language",
+			target:          "/api/v1/calculate",
+			exceptedLang:    "en",
+			exceptedMessage: "Expression has incompatible units: m and s",
+		},
+		{
+			name:            "Accept-Language",
+			target:          "/api/v1/calculate",
+			acceptLanguage:  "ru-RU,ru;q=0.9,en;q=0.8",
+			exceptedLang:    "ru",
+			exceptedMessage: "В выражении несовместимые единицы измерения: m и s",
+		},
+		{
+			name:            "Query parameter",
+			target:          "/api/v1/calculate?lang=ru",
+			acceptLanguage:  "en",
+			exceptedLang:    "ru",
+			exceptedMessage: "В выражении несовместимые единицы измерения: m и s",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(forms.Expression{Expression: "5 m + 2 s"})
+			req := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			CalcHandler(recorder, req)
+
+			// Check language and message
+			if language := recorder.Header().Get("Content-Language"); language != tt.exceptedLang {
+				t.Errorf("excepted language %s, got %s", tt.exceptedLang, language)
+			}
+			var httpError forms.HTTPError
+			if err := json.NewDecoder(recorder.Body).Decode(&httpError); err != nil {
+				t.Fatalf("error while decode json: %s", recorder.Body.String())
+			}
+			if httpError.Error != tt.exceptedMessage || httpError.Code != "INCOMPATIBLE_UNITS" {
+				t.Errorf("excepted INCOMPATIBLE_UNITS %q, got %s %q", tt.exceptedMessage, httpError.Code, httpError.Error)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerNegotiation(t *testing.T) {
+	cases := []struct {
+		name                string
+		accept              string
+		exceptedContentType string
+	}{
+		{
+			name:                "Without Accept",
+			exceptedContentType: "application/json; charset=utf-8",
+		},
+		{
+			name:                "Problem is accepted",
+			accept:              "application/problem+json",
+			exceptedContentType: ProblemContentType,
+		},
+		{
+			name:                "JSON is preferred",
+			accept:              "application/json, application/problem+json;q=0.5",
+			exceptedContentType: "application/json; charset=utf-8",
+		},
+		{
+			name:                "Problem is preferred",
+			accept:              "application/json;q=0.8, application/problem+json",
+			exceptedContentType: ProblemContentType,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(forms.Expression{Expression: "2 + (3 * 4"})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			CalcHandler(recorder, req)
+
+			// Check http code and content type
+			if recorder.Code != http.StatusUnprocessableEntity {
+				t.Errorf("excepted status code %d, got %d", http.StatusUnprocessableEntity, recorder.Code)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != tt.exceptedContentType {
+				t.Fatalf("excepted content type %s, got %s", tt.exceptedContentType, contentType)
+			}
+
+			// Check body error
+			if tt.exceptedContentType == ProblemContentType {
+				var problem forms.Problem
+				if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+					t.Fatalf("error while decode json: %s", recorder.Body.String())
+				}
+				excepted := forms.Problem{
+					Type:     "about:blank",
+					Title:    "Unprocessable Entity",
+					Status:   http.StatusUnprocessableEntity,
+					Detail:   "Expression has unpaired brackets",
+					Instance: "/api/v1/calculate",
+					Code:     "UNPAIRED_BRACKET",
+					Position: problem.Position,
+				}
+				if !reflect.DeepEqual(problem, excepted) || problem.Position == nil || *problem.Position != 4 {
+					t.Errorf("excepted problem %+v at position 4, got %+v", excepted, problem)
+				}
+				return
+			}
+
+			var httpError forms.HTTPError
+			if err := json.NewDecoder(recorder.Body).Decode(&httpError); err != nil {
+				t.Fatalf("error while decode json: %s", recorder.Body.String())
+			}
+			if httpError.Code != "UNPAIRED_BRACKET" || httpError.Position == nil || *httpError.Position != 4 {
+				t.Errorf("excepted UNPAIRED_BRACKET at position 4, got %+v", httpError)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// NamespaceHeader is the header with the namespace of user-defined functions.
+// It is ignored for authenticated requests, which use the namespace of the key
+const NamespaceHeader = "X-Namespace"
+
+// namespace returns the namespace of user-defined functions of the request.
+// The namespace of authenticated requests is bound to the API key, so clients
+// could not change functions of other clients
+func namespace(r *http.Request) string {
+	if key, ok := apikeys.FromContext(r.Context()); ok {
+		return key.FunctionNamespace()
+	}
+	if namespace := r.Header.Get(NamespaceHeader); namespace != "" {
+		return namespace
+	}
+	return functions.DefaultNamespace
+}
+
+// NewDefineFunctionHandler godoc
+//
+//	@Summary		Define function
+//	@Description	define the function of parameters which could be called in expressions of the same namespace, e.g. f(3, 4). The expression could call built-in functions and functions defined before, but not the function itself
+//	@Tags			Functions
+//	@Param			name		path	string			true	"Function name"
+//	@Param			X-Namespace	header	string			false	"Namespace of functions without API key"	default(default)
+//	@Param			Function	body	forms.Function	true	"Function"
+//	@Accept			json
+//	@Produce		json,application/problem+json
+//	@Success		200	{object}	models.Function
+//	@Failure		400	{object}	forms.HTTPError
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		413	{object}	forms.HTTPError
+//	@Failure		415	{object}	forms.HTTPError
+//	@Failure		422	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Failure		500	{object}	forms.HTTPError
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/functions/{name} [put]
+func NewDefineFunctionHandler(store *functions.Store, body BodyOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get data from request
+		var function forms.Function
+
+		if err := DecodeJSON(w, r, &function, body); err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		// Define the function
+		name := chi.URLParam(r, "name")
+		err := store.Define(namespace(r), name, function.Parameters, function.Expression)
+		if err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		JSON(w, models.Function{Name: name, Parameters: function.Parameters, Expression: function.Expression})
+	}
+}
+
+// NewListFunctionsHandler godoc
+//
+//	@Summary		List functions
+//	@Description	get functions of the namespace sorted by name
+//	@Tags			Functions
+//	@Param			X-Namespace	header	string	false	"Namespace of functions without API key"	default(default)
+//	@Produce		json
+//	@Success		200	{object}	models.Functions
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/functions [get]
+func NewListFunctionsHandler(store *functions.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := models.Functions{Functions: []models.Function{}}
+		for _, definition := range store.List(namespace(r)) {
+			result.Functions = append(result.Functions, models.Function(definition))
+		}
+
+		JSON(w, result)
+	}
+}
+
+// NewDeleteFunctionHandler godoc
+//
+//	@Summary		Delete function
+//	@Description	delete the function of the namespace. Function called by other functions could not be deleted
+//	@Tags			Functions
+//	@Param			name		path	string	true	"Function name"
+//	@Param			X-Namespace	header	string	false	"Namespace of functions without API key"	default(default)
+//	@Success		204
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		404	{object}	forms.HTTPError
+//	@Failure		409	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/functions/{name} [delete]
+func NewDeleteFunctionHandler(store *functions.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := store.Delete(namespace(r), chi.URLParam(r, "name"))
+		if err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// newFunctionsRouter returns the router with handlers of functions and calculation
+func newFunctionsRouter() http.Handler {
+	store := functions.NewStore(functions.Limits{})
+
+	r := chi.NewRouter()
+	r.Post("/calculate", NewCalcHandler(CalcOptions{Functions: store}))
+	r.Get("/functions", NewListFunctionsHandler(store))
+	r.Put("/functions/{name}", NewDefineFunctionHandler(store, BodyOptions{MaxSize: 1000, SingleObject: true}))
+	r.Delete("/functions/{name}", NewDeleteFunctionHandler(store))
+	return r
+}
+
+// request sends the request with the body to the router in the namespace
+func request(router http.Handler, method, target, namespace string, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if namespace != "" {
+		req.Header.Set(NamespaceHeader, namespace)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestFunctionHandlers(t *testing.T) {
+	router := newFunctionsRouter()
+
+	steps := []struct {
+		name           string
+		method         string
+		target         string
+		namespace      string
+		body           any
+		exceptedCode   int
+		exceptedResult float64
+		exceptedError  string
+	}{
+		{
+			name:         "Define function",
+			method:       http.MethodPut,
+			target:       "/functions/f",
+			body:         forms.Function{Parameters: []string{"x", "y"}, Expression: "x^2 + y"},
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:           "Call function",
+			method:         http.MethodPost,
+			target:         "/calculate",
+			body:           forms.Expression{Expression: "f(3, 4)"},
+			exceptedCode:   http.StatusOK,
+			exceptedResult: 13,
+		},
+		{
+			name:          "Call function of other namespace",
+			method:        http.MethodPost,
+			target:        "/calculate",
+			namespace:     "team",
+			body:          forms.Expression{Expression: "f(3, 4)"},
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Expression has extra characters",
+		},
+		{
+			name:         "Define function calling other function",
+			method:       http.MethodPut,
+			target:       "/functions/g",
+			body:         forms.Function{Parameters: []string{"x"}, Expression: "f(x, 1) * 2"},
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:          "Define recursive function",
+			method:        http.MethodPut,
+			target:        "/functions/f",
+			body:          forms.Function{Parameters: []string{"x", "y"}, Expression: "g(x) + y"},
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Function calls itself",
+		},
+		{
+			name:          "Define invalid function",
+			method:        http.MethodPut,
+			target:        "/functions/h",
+			body:          forms.Function{Parameters: []string{"x"}, Expression: "x + z"},
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Expression has extra characters",
+		},
+		{
+			name:          "Delete function in use",
+			method:        http.MethodDelete,
+			target:        "/functions/f",
+			exceptedCode:  http.StatusConflict,
+			exceptedError: "Function is called by other functions",
+		},
+		{
+			name:         "Delete function",
+			method:       http.MethodDelete,
+			target:       "/functions/g",
+			exceptedCode: http.StatusNoContent,
+		},
+		{
+			name:          "Delete unknown function",
+			method:        http.MethodDelete,
+			target:        "/functions/g",
+			exceptedCode:  http.StatusNotFound,
+			exceptedError: "Function is not defined",
+		},
+	}
+	for _, step := range steps {
+		recorder := request(router, step.method, step.target, step.namespace, step.body)
+
+		// Check http code
+		if recorder.Code != step.exceptedCode {
+			t.Fatalf("%s: excepted status code %d, got %d: %s", step.name, step.exceptedCode, recorder.Code, recorder.Body.String())
+		}
+		if step.exceptedError != "" || step.target == "/calculate" {
+			checkResultBody(t, recorder, step.exceptedResult, step.exceptedError)
+		}
+	}
+
+	// Check list of functions
+	recorder := request(router, http.MethodGet, "/functions", "", nil)
+	var list models.Functions
+	if err := json.NewDecoder(recorder.Body).Decode(&list); err != nil {
+		t.Fatalf("error while decode json: %s", recorder.Body.String())
+	}
+	excepted := []models.Function{{Name: "f", Parameters: []string{"x", "y"}, Expression: "x^2 + y"}}
+	if !reflect.DeepEqual(list.Functions, excepted) {
+		t.Errorf("excepted functions %+v, got %+v", excepted, list.Functions)
+	}
+
+	// Other namespace has no functions
+	recorder = request(router, http.MethodGet, "/functions", "team", nil)
+	if body := recorder.Body.String(); body != "{\"functions\":[]}\n" {
+		t.Errorf("excepted empty list of functions, got %s", body)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	cases := []struct {
+		name              string
+		header            string
+		key               *apikeys.Key
+		exceptedNamespace string
+	}{
+		{name: "Without header", exceptedNamespace: functions.DefaultNamespace},
+		{name: "Header", header: "a", exceptedNamespace: "a"},
+		{name: "Key without namespace", header: "a", key: &apikeys.Key{ID: "3f2c1e9a0b7d4c1e"}, exceptedNamespace: "3f2c1e9a0b7d4c1e"},
+		{name: "Key with namespace", header: "a", key: &apikeys.Key{ID: "3f2c1e9a0b7d4c1e", Namespace: "team"}, exceptedNamespace: "team"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/functions", nil)
+			if tt.header != "" {
+				req.Header.Set(NamespaceHeader, tt.header)
+			}
+			if tt.key != nil {
+				req = req.WithContext(apikeys.NewContext(req.Context(), *tt.key))
+			}
+			if got := namespace(req); got != tt.exceptedNamespace {
+				t.Errorf("excepted namespace %q, got %q", tt.exceptedNamespace, got)
+			}
+		})
+	}
+}
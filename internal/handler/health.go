@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/internal/version"
+)
+
+// statusOK is the state of healthy service or component
+const statusOK = "ok"
+
+// statusUnavailable is the state of service with failed readiness checks
+const statusUnavailable = "unavailable"
+
+// ReadinessCheck returns the error when the component is not ready to serve requests
+type ReadinessCheck func(ctx context.Context) error
+
+// HealthHandler reports that the process is alive. It is the liveness probe
+// and does not check components
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	JSON(w, models.Health{Status: statusOK})
+}
+
+// NewReadyHandler returns the readiness probe which runs the checks of
+// components. It responds with 503 when any check fails
+func NewReadyHandler(checks map[string]ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		result := models.Health{Status: statusOK, Checks: make(map[string]string, len(checks))}
+		for name, check := range checks {
+			if err := check(r.Context()); err != nil {
+				result.Status, result.Checks[name] = statusUnavailable, err.Error()
+				continue
+			}
+			result.Checks[name] = statusOK
+		}
+
+		if result.Status != statusOK {
+			ErrorJSONHandler(w, http.StatusServiceUnavailable, result)
+			return
+		}
+		JSON(w, result)
+	}
+}
+
+// VersionHandler reports the build mode, the git commit and the time of build
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	info := version.Get()
+	JSON(w, models.Version{
+		Mode:      info.Mode,
+		Commit:    info.Commit,
+		BuildTime: info.BuildTime,
+		GoVersion: info.GoVersion,
+	})
+}
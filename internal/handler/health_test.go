@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/internal/version"
+)
+
+func TestHealthHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	HealthHandler(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("excepted status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var health models.Health
+	if err := json.NewDecoder(recorder.Body).Decode(&health); err != nil {
+		t.Fatalf("error while decode json: %s", recorder.Body.String())
+	}
+	if health.Status != "ok" {
+		t.Errorf("excepted status ok, got %s", health.Status)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	ready := func(context.Context) error { return nil }
+	notReady := func(context.Context) error { return errors.New("table is not loaded") }
+
+	cases := []struct {
+		name           string
+		checks         map[string]ReadinessCheck
+		exceptedStatus int
+		exceptedHealth models.Health
+	}{
+		{
+			name:           "Without checks",
+			exceptedStatus: http.StatusOK,
+			exceptedHealth: models.Health{Status: "ok"},
+		},
+		{
+			name:           "All checks pass",
+			checks:         map[string]ReadinessCheck{"server": ready, "conversion": ready},
+			exceptedStatus: http.StatusOK,
+			exceptedHealth: models.Health{Status: "ok", Checks: map[string]string{"server": "ok", "conversion": "ok"}},
+		},
+		{
+			name:           "Check fails",
+			checks:         map[string]ReadinessCheck{"server": ready, "conversion": notReady},
+			exceptedStatus: http.StatusServiceUnavailable,
+			exceptedHealth: models.Health{Status: "unavailable", Checks: map[string]string{"server": "ok", "conversion": "table is not loaded"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			NewReadyHandler(tt.checks)(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if recorder.Code != tt.exceptedStatus {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedStatus, recorder.Code)
+			}
+			var health models.Health
+			if err := json.NewDecoder(recorder.Body).Decode(&health); err != nil {
+				t.Fatalf("error while decode json: %s", recorder.Body.String())
+			}
+			if !reflect.DeepEqual(health, tt.exceptedHealth) {
+				t.Errorf("excepted %+v, got %+v", tt.exceptedHealth, health)
+			}
+		})
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	commit, buildTime := version.Commit, version.BuildTime
+	version.Commit, version.BuildTime = "3f2c1e9", "2026-10-19T12:00:00Z"
+	defer func() { version.Commit, version.BuildTime = commit, buildTime }()
+
+	recorder := httptest.NewRecorder()
+	VersionHandler(recorder, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	var got models.Version
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatalf("error while decode json: %s", recorder.Body.String())
+	}
+	excepted := models.Version{
+		Mode:      version.Mode,
+		Commit:    "3f2c1e9",
+		BuildTime: "2026-10-19T12:00:00Z",
+		GoVersion: runtime.Version(),
+	}
+	if got != excepted {
+		t.Errorf("excepted %+v, got %+v", excepted, got)
+	}
+}
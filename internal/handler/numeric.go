@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Irurnnen/ordinary-calc/internal/config"
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+// IntegrateHandler integrates expressions with default options
+var IntegrateHandler = NewIntegrateHandler(CalcOptions{
+	Limits: config.DefaultLimits,
+	Body:   BodyOptions{MaxSize: config.DefaultMaxBodySize, SingleObject: true},
+})
+
+// NewIntegrateHandler godoc
+//
+//	@Summary		Integrate expression
+//	@Description	get definite integral of expression by variable using adaptive Simpson quadrature
+//	@Tags			Calculator
+//	@Param			Accept-Language	header	string			false	"Language of error messages"										default(en)
+//	@Param			lang			query	string			false	"Language of error messages, takes precedence over Accept-Language"	Enums(en, ru)
+//	@Param			Integral		body	forms.Integral	true	"Integral"
+//	@Accept			json
+//	@Produce		json,application/problem+json
+//	@Success		200	{object}	models.Result
+//	@Failure		400	{object}	forms.HTTPError
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		413	{object}	forms.HTTPError
+//	@Failure		415	{object}	forms.HTTPError
+//	@Failure		422	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Failure		500	{object}	forms.HTTPError
+//	@Failure		503	{object}	forms.HTTPError
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/integrate [post]
+func NewIntegrateHandler(options CalcOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get data from request
+		var integral forms.Integral
+
+		if err := DecodeJSON(w, r, &integral, options.Body); err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		// Calculate the integral
+		ctx, cancel := calcContext(r, options)
+		defer cancel()
+		result, err := calc.IntegrateContext(ctx, integral.Expression, integral.Variable, integral.From, integral.To, integral.Tolerance, options.Limits)
+		if err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		JSON(w, models.Result{Result: &result})
+	}
+}
+
+// SumHandler sums expressions with default options
+var SumHandler = NewSumHandler(CalcOptions{
+	Limits: config.DefaultLimits,
+	Body:   BodyOptions{MaxSize: config.DefaultMaxBodySize, SingleObject: true},
+})
+
+// NewSumHandler godoc
+//
+//	@Summary		Sum expression
+//	@Description	get sum of expression for every integer value of variable in range
+//	@Tags			Calculator
+//	@Param			Accept-Language	header	string		false	"Language of error messages"										default(en)
+//	@Param			lang			query	string		false	"Language of error messages, takes precedence over Accept-Language"	Enums(en, ru)
+//	@Param			Sum				body	forms.Sum	true	"Sum"
+//	@Accept			json
+//	@Produce		json,application/problem+json
+//	@Success		200	{object}	models.Result
+//	@Failure		400	{object}	forms.HTTPError
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		413	{object}	forms.HTTPError
+//	@Failure		415	{object}	forms.HTTPError
+//	@Failure		422	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Failure		500	{object}	forms.HTTPError
+//	@Failure		503	{object}	forms.HTTPError
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/sum [post]
+func NewSumHandler(options CalcOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get data from request
+		var sum forms.Sum
+
+		if err := DecodeJSON(w, r, &sum, options.Body); err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		// Calculate the sum
+		ctx, cancel := calcContext(r, options)
+		defer cancel()
+		result, err := calc.SumContext(ctx, sum.Expression, sum.Variable, sum.From, sum.To, options.Limits)
+		if err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		JSON(w, models.Result{Result: &result})
+	}
+}
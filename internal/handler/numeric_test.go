@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+)
+
+func TestIntegrateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		integral       forms.Integral
+		exceptedCode   int
+		exceptedResult float64
+		exceptedError  string
+	}{
+		{
+			name:           "Square",
+			integral:       forms.Integral{Expression: "x^2", Variable: "x", From: 0, To: 3},
+			exceptedCode:   200,
+			exceptedResult: 9,
+		},
+		{
+			name:          "Invalid variable",
+			integral:      forms.Integral{Expression: "x^2", Variable: "", From: 0, To: 3},
+			exceptedCode:  422,
+			exceptedError: "Variable name is invalid",
+		},
+		{
+			name:          "Singularity",
+			integral:      forms.Integral{Expression: "1/(x-0.1)", Variable: "x", From: 0, To: 1},
+			exceptedCode:  422,
+			exceptedError: "Calculation exceeds iteration limit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(tt.integral)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/integrate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			IntegrateHandler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+
+			checkResultBody(t, recorder, tt.exceptedResult, tt.exceptedError)
+		})
+	}
+}
+
+func TestSumHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		sum            forms.Sum
+		exceptedCode   int
+		exceptedResult float64
+		exceptedError  string
+	}{
+		{
+			name:           "Arithmetic progression",
+			sum:            forms.Sum{Expression: "i", Variable: "i", From: 1, To: 100},
+			exceptedCode:   200,
+			exceptedResult: 5050,
+		},
+		{
+			name:          "Too many terms",
+			sum:           forms.Sum{Expression: "i", Variable: "i", From: 1, To: 1000000000},
+			exceptedCode:  422,
+			exceptedError: "Calculation exceeds iteration limit",
+		},
+		{
+			name:          "Unknown variable",
+			sum:           forms.Sum{Expression: "i + j", Variable: "i", From: 1, To: 10},
+			exceptedCode:  422,
+			exceptedError: "Expression has extra characters",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(tt.sum)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/sum", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			SumHandler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+
+			checkResultBody(t, recorder, tt.exceptedResult, tt.exceptedError)
+		})
+	}
+}
+
+// checkResultBody checks the result or the error in body of the response
+func checkResultBody(t *testing.T, recorder *httptest.ResponseRecorder, exceptedResult float64, exceptedError string) {
+	t.Helper()
+
+	if exceptedError != "" {
+		var httpError forms.HTTPError
+		err := json.NewDecoder(recorder.Body).Decode(&httpError)
+		if err != nil {
+			t.Errorf("error while decode json: %s", recorder.Body.String())
+		}
+		if httpError.Error != exceptedError {
+			t.Errorf("excepted error %s, got %s", exceptedError, httpError.Error)
+		}
+		return
+	}
+
+	var result models.Result
+	err := json.NewDecoder(recorder.Body).Decode(&result)
+	if err != nil {
+		t.Errorf("error while decode json: %s", recorder.Body.String())
+	}
+	if got := scalar(result); result.Result == nil || got < exceptedResult-1e-6 || got > exceptedResult+1e-6 {
+		t.Errorf("excepted result %f, got %+v", exceptedResult, result)
+	}
+}
+
+// number returns the pointer to the number of excepted result
+func number(value float64) *float64 {
+	return &value
+}
+
+// scalar returns the number of result or zero when it is omitted
+func scalar(result models.Result) float64 {
+	if result.Result == nil {
+		return 0
+	}
+	return *result.Result
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"github.com/Irurnnen/ordinary-calc/internal/config"
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/i18n"
+	"github.com/Irurnnen/ordinary-calc/internal/logging"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/internal/plot"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+// SVGContentType is the content type of charts
+const SVGContentType = "image/svg+xml"
+
+// PlotHandler plots expressions with default options
+var PlotHandler = NewPlotHandler(CalcOptions{
+	Limits: config.DefaultLimits,
+	Body:   BodyOptions{MaxSize: config.DefaultMaxBodySize, SingleObject: true},
+})
+
+// NewPlotHandler godoc
+//
+//	@Summary		Plot expression
+//	@Description	get values of expression by variable at evenly spaced points. Points where the expression could not be calculated are returned as gaps with null y. The chart in SVG format is returned when image/svg+xml is accepted with the quality not lower than application/json
+//	@Tags			Calculator
+//	@Param			Accept-Language	header	string		false	"Language of error messages"										default(en)
+//	@Param			lang			query	string		false	"Language of error messages, takes precedence over Accept-Language"	Enums(en, ru)
+//	@Param			Plot			body	forms.Plot	true	"Plot"
+//	@Accept			json
+//	@Produce		json,image/svg+xml,application/problem+json
+//	@Success		200	{object}	models.Plot
+//	@Failure		400	{object}	forms.HTTPError
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		413	{object}	forms.HTTPError
+//	@Failure		415	{object}	forms.HTTPError
+//	@Failure		422	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Failure		500	{object}	forms.HTTPError
+//	@Failure		503	{object}	forms.HTTPError
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/plot [post]
+func NewPlotHandler(options CalcOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get data from request
+		var form forms.Plot
+
+		if err := DecodeJSON(w, r, &form, options.Body); err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		// Sample the expression
+		ctx, cancel := calcContext(r, options)
+		defer cancel()
+		points, err := calc.SampleContext(ctx, form.Expression, form.Variable, form.From, form.To, form.Points, options.Limits)
+		if err != nil {
+			ErrorHandler(w, r, err)
+			return
+		}
+
+		// Draw the chart if it is preferred to JSON
+		if qualities := acceptQualities(r); qualities[SVGContentType] > 0 && qualities[SVGContentType] >= qualities["application/json"] {
+			// The chart is drawn to the buffer, so the error could still be sent
+			var image bytes.Buffer
+			if err := plot.SVG(&image, points); err != nil {
+				ErrorHandler(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", SVGContentType)
+			if _, err := image.WriteTo(w); err != nil {
+				logging.FromContext(r.Context()).Warn("write chart", slog.Any("error", err))
+			}
+			return
+		}
+
+		language := i18n.Language(r)
+		w.Header().Set("Content-Language", language)
+		JSON(w, models.Plot{Points: plotPoints(points, language)})
+	}
+}
+
+// plotPoints returns points of the response. Points with errors and
+// values which are not finite are gaps with null y, because JSON has no
+// NaN and infinity
+func plotPoints(points []calc.Point, language string) []models.Point {
+	result := make([]models.Point, len(points))
+	for i, p := range points {
+		result[i].X = p.X
+		if p.Err != nil {
+			_, response := NewHTTPError(p.Err, language)
+			result[i].Error, result[i].Code = response.Error, response.Code
+			continue
+		}
+		if math.IsNaN(p.Y) || math.IsInf(p.Y, 0) {
+			continue
+		}
+		y := p.Y
+		result[i].Y = &y
+	}
+	return result
+}
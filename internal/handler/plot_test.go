@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+func TestPlotHandler(t *testing.T) {
+	// Data preparation
+	body, _ := json.Marshal(forms.Plot{Expression: "1/x", Variable: "x", From: -1, To: 1, Points: 3})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/plot", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	// Create recorder
+	recorder := httptest.NewRecorder()
+
+	// Run handler
+	PlotHandler(recorder, req)
+
+	// Check http code
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("excepted status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	// Check body
+	var result models.Plot
+	err := json.NewDecoder(recorder.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("error while decode json: %s", recorder.Body.String())
+	}
+	if len(result.Points) != 3 {
+		t.Fatalf("excepted 3 points, got %d", len(result.Points))
+	}
+	if result.Points[0].Y == nil || *result.Points[0].Y != -1 {
+		t.Errorf("excepted first point -1, got %v", result.Points[0].Y)
+	}
+	if result.Points[1].Y != nil || result.Points[1].Error != "Expression has zero by division" {
+		t.Errorf("excepted gap at the second point, got %+v", result.Points[1])
+	}
+}
+
+func TestPlotHandlerSVG(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		// exceptedSVG is false when JSON is excepted
+		exceptedSVG bool
+	}{
+		{
+			name:        "SVG",
+			accept:      "image/svg+xml",
+			exceptedSVG: true,
+		},
+		{
+			name:        "SVG preferred to JSON",
+			accept:      "application/json;q=0.9, image/svg+xml",
+			exceptedSVG: true,
+		},
+		{
+			name:   "JSON preferred to SVG",
+			accept: "application/json, image/svg+xml;q=0.5",
+		},
+		{
+			name:   "SVG is not acceptable",
+			accept: "image/svg+xml;q=0",
+		},
+		{
+			name:   "Any type",
+			accept: "*/*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(forms.Plot{Expression: "x^2", Variable: "x", From: -1, To: 1, Points: 21})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/plot", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", tt.accept)
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			PlotHandler(recorder, req)
+
+			// Check http code and type of content
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("excepted status code %d, got %d", http.StatusOK, recorder.Code)
+			}
+			contentType := recorder.Header().Get("Content-Type")
+			if isSVG := contentType == SVGContentType; isSVG != tt.exceptedSVG {
+				t.Errorf("excepted svg %v, got content type %s", tt.exceptedSVG, contentType)
+			}
+			if tt.exceptedSVG && !strings.HasPrefix(recorder.Body.String(), "<svg") {
+				t.Errorf("excepted svg image, got %s", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestPlotHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		plot          forms.Plot
+		exceptedError string
+	}{
+		{
+			name:          "Invalid range",
+			plot:          forms.Plot{Expression: "x", Variable: "x", From: 1, To: -1, Points: 10},
+			exceptedError: "Range is invalid",
+		},
+		{
+			name:          "Too wide range",
+			plot:          forms.Plot{Expression: "x", Variable: "x", From: -1.7e308, To: 1.7e308, Points: 3},
+			exceptedError: "Range is invalid",
+		},
+		{
+			name:          "Invalid expression",
+			plot:          forms.Plot{Expression: "x**2", Variable: "x", From: -1, To: 1, Points: 10},
+			exceptedError: "Expression has multiple operands",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(tt.plot)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/plot", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			PlotHandler(recorder, req)
+
+			// Check http code
+			if recorder.Code != http.StatusUnprocessableEntity {
+				t.Errorf("excepted status code %d, got %d", http.StatusUnprocessableEntity, recorder.Code)
+			}
+
+			checkResultBody(t, recorder, 0, tt.exceptedError)
+		})
+	}
+}
+
+func TestPlotHandlerLimits(t *testing.T) {
+	handler := NewPlotHandler(CalcOptions{Limits: calc.Options{MaxLength: 30, MaxSteps: 5, MaxIterations: 100}})
+
+	tests := []struct {
+		name          string
+		plot          forms.Plot
+		cancel        bool
+		exceptedCode  int
+		exceptedError string
+	}{
+		{
+			name:          "Too long expression",
+			plot:          forms.Plot{Expression: "x + x + x + x + x + x + x + x + x", Variable: "x", From: -1, To: 1, Points: 10},
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Expression is too long",
+		},
+		{
+			name:          "Too many steps",
+			plot:          forms.Plot{Expression: "x + 2 + 3 + 4", Variable: "x", From: -1, To: 1, Points: 10},
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Calculation exceeds step limit",
+		},
+		{
+			name:          "Too many points",
+			plot:          forms.Plot{Expression: "x", Variable: "x", From: -1, To: 1, Points: 101},
+			exceptedCode:  http.StatusUnprocessableEntity,
+			exceptedError: "Calculation exceeds iteration limit",
+		},
+		{
+			name:          "Canceled request",
+			plot:          forms.Plot{Expression: "x", Variable: "x", From: -1, To: 1, Points: 10},
+			cancel:        true,
+			exceptedCode:  http.StatusServiceUnavailable,
+			exceptedError: "Calculation takes too long",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			body, _ := json.Marshal(tt.plot)
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/plot", bytes.NewReader(body)).WithContext(ctx)
+			req.Header.Set("Content-Type", "application/json")
+			// Create recorder
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			handler(recorder, req)
+
+			// Check http code
+			if recorder.Code != tt.exceptedCode {
+				t.Errorf("excepted status code %d, got %d", tt.exceptedCode, recorder.Code)
+			}
+
+			checkResultBody(t, recorder, 0, tt.exceptedError)
+		})
+	}
+}
+
+func TestPlotPoints(t *testing.T) {
+	points := plotPoints([]calc.Point{
+		{X: -1, Y: 1},
+		{X: 0, Y: math.Inf(1)},
+		{X: 1, Y: math.NaN()},
+		{X: 2, Err: calc.ErrOverflow},
+	}, "en")
+
+	if points[0].Y == nil || *points[0].Y != 1 {
+		t.Errorf("excepted first point 1, got %v", points[0].Y)
+	}
+	for _, p := range points[1:] {
+		if p.Y != nil {
+			t.Errorf("excepted gap at %v, got %v", p.X, *p.Y)
+		}
+	}
+	if points[3].Code != "OVERFLOW" {
+		t.Errorf("excepted code OVERFLOW at the last point, got %q", points[3].Code)
+	}
+
+	// Gaps are encoded, so the response is not broken
+	if _, err := json.Marshal(models.Plot{Points: points}); err != nil {
+		t.Errorf("unexcepted error %q", err)
+	}
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/i18n"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+	"github.com/Irurnnen/ordinary-calc/internal/ratelimit"
+)
+
+// webSocketWriteTimeout limits the time of sending a single response
+const webSocketWriteTimeout = 10 * time.Second
+
+// WebSocketProtocol is the subprotocol of the WebSocket endpoint. Clients
+// which send subprotocols, e.g. with the API key, must send this one too
+const WebSocketProtocol = "calc"
+
+var errForbiddenOrigin = errors.New("origin is not allowed")
+var errUnsupportedProtocol = errors.New("subprotocol calc is required")
+
+// WebSocketOptions configures the WebSocket endpoint. Limits are applied to
+// every connection
+type WebSocketOptions struct {
+	// Calc configures calculation of expressions. Body is not used
+	Calc CalcOptions
+	// MaxMessageSize is the maximum size of message in bytes. Larger messages
+	// get the MESSAGE_TOO_LARGE error
+	MaxMessageSize int
+	// Rate is the number of messages per second with the Burst of messages
+	// which could be sent at once. Exceeding messages get the RATE_LIMITED
+	// error. Messages are not limited when the rate is zero
+	Rate  float64
+	Burst int
+	// IdleTimeout closes the connection without messages. Connections are
+	// not closed when it is zero
+	IdleTimeout time.Duration
+	// Done closes all connections when it is closed, e.g. on shutdown
+	Done <-chan struct{}
+	// AllowedOrigins are origins of pages which could open connections in
+	// addition to the origin of the server, e.g. https://calc.example.com.
+	// The wildcard "*" allows any origin
+	AllowedOrigins []string
+}
+
+// NewWebSocketHandler godoc
+//
+//	@Summary		Calculate expressions over WebSocket
+//	@Description	upgrades the connection to WebSocket for calculation of expressions while they are typed. The client sends JSON messages with the expression and the ID, e.g. {"id": 1, "expression": "2+2"}, which also could have mode and variables as in POST /calculate. The server responds with the same ID and either the result, e.g. {"id": 1, "result": {"result": 4}}, or the error with its code and position, e.g. {"id": 2, "error": {"error": "Expression has unpaired brackets", "code": "UNPAIRED_BRACKET", "position": 3}}. The response to the previous message could come after the client has sent the next one, so the client drops responses with IDs older than the last sent one. A new message cancels the calculation of the previous one, and the response to the cancelled message is not sent. Every connection is limited by the size of messages, the rate of messages and the idle time. Pages of other origins than the server and allowed ones get 403. Browsers could not set headers, so they send the API key as the subprotocol "apikey.<key>" together with the subprotocol "calc", which is selected by the server
+//	@Tags			Calculator
+//	@Param			X-Namespace				header	string	false	"Namespace of user-defined functions without API key"				default(default)
+//	@Param			Accept-Language			header	string	false	"Language of error messages"										default(en)
+//	@Param			lang					query	string	false	"Language of error messages, takes precedence over Accept-Language"	Enums(en, ru)
+//	@Param			Sec-WebSocket-Protocol	header	string	false	"Subprotocols: calc and optionally apikey.<key>"
+//	@Success		101
+//	@Failure		400
+//	@Failure		401	{object}	forms.HTTPError
+//	@Failure		403	{object}	forms.HTTPError
+//	@Failure		429	{object}	forms.HTTPError
+//	@Header			429	{integer}	Retry-After	"Seconds to wait before the next request"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Router			/ws [get]
+func NewWebSocketHandler(options WebSocketOptions) http.Handler {
+	return websocket.Server{
+		// Browsers open connections of any page, so the origin is checked
+		// even without API keys, otherwise any site could use the server
+		// through browsers of visitors
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			if !allowedOrigin(r, options.AllowedOrigins) {
+				return errForbiddenOrigin
+			}
+			return selectProtocol(config)
+		},
+		Handler: func(conn *websocket.Conn) {
+			newWebSocketSession(conn, options).serve()
+		},
+	}
+}
+
+// allowedOrigin reports whether the page of the request origin could open the
+// connection. Requests without the Origin header are not sent by browsers
+func allowedOrigin(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+	// The scheme is not compared, because TLS could be terminated by the proxy
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// selectProtocol responds with WebSocketProtocol when the client has sent
+// subprotocols. Other subprotocols, e.g. with the API key, are never sent back
+func selectProtocol(config *websocket.Config) error {
+	if len(config.Protocol) == 0 {
+		return nil
+	}
+	if !slices.Contains(config.Protocol, WebSocketProtocol) {
+		return errUnsupportedProtocol
+	}
+	config.Protocol = []string{WebSocketProtocol}
+	return nil
+}
+
+// webSocketSession calculates expressions from messages of a single connection
+type webSocketSession struct {
+	conn     *websocket.Conn
+	options  WebSocketOptions
+	language string
+	limiter  *ratelimit.Limiter
+
+	// writeMu serializes responses
+	writeMu sync.Mutex
+}
+
+// webSocketCalculation is the message with the context of its calculation,
+// which is cancelled by the next message
+type webSocketCalculation struct {
+	ctx     context.Context
+	message forms.Message
+}
+
+func newWebSocketSession(conn *websocket.Conn, options WebSocketOptions) *webSocketSession {
+	s := &webSocketSession{
+		conn:     conn,
+		options:  options,
+		language: i18n.Language(conn.Request()),
+	}
+	if options.Rate > 0 {
+		s.limiter = ratelimit.New(options.Rate, options.Burst)
+	}
+	return s
+}
+
+// serve reads messages until the connection is closed. Messages are
+// calculated one by one, and only the last one waits for calculation, so
+// stale messages are dropped
+func (s *webSocketSession) serve() {
+	ctx, cancel := context.WithCancel(s.conn.Request().Context())
+	defer cancel()
+
+	// Deadlines of the HTTP server are for requests, not for connections
+	s.conn.SetDeadline(time.Time{})
+	s.conn.MaxPayloadBytes = s.options.MaxMessageSize
+	if s.options.Done != nil {
+		go func() {
+			select {
+			case <-s.options.Done:
+				s.conn.Close()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	calculations := make(chan webSocketCalculation, 1)
+	calculated := make(chan struct{})
+	go func() {
+		defer close(calculated)
+		s.calculate(calculations)
+	}()
+
+	s.read(ctx, calculations)
+	close(calculations)
+	cancel()
+	<-calculated
+}
+
+// read sends messages of the client to the channel replacing the message
+// which has not been calculated yet
+func (s *webSocketSession) read(ctx context.Context, calculations chan webSocketCalculation) {
+	cancelLast := func() {}
+	defer func() { cancelLast() }()
+
+	for {
+		if s.options.IdleTimeout > 0 {
+			s.conn.SetReadDeadline(time.Now().Add(s.options.IdleTimeout))
+		}
+		var data []byte
+		err := websocket.Message.Receive(s.conn, &data)
+		if errors.Is(err, websocket.ErrFrameTooLarge) {
+			s.sendError(0, errMessageTooLarge)
+			continue
+		}
+		if err != nil {
+			// The connection is closed, idle or broken
+			return
+		}
+
+		var message forms.Message
+		if err := decodeJSON(bytes.NewReader(data), &message, true); err != nil {
+			s.sendError(message.ID, err)
+			continue
+		}
+		if s.limiter != nil {
+			if ok, _ := s.limiter.Allow(""); !ok {
+				s.sendError(message.ID, ratelimit.ErrRateLimited)
+				continue
+			}
+		}
+
+		// The new message makes the current and the waiting ones stale
+		cancelLast()
+		calcCtx, cancel := context.WithCancel(ctx)
+		cancelLast = cancel
+		select {
+		case <-calculations:
+		default:
+		}
+		calculations <- webSocketCalculation{ctx: calcCtx, message: message}
+	}
+}
+
+// calculate responds to messages from the channel. Responses to cancelled
+// calculations are not sent
+func (s *webSocketSession) calculate(calculations <-chan webSocketCalculation) {
+	for calculation := range calculations {
+		message := calculation.message
+		r := s.conn.Request().WithContext(calculation.ctx)
+		var key string
+		if s.options.Calc.Cache != nil {
+			key = cacheKey(r, message.Expression, s.options.Calc)
+		}
+		result, _, err := calculateCached(r, message.Expression, key, s.options.Calc)
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			continue
+		case err != nil:
+			s.sendError(message.ID, err)
+		default:
+			s.send(models.Message{ID: message.ID, Result: &result})
+		}
+	}
+}
+
+// sendError responds to the message with the error in the language of connection
+func (s *webSocketSession) sendError(id int64, err error) {
+	_, response := NewHTTPError(err, s.language)
+	s.send(models.Message{ID: id, Error: &response})
+}
+
+func (s *webSocketSession) send(message models.Message) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	websocket.JSON.Send(s.conn, message)
+}
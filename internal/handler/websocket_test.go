@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/Irurnnen/ordinary-calc/internal/config"
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+	"github.com/Irurnnen/ordinary-calc/internal/functions"
+	"github.com/Irurnnen/ordinary-calc/internal/models"
+)
+
+// dialWebSocket starts the server with the WebSocket handler and connects to
+// it. The query is added to the URL of connection
+func dialWebSocket(t *testing.T, options WebSocketOptions, query string) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(NewWebSocketHandler(options))
+	t.Cleanup(server.Close)
+	conn, err := websocket.Dial(strings.Replace(server.URL, "http://", "ws://", 1)+"/api/v1/ws"+query, "", server.URL)
+	if err != nil {
+		t.Fatalf("Dial: unexcepted error %q", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// exchange sends the message and receives the response
+func exchange(t *testing.T, conn *websocket.Conn, message any) models.Message {
+	t.Helper()
+
+	var err error
+	if text, ok := message.(string); ok {
+		err = websocket.Message.Send(conn, text)
+	} else {
+		err = websocket.JSON.Send(conn, message)
+	}
+	if err != nil {
+		t.Fatalf("Send: unexcepted error %q", err)
+	}
+	return receive(t, conn)
+}
+
+func receive(t *testing.T, conn *websocket.Conn) models.Message {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var response models.Message
+	if err := websocket.JSON.Receive(conn, &response); err != nil {
+		t.Fatalf("Receive: unexcepted error %q", err)
+	}
+	return response
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	conn := dialWebSocket(t, WebSocketOptions{
+		Calc:           CalcOptions{Limits: config.DefaultLimits},
+		MaxMessageSize: 1024,
+	}, "")
+
+	cases := []struct {
+		name             string
+		message          any
+		exceptedID       int64
+		exceptedResult   float64
+		exceptedCode     string
+		exceptedPosition int
+	}{
+		{
+			name:           "Result",
+			message:        forms.Message{ID: 1, Expression: forms.Expression{Expression: "2 + 2 * 2"}},
+			exceptedID:     1,
+			exceptedResult: 6,
+		},
+		{
+			name:           "Variables",
+			message:        forms.Message{ID: 2, Expression: forms.Expression{Expression: "x * 2", Variables: map[string]any{"x": 4.0}}},
+			exceptedID:     2,
+			exceptedResult: 8,
+		},
+		{
+			name:             "Syntax error",
+			message:          forms.Message{ID: 3, Expression: forms.Expression{Expression: "2 + (2"}},
+			exceptedID:       3,
+			exceptedCode:     "UNPAIRED_BRACKET",
+			exceptedPosition: 4,
+		},
+		{
+			name:         "Invalid JSON",
+			message:      `{"id": 4, "expression": `,
+			exceptedCode: "INVALID_DATA",
+		},
+		{
+			name:         "Unknown field",
+			message:      `{"id": 5, "expression": "1", "precision": 2}`,
+			exceptedID:   5,
+			exceptedCode: "UNKNOWN_FIELDS",
+		},
+		{
+			name:         "Too large message",
+			message:      forms.Message{ID: 6, Expression: forms.Expression{Expression: strings.Repeat("1+", 1000) + "1"}},
+			exceptedCode: "MESSAGE_TOO_LARGE",
+		},
+		{
+			name:           "After too large message",
+			message:        forms.Message{ID: 7, Expression: forms.Expression{Expression: "1+1"}},
+			exceptedID:     7,
+			exceptedResult: 2,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			response := exchange(t, conn, tt.message)
+			if response.ID != tt.exceptedID {
+				t.Errorf("excepted ID %d, got %d", tt.exceptedID, response.ID)
+			}
+			if tt.exceptedCode == "" {
+				if response.Error != nil || response.Result == nil || scalar(*response.Result) != tt.exceptedResult {
+					t.Errorf("excepted result %g, got %+v with error %+v", tt.exceptedResult, response.Result, response.Error)
+				}
+				return
+			}
+			if response.Result != nil || response.Error == nil || response.Error.Code != tt.exceptedCode {
+				t.Fatalf("excepted error %s, got %+v with error %+v", tt.exceptedCode, response.Result, response.Error)
+			}
+			if tt.exceptedPosition != 0 && (response.Error.Position == nil || *response.Error.Position != tt.exceptedPosition) {
+				t.Errorf("excepted position %d, got %v", tt.exceptedPosition, response.Error.Position)
+			}
+		})
+	}
+}
+
+func TestWebSocketHandlerStaleMessages(t *testing.T) {
+	// Every function calls the previous one twice, so the last one is slow
+	store := functions.NewStore(functions.Limits{})
+	store.Define(functions.DefaultNamespace, "f0", []string{"x"}, "x + 1")
+	for i := 1; i <= 40; i++ {
+		store.Define(functions.DefaultNamespace, fmt.Sprintf("f%d", i), []string{"x"}, fmt.Sprintf("f%d(x) + f%d(x)", i-1, i-1))
+	}
+	conn := dialWebSocket(t, WebSocketOptions{Calc: CalcOptions{Functions: store}}, "")
+
+	for _, message := range []forms.Message{
+		{ID: 1, Expression: forms.Expression{Expression: "f40(1)"}},
+		{ID: 2, Expression: forms.Expression{Expression: "2 + 2"}},
+	} {
+		if err := websocket.JSON.Send(conn, message); err != nil {
+			t.Fatalf("Send: unexcepted error %q", err)
+		}
+	}
+
+	// The slow calculation is cancelled, so its response is not sent before
+	// the responses to following messages
+	if response := receive(t, conn); response.ID != 2 || response.Result == nil || scalar(*response.Result) != 4 {
+		t.Fatalf("excepted the result 4 of message 2, got %+v", response)
+	}
+	response := exchange(t, conn, forms.Message{ID: 3, Expression: forms.Expression{Expression: "3 + 3"}})
+	if response.ID != 3 {
+		t.Errorf("excepted the response to message 3, got %+v", response)
+	}
+}
+
+func TestWebSocketHandlerLimits(t *testing.T) {
+	t.Run("Rate", func(t *testing.T) {
+		conn := dialWebSocket(t, WebSocketOptions{Calc: CalcOptions{Limits: config.DefaultLimits}, Rate: 0.001, Burst: 2}, "?lang=ru")
+
+		for id := int64(1); id <= 3; id++ {
+			response := exchange(t, conn, forms.Message{ID: id, Expression: forms.Expression{Expression: "1"}})
+			limited := response.Error != nil && response.Error.Code == "RATE_LIMITED"
+			if response.ID != id || limited != (id == 3) {
+				t.Errorf("message %d: excepted rate limit %v, got %+v", id, id == 3, response)
+			}
+			if limited && response.Error.Error != "Слишком много запросов, повторите позже" {
+				t.Errorf("excepted error in language of connection, got %q", response.Error.Error)
+			}
+		}
+	})
+
+	t.Run("Idle timeout", func(t *testing.T) {
+		conn := dialWebSocket(t, WebSocketOptions{Calc: CalcOptions{Limits: config.DefaultLimits}, IdleTimeout: 50 * time.Millisecond}, "")
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var response models.Message
+		if err := websocket.JSON.Receive(conn, &response); err == nil {
+			t.Errorf("excepted the connection is closed, got %+v", response)
+		}
+	})
+
+	t.Run("Done", func(t *testing.T) {
+		done := make(chan struct{})
+		conn := dialWebSocket(t, WebSocketOptions{Calc: CalcOptions{Limits: config.DefaultLimits}, Done: done}, "")
+
+		if response := exchange(t, conn, forms.Message{ID: 1, Expression: forms.Expression{Expression: "1"}}); response.ID != 1 {
+			t.Fatalf("excepted the response to message 1, got %+v", response)
+		}
+		close(done)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var response models.Message
+		if err := websocket.JSON.Receive(conn, &response); err == nil {
+			t.Errorf("excepted the connection is closed, got %+v", response)
+		}
+	})
+}
+
+func TestWebSocketHandlerHandshake(t *testing.T) {
+	cases := []struct {
+		name      string
+		allowed   []string
+		origin    string
+		protocols []string
+		// exceptedProtocol is the protocol selected by the server
+		exceptedProtocol string
+		exceptedError    bool
+	}{
+		{
+			name: "Same origin",
+		},
+		{
+			name:          "Other origin",
+			origin:        "https://evil.example.com",
+			exceptedError: true,
+		},
+		{
+			name:    "Allowed origin",
+			allowed: []string{"https://calc.example.com"},
+			origin:  "https://calc.example.com",
+		},
+		{
+			name:    "Any origin",
+			allowed: []string{"*"},
+			origin:  "https://evil.example.com",
+		},
+		{
+			name:             "Protocol with API key",
+			protocols:        []string{WebSocketProtocol, "apikey.secret"},
+			exceptedProtocol: WebSocketProtocol,
+		},
+		{
+			name:          "Only API key protocol",
+			protocols:     []string{"apikey.secret"},
+			exceptedError: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(NewWebSocketHandler(WebSocketOptions{Calc: CalcOptions{Limits: config.DefaultLimits}, AllowedOrigins: tt.allowed}))
+			defer server.Close()
+			origin := tt.origin
+			if origin == "" {
+				origin = server.URL
+			}
+			wsConfig, _ := websocket.NewConfig(strings.Replace(server.URL, "http://", "ws://", 1), origin)
+			wsConfig.Protocol = tt.protocols
+
+			conn, err := websocket.DialConfig(wsConfig)
+			if tt.exceptedError {
+				if err == nil {
+					conn.Close()
+					t.Fatal("DialConfig: excepted error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DialConfig: unexcepted error %q", err)
+			}
+			defer conn.Close()
+
+			var protocol string
+			if len(conn.Config().Protocol) > 0 {
+				protocol = conn.Config().Protocol[0]
+			}
+			if protocol != tt.exceptedProtocol {
+				t.Errorf("excepted protocol %q, got %q", tt.exceptedProtocol, protocol)
+			}
+			if response := exchange(t, conn, forms.Message{ID: 1, Expression: forms.Expression{Expression: "2+2"}}); response.Result == nil || scalar(*response.Result) != 4 {
+				t.Errorf("excepted result 4, got %+v", response)
+			}
+		})
+	}
+}
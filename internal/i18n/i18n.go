@@ -0,0 +1,134 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when the requested language has no catalog
+const DefaultLanguage = "en"
+
+// LanguageParameter is the query parameter with the language which takes
+// precedence over the Accept-Language header
+const LanguageParameter = "lang"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog has messages of a single language by their codes. Messages could
+// have placeholders like {name} which are replaced by details of the error
+type Catalog struct {
+	// Separator joins elements of lists in details, e.g. " and "
+	Separator string            `json:"separator"`
+	Messages  map[string]string `json:"messages"`
+}
+
+// catalogs are the embedded catalogs by their languages
+var catalogs = mustLoad()
+
+// mustLoad reads the embedded catalogs. The language of a catalog is the name of its file
+func mustLoad() map[string]Catalog {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	result := make(map[string]Catalog, len(files))
+	for _, file := range files {
+		data, err := locales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Errorf("catalog %s: %w", file.Name(), err))
+		}
+		result[strings.TrimSuffix(file.Name(), ".json")] = catalog
+	}
+	if _, ok := result[DefaultLanguage]; !ok {
+		panic("catalog of the default language is not found")
+	}
+	return result
+}
+
+// Languages returns the languages of catalogs sorted by name
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// Message returns the message with the code in the language where
+// placeholders are replaced by details. The message of the default language
+// is used when the language has no catalog or no such message. It returns the
+// empty string when the message is unknown
+func Message(language, code string, details map[string]any) string {
+	catalog, ok := catalogs[language]
+	message, found := catalog.Messages[code]
+	if !ok || !found {
+		catalog = catalogs[DefaultLanguage]
+		message = catalog.Messages[code]
+	}
+
+	var replacements []string
+	for name, value := range details {
+		var text string
+		switch value := value.(type) {
+		case string:
+			text = value
+		case []string:
+			text = strings.Join(value, catalog.Separator)
+		default:
+			text = fmt.Sprint(value)
+		}
+		replacements = append(replacements, "{"+name+"}", text)
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
+
+// Language returns the language of response to the request. It is taken from
+// the lang query parameter or the Accept-Language header, the default language
+// is used when there is no catalog of requested languages
+func Language(r *http.Request) string {
+	if language, ok := match(r.URL.Query().Get(LanguageParameter)); ok {
+		return language
+	}
+
+	// Choose the accepted language with the highest quality
+	language, best := DefaultLanguage, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		// Language ranges are parsed like media types to get the quality
+		tag, parameters, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := parameters["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if matched, ok := match(tag); ok && quality > best {
+			language, best = matched, quality
+		}
+	}
+	return language
+}
+
+// match returns the language of catalog for the language tag, e.g. "ru" for "ru-RU"
+func match(tag string) (string, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	_, ok := catalogs[primary]
+	return primary, ok
+}
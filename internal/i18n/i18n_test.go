@@ -0,0 +1,173 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var placeholderRegular = regexp.MustCompile(`\{\w+\}`)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	excepted := catalogs[DefaultLanguage]
+	for _, language := range Languages() {
+		catalog := catalogs[language]
+		if catalog.Separator == "" {
+			t.Errorf("catalog %s has empty separator", language)
+		}
+
+		for code, message := range excepted.Messages {
+			translation, ok := catalog.Messages[code]
+			if !ok || translation == "" {
+				t.Errorf("catalog %s has no message %s", language, code)
+				continue
+			}
+
+			// Translations must have the same placeholders
+			exceptedPlaceholders := placeholderRegular.FindAllString(message, -1)
+			placeholders := placeholderRegular.FindAllString(translation, -1)
+			slices.Sort(exceptedPlaceholders)
+			slices.Sort(placeholders)
+			if !slices.Equal(placeholders, exceptedPlaceholders) {
+				t.Errorf("message %s of catalog %s: excepted placeholders %v, got %v", code, language, exceptedPlaceholders, placeholders)
+			}
+		}
+		for code := range catalog.Messages {
+			if _, ok := excepted.Messages[code]; !ok {
+				t.Errorf("catalog %s has unknown message %s", language, code)
+			}
+		}
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	if got := Languages(); !slices.Equal(got, []string{"en", "ru"}) {
+		t.Errorf("excepted languages [en ru], got %v", got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	cases := []struct {
+		name     string
+		language string
+		code     string
+		details  map[string]any
+		excepted string
+	}{
+		{
+			name:     "Default language",
+			language: "en",
+			code:     "EMPTY_EXPRESSION",
+			excepted: "Expression is empty",
+		},
+		{
+			name:     "Translation",
+			language: "ru",
+			code:     "EMPTY_EXPRESSION",
+			excepted: "Выражение пустое",
+		},
+		{
+			name:     "Unknown language",
+			language: "de",
+			code:     "EMPTY_EXPRESSION",
+			excepted: "Expression is empty",
+		},
+		{
+			name:     "Placeholders",
+			language: "ru",
+			code:     "INCOMPATIBLE_UNITS",
+			details:  map[string]any{"operation": "+", "left": "m", "right": "s"},
+			excepted: "В выражении несовместимые единицы измерения: m и s",
+		},
+		{
+			name:     "List in placeholder",
+			language: "en",
+			code:     "INCOMPATIBLE_SHAPES",
+			details:  map[string]any{"operation": "+", "shapes": []string{"2x2", "3x1"}},
+			excepted: "Expression has incompatible shapes in +: 2x2 and 3x1",
+		},
+		{
+			name:     "Unknown code",
+			language: "en",
+			code:     "UNKNOWN",
+			excepted: "",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Message(tt.language, tt.code, tt.details); got != tt.excepted {
+				t.Errorf("excepted %q, got %q", tt.excepted, got)
+			}
+		})
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	cases := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		excepted       string
+	}{
+		{
+			name:     "Without preferences",
+			target:   "/",
+			excepted: "en",
+		},
+		{
+			name:           "Accept-Language",
+			target:         "/",
+			acceptLanguage: "ru",
+			excepted:       "ru",
+		},
+		{
+			name:           "Language with region",
+			target:         "/",
+			acceptLanguage: "ru-RU,ru;q=0.9",
+			excepted:       "ru",
+		},
+		{
+			name:           "Quality",
+			target:         "/",
+			acceptLanguage: "ru;q=0.5, en-US;q=0.8",
+			excepted:       "en",
+		},
+		{
+			name:           "Unknown languages are skipped",
+			target:         "/",
+			acceptLanguage: "de, fr;q=0.9, ru;q=0.1",
+			excepted:       "ru",
+		},
+		{
+			name:           "Unknown language",
+			target:         "/",
+			acceptLanguage: "de",
+			excepted:       "en",
+		},
+		{
+			name:           "Query parameter",
+			target:         "/?lang=ru",
+			acceptLanguage: "en",
+			excepted:       "ru",
+		},
+		{
+			name:           "Unknown query parameter",
+			target:         "/?lang=de",
+			acceptLanguage: "ru",
+			excepted:       "ru",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if got := Language(req); got != tt.excepted {
+				t.Errorf("excepted language %s, got %s", tt.excepted, got)
+			}
+		})
+	}
+}
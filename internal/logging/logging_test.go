@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		name          string
+		input         string
+		exceptedLevel slog.Level
+		exceptedError bool
+	}{
+		{name: "Debug", input: "debug", exceptedLevel: slog.LevelDebug},
+		{name: "Upper case", input: "WARN", exceptedLevel: slog.LevelWarn},
+		{name: "Error", input: "error", exceptedLevel: slog.LevelError},
+		{name: "Unknown level", input: "verbose", exceptedError: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := ParseLevel(tt.input)
+			if (err != nil) != tt.exceptedError {
+				t.Fatalf("ParseLevel(%q): unexcepted error %v", tt.input, err)
+			}
+			if err == nil && level != tt.exceptedLevel {
+				t.Errorf("ParseLevel(%q): excepted %s, got %s", tt.input, tt.exceptedLevel, level)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		name       string
+		requestID  string
+		generateID bool
+	}{
+		{
+			name:      "ID from request",
+			requestID: "abc-123",
+		},
+		{
+			name:       "Generated ID",
+			generateID: true,
+		},
+		{
+			name:       "Invalid ID is replaced",
+			requestID:  "abc 123",
+			generateID: true,
+		},
+		{
+			name:       "Too long ID is replaced",
+			requestID:  strings.Repeat("a", maxRequestIDLength+1),
+			generateID: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Data preparation
+			var output bytes.Buffer
+			var handlerID string
+			handler := Middleware(New(&output, slog.LevelInfo))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerID = RequestID(r.Context())
+				FromContext(r.Context()).Info("inside")
+				w.WriteHeader(http.StatusTeapot)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			recorder := httptest.NewRecorder()
+
+			// Run handler
+			handler.ServeHTTP(recorder, req)
+
+			// Check ID of request
+			id := recorder.Header().Get(RequestIDHeader)
+			if tt.generateID && (id == "" || id == tt.requestID) {
+				t.Errorf("excepted generated ID, got %q", id)
+			}
+			if !tt.generateID && id != tt.requestID {
+				t.Errorf("excepted ID %q, got %q", tt.requestID, id)
+			}
+			if handlerID != id {
+				t.Errorf("excepted ID %q in context, got %q", id, handlerID)
+			}
+
+			// Check records of both loggers
+			lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("excepted 2 records, got %d: %s", len(lines), output.String())
+			}
+			for _, line := range lines {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("record is not JSON: %s", line)
+				}
+				if record["request_id"] != id {
+					t.Errorf("excepted request_id %q, got %v", id, record["request_id"])
+				}
+			}
+
+			var record map[string]any
+			json.Unmarshal([]byte(lines[1]), &record)
+			if record["msg"] != "request" || record["status"] != float64(http.StatusTeapot) || record["path"] != "/api/v1/calculate" {
+				t.Errorf("unexcepted record of request %v", record)
+			}
+			if _, ok := record["latency_ms"]; !ok {
+				t.Errorf("record of request has no latency: %v", record)
+			}
+		})
+	}
+}
+
+func TestFromContextWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if FromContext(req.Context()) != slog.Default() {
+		t.Errorf("excepted default logger")
+	}
+	if id := RequestID(req.Context()); id != "" {
+		t.Errorf("excepted empty ID, got %q", id)
+	}
+}
+
+func TestHash(t *testing.T) {
+	if Hash("2 + 2") != Hash("2 + 2") {
+		t.Errorf("hash of the same text differs")
+	}
+	if Hash("2 + 2") == Hash("2 + 3") {
+		t.Errorf("hash of different texts is the same")
+	}
+	if hash := Hash("2 + 2"); len(hash) != 16 || strings.Contains(hash, "2 + 2") {
+		t.Errorf("unexcepted hash %q", hash)
+	}
+}
+
+func TestNewRequestIDWithoutRandom(t *testing.T) {
+	randRead = func([]byte) (int, error) { return 0, errors.New("no entropy") }
+	t.Cleanup(func() { randRead = rand.Read })
+
+	first, second := newRequestID(), newRequestID()
+	if first == second {
+		t.Errorf("excepted unique IDs, got %q twice", first)
+	}
+	if first == strings.Repeat("0", 32) || !validRequestID(first) {
+		t.Errorf("unexcepted ID %q", first)
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute is the route of requests which are not matched by the router.
+// Paths of such requests are not used as labels to limit the number of series
+const unmatchedRoute = "unmatched"
+
+// Metrics are the metrics of the HTTP server
+type Metrics struct {
+	Registry *Registry
+
+	requests         *Counter
+	requestDuration  *Histogram
+	calcErrors       *Counter
+	expressionLength *Histogram
+	expressionTokens *Histogram
+	cacheRequests    *Counter
+}
+
+// New returns the metrics of the HTTP server registered in the new registry
+func New() *Metrics {
+	registry := NewRegistry()
+	return &Metrics{
+		Registry: registry,
+		requests: registry.NewCounter(
+			"ordinary_calc_http_requests_total",
+			"Total number of HTTP requests by route, method and status code.",
+			"route", "method", "status",
+		),
+		requestDuration: registry.NewHistogram(
+			"ordinary_calc_http_request_duration_seconds",
+			"Latency of HTTP requests in seconds by route.",
+			[]float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			"route",
+		),
+		calcErrors: registry.NewCounter(
+			"ordinary_calc_calculation_errors_total",
+			"Total number of failed calculations by error code.",
+			"code",
+		),
+		expressionLength: registry.NewHistogram(
+			"ordinary_calc_expression_length_bytes",
+			"Length of calculated expressions in bytes.",
+			[]float64{8, 16, 32, 64, 128, 256, 512, 1024, 4096, 10000},
+		),
+		expressionTokens: registry.NewHistogram(
+			"ordinary_calc_expression_tokens",
+			"Number of tokens of calculated expressions.",
+			[]float64{4, 8, 16, 32, 64, 128, 256, 512, 1024, 5000},
+		),
+		cacheRequests: registry.NewCounter(
+			"ordinary_calc_cache_requests_total",
+			"Total number of lookups of cached results by result: hit or miss.",
+			"result",
+		),
+	}
+}
+
+// Handler returns the handler writing the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.HandlerFunc {
+	return m.Registry.Handler()
+}
+
+// Middleware counts requests and observes their latencies by route. The
+// route is the pattern of router, e.g. /api/v1/functions/{name}
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requests.Inc(route, r.Method, strconv.Itoa(status))
+		m.requestDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+// ObserveExpression observes the length and the number of tokens of
+// calculated expression. It does nothing when metrics are nil
+func (m *Metrics) ObserveExpression(length, tokens int) {
+	if m == nil {
+		return
+	}
+	m.expressionLength.Observe(float64(length))
+	m.expressionTokens.Observe(float64(tokens))
+}
+
+// CalcError counts the failed calculation by the code of error. It does
+// nothing when metrics are nil
+func (m *Metrics) CalcError(code string) {
+	if m == nil {
+		return
+	}
+	m.calcErrors.Inc(code)
+}
+
+// CacheLookup counts the lookup of cached result as the hit or the miss. It
+// does nothing when metrics are nil
+func (m *Metrics) CacheLookup(hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.Inc(result)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/api/v1/functions/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Post("/api/v1/calculate", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+
+	requests := []struct {
+		method string
+		target string
+	}{
+		{http.MethodGet, "/api/v1/functions/f"},
+		{http.MethodGet, "/api/v1/functions/g"},
+		{http.MethodPost, "/api/v1/calculate"},
+		{http.MethodGet, "/unknown/path"},
+	}
+	for _, req := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.target, nil))
+	}
+
+	cases := []struct {
+		route    string
+		method   string
+		status   string
+		excepted float64
+	}{
+		{"/api/v1/functions/{name}", http.MethodGet, "404", 2},
+		{"/api/v1/calculate", http.MethodPost, "200", 1},
+		{unmatchedRoute, http.MethodGet, "404", 1},
+	}
+	for _, tt := range cases {
+		if got := m.requests.Value(tt.route, tt.method, tt.status); got != tt.excepted {
+			t.Errorf("requests of %s %s %s: excepted %v, got %v", tt.method, tt.route, tt.status, tt.excepted, got)
+		}
+	}
+	if got := m.requestDuration.Count("/api/v1/functions/{name}"); got != 2 {
+		t.Errorf("excepted 2 observed latencies, got %d", got)
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.ObserveExpression(1, 1)
+	m.CalcError("DIVISION_BY_ZERO")
+	m.CacheLookup(true)
+}
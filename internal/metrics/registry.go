@@ -0,0 +1,263 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// collector is a metric which could be written in the text format
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry keeps metrics and writes them in the Prometheus text format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry returns the empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounter registers the counter with the names of labels
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	counter := &Counter{metric: newMetric(name, help, labels)}
+	r.register(counter)
+	return counter
+}
+
+// NewHistogram registers the histogram with the upper bounds of buckets in
+// increasing order and the names of labels
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{metric: newMetric(name, help, labels), buckets: buckets}
+	r.register(histogram)
+	return histogram
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes all metrics in the Prometheus text format. Metrics are written
+// in order of registration and series are sorted by values of labels
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	buffer := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(buffer)
+	}
+	return buffer.Flush()
+}
+
+// Handler returns the handler writing metrics of the registry
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.Write(w)
+	}
+}
+
+// metric has the name, the description and the series of a metric by values of labels
+type metric struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string][]string
+}
+
+func newMetric(name, help string, labels []string) metric {
+	return metric{name: name, help: help, labels: labels, series: map[string][]string{}}
+}
+
+// key returns the key of series with the values of labels. It panics when
+// the number of values differs from the number of labels
+func (m *metric) key(values []string) string {
+	if len(values) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s: got %d values of labels, excepted %d", m.name, len(values), len(m.labels)))
+	}
+	key := strings.Join(values, "\xff")
+	if _, ok := m.series[key]; !ok {
+		m.series[key] = slices.Clone(values)
+	}
+	return key
+}
+
+// sortedKeys returns the keys of series sorted by values of labels
+func (m *metric) sortedKeys() []string {
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// writeHeader writes the HELP and TYPE lines of metric
+func (m *metric) writeHeader(w *bufio.Writer, kind string) {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(m.help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, help, m.name, kind)
+}
+
+// labelPairs returns the labels with the values in braces, extra pairs are
+// appended to the end. It returns the empty string when there are no labels
+func (m *metric) labelPairs(values []string, extra ...string) string {
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, value := range values {
+		pairs = append(pairs, m.labels[i]+`="`+escapeLabel(value)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabel escapes the backslash, the double quote and the line feed in value of label
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats the value like the Prometheus client
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Counter is the metric which only increases, e.g. the number of requests
+type Counter struct {
+	metric
+	values map[string]float64
+}
+
+// Inc increases the counter of series with the values of labels by one
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add increases the counter of series with the values of labels. Negative values are ignored
+func (c *Counter) Add(value float64, labels ...string) {
+	if value < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = map[string]float64{}
+	}
+	c.values[c.key(labels)] += value
+}
+
+// Value returns the value of series with the values of labels
+func (c *Counter) Value(labels ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(labels, "\xff")]
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w, "counter")
+	for _, key := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(c.series[key]), formatFloat(c.values[key]))
+	}
+}
+
+// Histogram counts observed values in buckets, e.g. latencies of requests
+type Histogram struct {
+	metric
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+// histogramValue is the state of a single series of histogram
+type histogramValue struct {
+	// counts are the numbers of values in buckets, the last one is +Inf
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Observe adds the value to the series with the values of labels
+func (h *Histogram) Observe(value float64, labels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.values == nil {
+		h.values = map[string]*histogramValue{}
+	}
+
+	key := h.key(labels)
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = v
+	}
+
+	i, _ := slices.BinarySearch(h.buckets, value)
+	v.counts[i]++
+	v.sum += value
+	v.count++
+}
+
+// Count returns the number of observed values of series with the values of labels
+func (h *Histogram) Count(labels ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if v, ok := h.values[strings.Join(labels, "\xff")]; ok {
+		return v.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w, "histogram")
+	for _, key := range h.sortedKeys() {
+		labels, v := h.series[key], h.values[key]
+
+		// Buckets are cumulative
+		var cumulative uint64
+		for i, count := range v.counts {
+			cumulative += count
+			bound := math.Inf(1)
+			if i < len(h.buckets) {
+				bound = h.buckets[i]
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(labels, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(labels), formatFloat(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(labels), v.count)
+	}
+}
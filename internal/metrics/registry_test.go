@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("requests_total", "Total number of requests.", "route", "status")
+	histogram := registry.NewHistogram("latency_seconds", "Latency of requests\nin seconds.", []float64{0.1, 1})
+	registry.NewCounter("errors_total", "Total number of errors.")
+
+	counter.Inc("/b", "200")
+	counter.Add(2, "/a", "500")
+	counter.Add(-1, "/a", "500")
+	counter.Inc(`/a"\`+"\n", "200")
+	histogram.Observe(0.05)
+	histogram.Observe(0.1)
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+
+	var output strings.Builder
+	if err := registry.Write(&output); err != nil {
+		t.Fatalf("Write: unexcepted error %q", err)
+	}
+
+	excepted := `# HELP requests_total Total number of requests.
+# TYPE requests_total counter
+requests_total{route="/a\"\\\n",status="200"} 1
+requests_total{route="/a",status="500"} 2
+requests_total{route="/b",status="200"} 1
+# HELP latency_seconds Latency of requests\nin seconds.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 3.65
+latency_seconds_count 4
+# HELP errors_total Total number of errors.
+# TYPE errors_total counter
+`
+	if output.String() != excepted {
+		t.Errorf("excepted output:\n%s\ngot:\n%s", excepted, output.String())
+	}
+
+	if got := counter.Value("/a", "500"); got != 2 {
+		t.Errorf("excepted value 2, got %v", got)
+	}
+	if got := histogram.Count(); got != 4 {
+		t.Errorf("excepted count 4, got %v", got)
+	}
+}
+
+func TestRegistryWrongLabels(t *testing.T) {
+	counter := NewRegistry().NewCounter("requests_total", "Total number of requests.", "route")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("excepted panic for wrong number of labels")
+		}
+	}()
+	counter.Inc("/a", "200")
+}
+
+// sampleRegular matches lines of samples in the Prometheus text format
+var sampleRegular = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*(\{([a-zA-Z_][a-zA-Z0-9_]*="([^"\\]|\\.)*",?)*\})? (\+Inf|-Inf|NaN|[-+0-9.eE]+)$`)
+
+// commentRegular matches lines of HELP and TYPE in the Prometheus text format
+var commentRegular = regexp.MustCompile(`^# (HELP [a-zA-Z_:][a-zA-Z0-9_:]* .*|TYPE [a-zA-Z_:][a-zA-Z0-9_:]* (counter|gauge|histogram|summary|untyped))$`)
+
+func TestHandlerFormat(t *testing.T) {
+	m := New()
+	m.requests.Inc("/api/v1/calculate", http.MethodPost, "200")
+	m.requestDuration.Observe(0.003, "/api/v1/calculate")
+	m.CalcError("DIVISION_BY_ZERO")
+	m.ObserveExpression(9, 5)
+
+	recorder := httptest.NewRecorder()
+	m.Handler()(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("excepted content type %s, got %s", ContentType, contentType)
+	}
+
+	// Every line must be a comment or a sample, every metric must have its type
+	types := map[string]string{}
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case commentRegular.MatchString(line):
+			if fields := strings.Fields(line); fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+		case sampleRegular.MatchString(line):
+			name := strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0]
+			if _, ok := types[name]; !ok {
+				base := regexp.MustCompile(`_(bucket|sum|count)$`).ReplaceAllString(name, "")
+				if types[base] != "histogram" {
+					t.Errorf("sample %q has no type", line)
+				}
+			}
+		default:
+			t.Errorf("line %q is not in the Prometheus text format", line)
+		}
+	}
+
+	exceptedTypes := map[string]string{
+		"ordinary_calc_http_requests_total":           "counter",
+		"ordinary_calc_http_request_duration_seconds": "histogram",
+		"ordinary_calc_calculation_errors_total":      "counter",
+		"ordinary_calc_expression_length_bytes":       "histogram",
+		"ordinary_calc_expression_tokens":             "histogram",
+	}
+	for name, kind := range exceptedTypes {
+		if types[name] != kind {
+			t.Errorf("excepted metric %s of type %s, got %q", name, kind, types[name])
+		}
+	}
+}
@@ -1,5 +1,28 @@
 package models
 
+import (
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/forms"
+)
+
 type Result struct {
-	Result float64 `json:"result" example:"65.5"`
+	// Result is the number, it is omitted when the result is a matrix
+	Result *float64 `json:"result,omitempty" example:"65.5"`
+	Unit   string   `json:"unit,omitempty" example:"km/h"`
+	// Matrix has the rows of the result when it is a matrix
+	Matrix [][]float64 `json:"matrix,omitempty"`
+	// Re and Im are the parts of the result in complex mode
+	Re *float64 `json:"re,omitempty" example:"11"`
+	Im *float64 `json:"im,omitempty" example:"-2"`
+	// RatesTimestamp is the time of the currency rates used in calculation
+	RatesTimestamp *time.Time `json:"rates_timestamp,omitempty" example:"2026-10-19T12:00:00Z"`
+}
+
+// Message is the response of WebSocket server to the message with the same
+// ID. It has either the result or the error
+type Message struct {
+	ID     int64            `json:"id" example:"1"`
+	Result *Result          `json:"result,omitempty"`
+	Error  *forms.HTTPError `json:"error,omitempty"`
 }
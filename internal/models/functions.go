@@ -0,0 +1,11 @@
+package models
+
+type Function struct {
+	Name       string   `json:"name" example:"f"`
+	Parameters []string `json:"parameters" example:"x,y"`
+	Expression string   `json:"expression" example:"x^2 + y"`
+}
+
+type Functions struct {
+	Functions []Function `json:"functions"`
+}
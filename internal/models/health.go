@@ -0,0 +1,17 @@
+package models
+
+// Health is the state of service and its components
+type Health struct {
+	Status string `json:"status" example:"ok"`
+	// Checks are the states of components by their names, every state is
+	// "ok" or the error of component
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Version is the information of build
+type Version struct {
+	Mode      string `json:"mode" example:"release"`
+	Commit    string `json:"commit" example:"3f2c1e9"`
+	BuildTime string `json:"build_time" example:"2026-10-19T12:00:00Z"`
+	GoVersion string `json:"go_version" example:"go1.23.1"`
+}
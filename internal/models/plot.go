@@ -0,0 +1,12 @@
+package models
+
+type Point struct {
+	X     float64  `json:"x" example:"0.5"`
+	Y     *float64 `json:"y" example:"2"`
+	Error string   `json:"error,omitempty" example:"Expression has zero by division"`
+	Code  string   `json:"code,omitempty" example:"DIVISION_BY_ZERO"`
+}
+
+type Plot struct {
+	Points []Point `json:"points"`
+}
@@ -0,0 +1,99 @@
+package plot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+const (
+	Width  = 640
+	Height = 480
+
+	// padding is a free space around the plot area
+	padding = 20
+)
+
+// SVG draws the points as a line chart in SVG format. Points with errors
+// break the line, so gaps in the domain of expression are seen on the chart
+func SVG(w io.Writer, points []calc.Point) error {
+	minX, maxX, minY, maxY := bounds(points)
+
+	// Functions to change coordinates of the point to coordinates of the image
+	scaleX := func(x float64) float64 {
+		return padding + (x-minX)/(maxX-minX)*(Width-2*padding)
+	}
+	scaleY := func(y float64) float64 {
+		return Height - padding - (y-minY)/(maxY-minY)*(Height-2*padding)
+	}
+
+	var path strings.Builder
+	gap := true
+	for _, p := range points {
+		if p.Err != nil {
+			gap = true
+			continue
+		}
+		command := "L"
+		if gap {
+			command = "M"
+			gap = false
+		}
+		fmt.Fprintf(&path, "%s%.2f %.2f ", command, scaleX(p.X), scaleY(p.Y))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, Width, Height, Width, Height)
+	b.WriteString("\n")
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`, Width, Height)
+	b.WriteString("\n")
+
+	// Draw axes when they are inside the plot area
+	if minX <= 0 && 0 <= maxX {
+		fmt.Fprintf(&b, `<line x1="%.2f" y1="0" x2="%.2f" y2="%d" stroke="gray"/>`, scaleX(0), scaleX(0), Height)
+		b.WriteString("\n")
+	}
+	if minY <= 0 && 0 <= maxY {
+		fmt.Fprintf(&b, `<line x1="0" y1="%.2f" x2="%d" y2="%.2f" stroke="gray"/>`, scaleY(0), Width, scaleY(0))
+		b.WriteString("\n")
+	}
+
+	if path.Len() != 0 {
+		fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="steelblue" stroke-width="2"/>`, strings.TrimSpace(path.String()))
+		b.WriteString("\n")
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// bounds returns the ranges of coordinates of points without errors. Empty
+// ranges are widened so that the scale of the plot is always defined
+func bounds(points []calc.Point) (minX, maxX, minY, maxY float64) {
+	minX, maxX = math.Inf(1), math.Inf(-1)
+	minY, maxY = math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		if p.Err == nil {
+			minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+		}
+	}
+
+	if math.IsInf(minX, 1) {
+		minX, maxX = 0, 0
+	}
+	if math.IsInf(minY, 1) {
+		minY, maxY = 0, 0
+	}
+	if minX == maxX {
+		minX, maxX = minX-1, maxX+1
+	}
+	if minY == maxY {
+		minY, maxY = minY-1, maxY+1
+	}
+	return minX, maxX, minY, maxY
+}
@@ -0,0 +1,57 @@
+package plot
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Irurnnen/ordinary-calc/pkg/calc"
+)
+
+func TestSVG(t *testing.T) {
+	points := []calc.Point{
+		{X: -1, Y: -1},
+		{X: -0.5, Y: -2},
+		{X: 0, Err: calc.ErrZeroByDivision},
+		{X: 0.5, Y: 2},
+		{X: 1, Y: 1},
+	}
+
+	var b strings.Builder
+	if err := SVG(&b, points); err != nil {
+		t.Fatalf("SVG: unexcepted error %q", err)
+	}
+
+	// Check that the image is a well-formed XML
+	decoder := xml.NewDecoder(strings.NewReader(b.String()))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("SVG is not well-formed: %q", err)
+			}
+			break
+		}
+	}
+
+	// The gap splits the line into two parts
+	if count := strings.Count(b.String(), "M"); count != 2 {
+		t.Errorf("SVG: got %d parts of line, excepted 2", count)
+	}
+}
+
+func TestSVGWithoutPoints(t *testing.T) {
+	points := []calc.Point{
+		{X: 0, Err: calc.ErrDomain},
+		{X: 1, Err: calc.ErrDomain},
+	}
+
+	var b strings.Builder
+	if err := SVG(&b, points); err != nil {
+		t.Fatalf("SVG: unexcepted error %q", err)
+	}
+	if strings.Contains(b.String(), "<path") {
+		t.Errorf("SVG: excepted no line, got %s", b.String())
+	}
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Irurnnen/ordinary-calc/internal/apikeys"
+)
+
+// sweepInterval is the period of removing buckets of idle clients
+const sweepInterval = time.Minute
+
+// ErrRateLimited is returned when the client has exceeded the rate of requests
+var ErrRateLimited = errors.New("too many requests")
+
+// bucket is the token bucket of a single client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter limits the rate of requests of every client by the token bucket.
+// The bucket of client holds up to burst tokens and is refilled with rate
+// tokens per second, every request takes one token
+type Limiter struct {
+	rate  float64
+	burst float64
+	// now returns the current time, it is replaced in tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New returns the limiter with the rate of requests per second and the burst
+// of requests which could be done at once
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes the token from the bucket of the key. It returns false and the
+// time after which the token will be available when the bucket is empty
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep removes buckets which have been refilled, they are the same as new ones
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// KeyFunc returns the key identifying the client of request
+type KeyFunc func(r *http.Request) string
+
+// ClientKey identifies the client by the ID of authenticated API key or by
+// the remote IP when the request is not authenticated
+func ClientKey(r *http.Request) string {
+	if key, ok := apikeys.FromContext(r.Context()); ok {
+		return "id:" + key.ID
+	}
+	return IPKey(r)
+}
+
+// IPKey identifies the client by the remote IP. It limits requests before
+// authentication, so clients could not guess API keys without limits
+func IPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Middleware rejects requests of clients which have exceeded the rate of the
+// limiter. The Retry-After header of rejected request has the number of
+// seconds to wait, and the body is written by the error handler with
+// ErrRateLimited. Requests are not limited when the limiter is nil
+func Middleware(l *Limiter, key KeyFunc, errorHandler func(w http.ResponseWriter, r *http.Request, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(key(r))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				errorHandler(w, r, ErrRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
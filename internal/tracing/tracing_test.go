@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// restoreProvider restores the global tracer provider at the end of test
+func restoreProvider(t *testing.T) {
+	t.Helper()
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	restoreProvider(t)
+	previous := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Setup: unexcepted error %q", err)
+	}
+	if otel.GetTracerProvider() != previous {
+		t.Errorf("excepted the tracer provider is not changed")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: unexcepted error %q", err)
+	}
+}
+
+func TestSetupExportsSpans(t *testing.T) {
+	restoreProvider(t)
+
+	// Collector records paths and content types of requests
+	var mu sync.Mutex
+	var requests []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Content-Type"))
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	shutdown, err := Setup(context.Background(), collector.URL)
+	if err != nil {
+		t.Fatalf("Setup: unexcepted error %q", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: unexcepted error %q", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	excepted := "POST " + DefaultPath + " application/x-protobuf"
+	if len(requests) != 1 || requests[0] != excepted {
+		t.Errorf("excepted request %q, got %q", excepted, requests)
+	}
+}
+
+func TestSetupInvalidEndpoint(t *testing.T) {
+	restoreProvider(t)
+	if _, err := Setup(context.Background(), "http://[::1"); err == nil {
+		t.Errorf("Setup: excepted error for invalid endpoint")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	restoreProvider(t)
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Put("/api/v1/functions/{name}", func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		name         string
+		method       string
+		target       string
+		exceptedSpan string
+	}{
+		{
+			name:         "Route",
+			method:       http.MethodPut,
+			target:       "/api/v1/functions/f",
+			exceptedSpan: "PUT /api/v1/functions/{name}",
+		},
+		{
+			name:         "Unknown route",
+			method:       http.MethodGet,
+			target:       "/unknown",
+			exceptedSpan: "GET",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.target, nil))
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 || spans[0].Name != tt.exceptedSpan {
+				t.Errorf("excepted span %q, got %v", tt.exceptedSpan, spans.Snapshots())
+			}
+		})
+	}
+}
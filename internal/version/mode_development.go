@@ -0,0 +1,7 @@
+//go:build !release && !debug
+
+package version
+
+// Mode is the build mode chosen by the build tag. Builds without the release
+// and debug tags, e.g. tests, are development builds
+const Mode = "development"
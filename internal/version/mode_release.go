@@ -0,0 +1,6 @@
+//go:build release
+
+package version
+
+// Mode is the build mode chosen by the build tag
+const Mode = "release"
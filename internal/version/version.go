@@ -0,0 +1,48 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Commit and BuildTime are set by the linker, e.g.
+//
+//	go build -ldflags "-X github.com/Irurnnen/ordinary-calc/internal/version.Commit=$(git rev-parse HEAD)"
+var (
+	// Commit is the git commit of build
+	Commit = ""
+	// BuildTime is the time of build in RFC 3339 format
+	BuildTime = ""
+)
+
+// unknown is reported when the information of build is not available
+const unknown = "unknown"
+
+// Info is the information of build
+type Info struct {
+	// Mode is the build mode chosen by the build tag: release or debug
+	Mode      string
+	Commit    string
+	BuildTime string
+	GoVersion string
+}
+
+// Get returns the information of build. The commit is taken from the version
+// control information embedded by Go when it is not set by the linker
+func Get() Info {
+	info := Info{Mode: Mode, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok && info.Commit == "" {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = unknown
+	}
+	return info
+}
@@ -0,0 +1,38 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	commit, buildTime := Commit, BuildTime
+	defer func() { Commit, BuildTime = commit, buildTime }()
+
+	cases := []struct {
+		name      string
+		commit    string
+		buildTime string
+		excepted  Info
+	}{
+		{
+			name:      "Set by linker",
+			commit:    "3f2c1e9",
+			buildTime: "2026-10-19T12:00:00Z",
+			excepted:  Info{Mode: "development", Commit: "3f2c1e9", BuildTime: "2026-10-19T12:00:00Z", GoVersion: runtime.Version()},
+		},
+		{
+			// Test binaries have no version control information
+			name:     "Not set",
+			excepted: Info{Mode: "development", Commit: "unknown", BuildTime: "unknown", GoVersion: runtime.Version()},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			Commit, BuildTime = tt.commit, tt.buildTime
+			if got := Get(); got != tt.excepted {
+				t.Errorf("excepted %+v, got %+v", tt.excepted, got)
+			}
+		})
+	}
+}
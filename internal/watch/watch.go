@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// DefaultInterval is the period of checking files for changes
+const DefaultInterval = 5 * time.Second
+
+// state is the state of file at the last loading
+type state struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher reloads files when any of them changes. Changes are found by
+// polling the modification time and the size of files, so it works on any
+// file system, including mounted volumes of containers
+type Watcher struct {
+	// kind is the name of loaded data in logs, e.g. "API keys"
+	kind     string
+	paths    []string
+	interval time.Duration
+	load     func() error
+
+	states map[string]state
+}
+
+// New calls load to load files of paths. Run calls it again when any of files
+// changes. Kind is the name of loaded data in logs
+func New(kind string, interval time.Duration, load func() error, paths ...string) (*Watcher, error) {
+	w := &Watcher{
+		kind:     kind,
+		paths:    paths,
+		interval: interval,
+		load:     load,
+		states:   make(map[string]state, len(paths)),
+	}
+	if err := w.reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run checks files for changes until the context is done. Errors of loading
+// are logged, so previously loaded data should be kept by load on errors
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := w.changed()
+			if err != nil {
+				slog.Error("Error while checking files", "kind", w.kind, "paths", w.paths, "error", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := w.reload(); err != nil {
+				slog.Error("Error while reloading files", "kind", w.kind, "paths", w.paths, "error", err)
+				continue
+			}
+			slog.Info("Files have been reloaded", "kind", w.kind, "paths", w.paths)
+		}
+	}
+}
+
+// changed returns the true if any of files was modified after the last loading
+func (w *Watcher) changed() (bool, error) {
+	changed := false
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		if s := w.states[path]; !info.ModTime().Equal(s.modTime) || info.Size() != s.size {
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+func (w *Watcher) reload() error {
+	states := make(map[string]state, len(w.paths))
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		states[path] = state{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	// Remember broken files so that they are not reloaded until next change
+	w.states = states
+	return w.load()
+}
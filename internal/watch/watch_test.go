@@ -0,0 +1,81 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	errLoad := errors.New("load error")
+
+	if _, err := New("test", time.Second, func() error { return nil }, path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("excepted os.ErrNotExist for missing file, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("first"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New("test", time.Second, func() error { return errLoad }, path); !errors.Is(err, errLoad) {
+		t.Errorf("excepted the error of load, got %v", err)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.txt"), filepath.Join(dir, "second.txt")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, []byte("first"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var loads atomic.Int32
+	var broken atomic.Bool
+	watcher, err := New("test", 10*time.Millisecond, func() error {
+		loads.Add(1)
+		if broken.Load() {
+			return errors.New("broken file")
+		}
+		return nil
+	}, first, second)
+	if err != nil {
+		t.Fatalf("New: unexcepted error %q", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	// Files are not reloaded without changes
+	time.Sleep(50 * time.Millisecond)
+	if got := loads.Load(); got != 1 {
+		t.Fatalf("excepted 1 load of unchanged files, got %d", got)
+	}
+
+	// Broken file is loaded once until the next change
+	broken.Store(true)
+	if err := os.WriteFile(second, []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := loads.Load(); got != 2 {
+		t.Fatalf("excepted 2 loads after the change of any file, got %d", got)
+	}
+
+	broken.Store(false)
+	if err := os.WriteFile(second, []byte("second file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for loads.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := loads.Load(); got != 3 {
+		t.Errorf("excepted 3 loads after the fix of file, got %d", got)
+	}
+}
@@ -1,65 +1,202 @@
 package calc
 
 import (
-	"math"
+	"context"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
-const disallowedSymbolsRegular = `[^0-9\.+\-*\/()^\s]`
+// disallowedSymbolsRegular matches symbols which are not allowed in expression.
+// Symbols of operators of the engine are added to it
+const disallowedSymbolsRegular = `[^0-9a-zA-Z\.,;()\[\]\s%s]`
 const spacesRegular = `\s`
+const namesRegular = `[a-zA-Z][a-zA-Z0-9]*`
 
-var operands = "+-*/^"
-var priority = map[string]int{"+": 1, "-": 1, "*": 2, "/": 2, "^": 3}
+// ImplicitMultiplication is the operator inserted between a number and a name
+// written one after another, as in "5 km". It has higher priority than "*" and
+// "/", so "100 km / 2 h" is the same as "(100 km) / (2 h)"
+const ImplicitMultiplication = "·"
 
+// UnaryMinus is the operator of negation. ToPostfix replaces "-" by it when
+// "-" is at the beginning, after an opening bracket, a separator or an operator
+const UnaryMinus = "~"
+
+// Variables binds names used in an expression to their values
+type Variables map[string]float64
+
+// isName returns the true if there is a variable with the name
+func (v Variables) isName(name string) bool {
+	_, ok := v[name]
+	return ok
+}
+
+// Calc calculates the expression with the default engine
 func Calc(expression string) (float64, error) {
-	// Checking validity of expression
-	if err := ValidateExpression(expression); err != nil {
+	return defaultEngine.Calc(expression)
+}
+
+// CalcWithVariables calculates the expression in which names are replaced
+// by values of variables. Names without value are treated as extra characters
+func CalcWithVariables(expression string, variables Variables) (float64, error) {
+	return defaultEngine.CalcWithVariables(expression, variables)
+}
+
+// CalcValue calculates the expression like CalcWithVariables, but values of
+// variables and the result could be matrices, e.g. "inv([1,2;3,4]) * b"
+func CalcValue(expression string, values Values) (Value, error) {
+	return defaultEngine.CalcValue(expression, values)
+}
+
+// CalcContext calculates the expression like Calc within limits of options.
+// Calculation stops with the error of context when it is done
+func CalcContext(ctx context.Context, expression string, options Options) (float64, error) {
+	return defaultEngine.CalcContext(ctx, expression, options)
+}
+
+// Calc calculates the expression with operators, functions and constants of the engine
+func (e *Engine) Calc(expression string) (float64, error) {
+	return e.CalcWithVariables(expression, nil)
+}
+
+// CalcWithVariables calculates the expression like the package-level
+// CalcWithVariables with operators, functions and constants of the engine
+func (e *Engine) CalcWithVariables(expression string, variables Variables) (float64, error) {
+	// Prepare postfix tokens of expression
+	postfixTokens, err := e.compile(expression, variables.isName, Options{}, nil)
+	if err != nil {
 		return 0, err
 	}
 
-	// Tokenize expression
-	tokens := ParseExpression(expression)
-
-	// Validate Tokens
-	if err := ValidateTokens(tokens); err != nil {
+	// Calculate the expression
+	result, err := e.EvalExpressionWithVariables(postfixTokens, variables)
+	if err != nil {
 		return 0, err
 	}
 
-	// Change to postfix
-	postfixTokens := ToPostfix(tokens)
+	return result, nil
+}
+
+// CalcValue calculates the expression like the package-level CalcValue with
+// operators, functions and constants of the engine
+func (e *Engine) CalcValue(expression string, values Values) (Value, error) {
+	if err := values.validate(); err != nil {
+		return Value{}, err
+	}
+
+	// Prepare postfix tokens of expression
+	postfixTokens, err := e.compile(expression, values.isName, Options{}, nil)
+	if err != nil {
+		return Value{}, err
+	}
 
 	// Calculate the expression
-	result, err := EvalExpression(postfixTokens)
+	return e.evalValue(postfixTokens, e.withConstants(values.lookup), nil, nil)
+}
+
+// CalcContext calculates the expression like the package-level CalcContext
+// with operators, functions and constants of the engine
+func (e *Engine) CalcContext(ctx context.Context, expression string, options Options) (float64, error) {
+	// Prepare postfix tokens of expression
+	limit := newLimiter(ctx, options)
+	postfixTokens, err := e.compile(expression, Variables(nil).isName, options, limit)
 	if err != nil {
 		return 0, err
 	}
 
-	return result, nil
+	// Calculate the expression
+	result, err := traceEval(limit, postfixTokens, func() (Value, error) {
+		return e.evalValue(postfixTokens, e.withConstants(Values(nil).lookup), nil, limit)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !result.IsNumber() {
+		return 0, ErrNotNumber
+	}
+	return result.Number(), nil
 }
 
+// compile validates the expression and changes it to postfix tokens which
+// could be evaluated several times with different values of variables. Every
+// name in expression must satisfy isName or be a function or a constant.
+// Expression must be within limits of options, stages are traced in the
+// context of limit
+func (e *Engine) compile(expression string, isName func(name string) bool, options Options, limit *limiter) ([]string, error) {
+	if err := options.checkLength(expression); err != nil {
+		return nil, err
+	}
+
+	// Checking validity of expression
+	err := limit.validate(expression, func(expression string) error {
+		return e.validateExpression(expression, e.withNames(isName))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokenize expression
+	tokens := limit.parse(expression)
+	if err := options.checkTokens(tokens); err != nil {
+		return nil, err
+	}
+
+	// Validate Tokens
+	if err := e.validateTokens(tokens); err != nil {
+		return nil, err
+	}
+
+	// Change to postfix
+	return limit.postfix(tokens, e.toPostfix), nil
+}
+
+// ValidateExpression checks expression for extra characters and for correction
+// of brackets. Expression without variables must not contain any names except
+// functions
 func ValidateExpression(expression string) error {
+	return defaultEngine.validateExpression(expression, defaultEngine.withNames(Variables(nil).isName))
+}
+
+// validateExpression checks expression for extra characters and for correction
+// of brackets. Every name in expression must satisfy isName. Errors are
+// returned as PositionError
+func (e *Engine) validateExpression(expression string, isName func(name string) bool) error {
 	// Check disallowed symbols
-	re := regexp.MustCompile(disallowedSymbolsRegular)
-	if re.MatchString(expression) {
-		return ErrExtraCharacters
+	if location := e.disallowed.FindStringIndex(expression); location != nil {
+		return newPositionError(ErrExtraCharacters, expression, location[0])
 	}
 
-	// Check correction of brackets
-	var bracketBalance int
-	for _, v := range expression {
-		if v == '(' {
-			bracketBalance++
-		} else if v == ')' {
-			bracketBalance--
-			if bracketBalance < 0 {
-				return ErrWrongBracketOrder
+	// Check that every name is known
+	re := regexp.MustCompile(namesRegular)
+	for _, location := range re.FindAllStringIndex(expression, -1) {
+		if !isName(expression[location[0]:location[1]]) {
+			return newPositionError(ErrExtraCharacters, expression, location[0])
+		}
+	}
+
+	// Check correction of round and square brackets
+	type bracket struct {
+		symbol rune
+		offset int
+	}
+	var brackets []bracket
+	for offset, v := range expression {
+		switch v {
+		case '(', '[':
+			brackets = append(brackets, bracket{symbol: v, offset: offset})
+		case ')', ']':
+			if len(brackets) == 0 {
+				return newPositionError(ErrWrongBracketOrder, expression, offset)
 			}
+			if opening := brackets[len(brackets)-1]; (opening.symbol == '(') != (v == ')') {
+				return newPositionError(ErrUnpairedBracket, expression, offset)
+			}
+			brackets = brackets[:len(brackets)-1]
 		}
 	}
-	if bracketBalance != 0 {
-		return ErrUnpairedBracket
+	if len(brackets) != 0 {
+		return newPositionError(ErrUnpairedBracket, expression, brackets[len(brackets)-1].offset)
 	}
 
 	return nil
@@ -72,53 +209,218 @@ func RemoveSpaces(expression string) string {
 
 func ParseExpression(expression string) []string {
 	var tokens []string
-	var number string
-
-	// Delete all space in expression
-	expression = RemoveSpaces(expression)
+	var number, name string
 
-	for _, character := range expression {
-		if IsNumber(string(character)) {
-			number += string(character)
-			continue
-		}
-		if number != "" {
-			tokens = append(tokens, number)
-			number = ""
+	for _, r := range expression {
+		character := string(r)
+		switch {
+		case unicode.IsSpace(r):
+			// Spaces are skipped, but they still separate names
+			if name != "" {
+				tokens = append(tokens, name)
+				name = ""
+			}
+		case IsLetter(character):
+			if number != "" {
+				tokens = append(tokens, number)
+				number = ""
+			}
+			name += character
+		case IsNumber(character) && name != "":
+			// Digits after letters are part of the name. The point is kept
+			// in the name too, so "x.5" is rejected as a whole instead of
+			// being split into the name and the number
+			name += character
+		case IsNumber(character):
+			if name != "" {
+				tokens = append(tokens, name)
+				name = ""
+			}
+			number += character
+		default:
+			if number != "" {
+				tokens = append(tokens, number)
+				number = ""
+			}
+			if name != "" {
+				tokens = append(tokens, name)
+				name = ""
+			}
+			tokens = append(tokens, character)
 		}
-		tokens = append(tokens, string(character))
 	}
 	if len(number) != 0 {
 		tokens = append(tokens, number)
 	}
+	if len(name) != 0 {
+		tokens = append(tokens, name)
+	}
 	return tokens
 }
 
 // ValidateTokens checks tokens for several errors: ErrEmptyExpression,
-// ErrMultipleOperands, ErrMultipleNumbers, ErrExtraOperands
+// ErrMultipleOperands, ErrMultipleNumbers, ErrExtraOperands, ErrUnexpectedComma,
+// ErrInvalidMatrix
 func ValidateTokens(tokens []string) error {
+	return defaultEngine.validateTokens(tokens)
+}
+
+// validateTokens checks tokens like ValidateTokens with operators of the engine
+func (e *Engine) validateTokens(tokens []string) error {
 	// Check exists of expression
 	if len(tokens) == 0 {
 		return ErrEmptyExpression
 	}
+	// Check names with points, e.g. "x.5"
+	for _, token := range tokens {
+		if IsLetter(token[:1]) && !IsIdentifier(token) {
+			return ErrExtraCharacters
+		}
+	}
+
 	// Check multiple operators or multiple numbers
 	for i := 1; i < len(tokens); i++ {
-		if IsOperand(tokens[i-1]) && IsOperand(tokens[i]) {
+		if e.isOperator(tokens[i-1]) && e.isOperator(tokens[i]) && !e.isUnary(tokens, i) {
 			return ErrMultipleOperands
 		}
-		if IsNumber(tokens[i-1]) && IsNumber(tokens[i]) {
+		if IsValue(tokens[i-1]) && IsValue(tokens[i]) {
+			return ErrMultipleNumbers
+		}
+	}
+
+	// Check unary operators used between operands
+	for i, token := range tokens {
+		if _, ok := e.binary[token]; !ok && e.isOperator(token) && !e.isUnary(tokens, i) {
 			return ErrMultipleNumbers
 		}
 	}
 
 	// Check operands at the beginning and end
-	if IsOperand(tokens[0]) || IsOperand(tokens[len(tokens)-1]) {
+	if (e.isOperator(tokens[0]) && !e.isUnary(tokens, 0)) || e.isOperator(tokens[len(tokens)-1]) {
 		return ErrExtraOperands
 	}
 
+	// Check operands next to brackets and separators
+	for i := 1; i < len(tokens); i++ {
+		if e.isOperator(tokens[i-1]) && isClosing(tokens[i]) {
+			return ErrExtraOperands
+		}
+		if isOpening(tokens[i-1]) && e.isOperator(tokens[i]) && !e.isUnary(tokens, i) {
+			return ErrExtraOperands
+		}
+	}
+
+	// Check that commas separate only arguments of functions and elements of
+	// matrices, and that all rows of matrix have the same length
+	type bracket struct {
+		call, matrix bool
+		// elements is the number of elements in the current row of matrix
+		// and columns is the length of the first row
+		elements, columns int
+	}
+	var brackets []bracket
+	for i, token := range tokens {
+		switch token {
+		case "(":
+			brackets = append(brackets, bracket{call: IsFunction(tokens, i-1)})
+		case "[":
+			brackets = append(brackets, bracket{matrix: true})
+		case ",", ";", ")", "]":
+			if len(brackets) == 0 {
+				if token == "," {
+					return ErrUnexpectedComma
+				}
+				if token == ";" {
+					return ErrInvalidMatrix
+				}
+				continue
+			}
+			b := &brackets[len(brackets)-1]
+			if !b.matrix {
+				switch token {
+				case ",":
+					if !b.call {
+						return ErrUnexpectedComma
+					}
+				case ";":
+					return ErrInvalidMatrix
+				default:
+					brackets = brackets[:len(brackets)-1]
+				}
+				continue
+			}
+
+			// Elements of matrix could not be empty
+			if isOpening(tokens[i-1]) {
+				return ErrInvalidMatrix
+			}
+			b.elements++
+			if token == "," {
+				continue
+			}
+			if b.columns == 0 {
+				b.columns = b.elements
+			}
+			if b.elements != b.columns {
+				return ErrInvalidMatrix
+			}
+			b.elements = 0
+			if token == "]" {
+				brackets = brackets[:len(brackets)-1]
+			}
+		}
+	}
+
 	return nil
 }
 
+// isOpening returns the true if token is an opening bracket or a separator
+// followed by a new argument or element otherwise false
+func isOpening(token string) bool {
+	return token == "(" || token == "[" || token == "," || token == ";"
+}
+
+// isClosing returns the true if token is a closing bracket or a separator
+// which ends an argument or element otherwise false
+func isClosing(token string) bool {
+	return token == ")" || token == "]" || token == "," || token == ";"
+}
+
+// isUnary returns the true if tokens[i] is an unary operator, e.g. "-" used
+// as negation, otherwise false
+func (e *Engine) isUnary(tokens []string, i int) bool {
+	if _, ok := e.unary[unaryToken(tokens[i])]; !ok {
+		return false
+	}
+	return i == 0 || isOpening(tokens[i-1]) || e.isOperator(tokens[i-1])
+}
+
+// IsFunction returns the true if tokens[i] is a name of function, that is
+// a name followed by an opening bracket, otherwise false
+func IsFunction(tokens []string, i int) bool {
+	return i >= 0 && i+1 < len(tokens) && IsIdentifier(tokens[i]) && tokens[i+1] == "("
+}
+
+// FunctionCall returns the token of function call with the number of arguments
+// used in reverse Polish notation, e.g. "sqrt(1)"
+func FunctionCall(name string, arguments int) string {
+	return name + "(" + strconv.Itoa(arguments) + ")"
+}
+
+// ParseFunctionCall returns the name and the number of arguments of function
+// call token made by FunctionCall
+func ParseFunctionCall(token string) (string, int, bool) {
+	name, arguments, found := strings.Cut(token, "(")
+	if !found || !IsIdentifier(name) || !strings.HasSuffix(arguments, ")") {
+		return "", 0, false
+	}
+	count, err := strconv.Atoi(strings.TrimSuffix(arguments, ")"))
+	if err != nil {
+		return "", 0, false
+	}
+	return name, count, true
+}
+
 // IsNumber returns the true if token is a number otherwise false
 func IsNumber(token string) bool {
 	for _, v := range token {
@@ -130,34 +432,140 @@ func IsNumber(token string) bool {
 	return true
 }
 
-// IsOperand returns the true if token is an operand otherwise false
+// InsertImplicitMultiplication inserts ImplicitMultiplication between a number
+// or a closing bracket and the name following it
+func InsertImplicitMultiplication(tokens []string) []string {
+	var result []string
+	for i, token := range tokens {
+		if i > 0 && IsIdentifier(token) && (IsNumber(tokens[i-1]) || tokens[i-1] == ")" || tokens[i-1] == "]") {
+			result = append(result, ImplicitMultiplication)
+		}
+		result = append(result, token)
+	}
+	return result
+}
+
+// IsLetter returns the true if token consists of latin letters otherwise false
+func IsLetter(token string) bool {
+	for _, v := range token {
+		if (v < 'a' || v > 'z') && (v < 'A' || v > 'Z') {
+			return false
+		}
+	}
+	return token != ""
+}
+
+// IsIdentifier returns the true if token is a name of variable otherwise false
+func IsIdentifier(token string) bool {
+	if token == "" || !IsLetter(token[:1]) {
+		return false
+	}
+	for _, v := range token[1:] {
+		if !IsLetter(string(v)) && (v < '0' || v > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValue returns the true if token is a number or a name otherwise false
+func IsValue(token string) bool {
+	return IsNumber(token) || IsIdentifier(token)
+}
+
+// IsOperand returns the true if token is an operator of the default engine otherwise false
 func IsOperand(token string) bool {
-	return strings.Contains(operands, token) && len(token) == 1
+	return defaultEngine.isOperator(token)
 }
 
-// To Postfix changes the order of tokens to reverse Polish notation
+// To Postfix changes the order of tokens to reverse Polish notation. Functions
+// are called by tokens made by FunctionCall after their arguments and matrices
+// are made by tokens made by MatrixLiteral after their elements
 func ToPostfix(tokens []string) []string {
+	return defaultEngine.toPostfix(tokens)
+}
+
+// toPostfix changes the order of tokens like ToPostfix with precedence and
+// associativity of operators of the engine
+func (e *Engine) toPostfix(tokens []string) []string {
 	var stack []string
 	var output []string
 
-	for _, token := range tokens {
-		if IsNumber(token) {
+	// Brackets opened by function calls with their names and numbers of
+	// commas, and by matrices with their numbers of elements and rows
+	type bracket struct {
+		function string
+		commas   int
+		matrix   bool
+		rows     int
+	}
+	var brackets []bracket
+
+	for i, token := range tokens {
+		if IsFunction(tokens, i) {
+			brackets = append(brackets, bracket{function: token})
+			continue
+		}
+		if IsValue(token) {
 			output = append(output, token)
 			continue
 		}
 		switch token {
 		case "(":
 			stack = append(stack, token)
-		case ")":
-			for len(stack) != 0 && stack[len(stack)-1] != "(" {
+			if i == 0 || !IsFunction(tokens, i-1) {
+				brackets = append(brackets, bracket{})
+			}
+		case "[":
+			stack = append(stack, token)
+			brackets = append(brackets, bracket{matrix: true, rows: 1})
+		case ",", ";":
+			for len(stack) != 0 && stack[len(stack)-1] != "(" && stack[len(stack)-1] != "[" {
+				output = append(output, stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			if len(brackets) != 0 {
+				brackets[len(brackets)-1].commas++
+				if token == ";" {
+					brackets[len(brackets)-1].rows++
+				}
+			}
+		case ")", "]":
+			for len(stack) != 0 && stack[len(stack)-1] != "(" && stack[len(stack)-1] != "[" {
 				output = append(output, stack[len(stack)-1])
 				stack = stack[:len(stack)-1]
 			}
 			if len(stack) != 0 {
 				stack = stack[:len(stack)-1]
 			}
+			if len(brackets) == 0 {
+				continue
+			}
+			b := brackets[len(brackets)-1]
+			brackets = brackets[:len(brackets)-1]
+			if b.matrix {
+				output = append(output, MatrixLiteral(b.rows, (b.commas+1)/b.rows))
+			}
+			if b.function != "" {
+				arguments := b.commas + 1
+				if tokens[i-1] == "(" {
+					arguments = 0
+				}
+				output = append(output, FunctionCall(b.function, arguments))
+			}
 		default:
-			for len(stack) != 0 && stack[len(stack)-1] != "(" && priority[token] <= priority[stack[len(stack)-1]] {
+			if e.isUnary(tokens, i) {
+				// Unary operator is applied to the following operand, so it does
+				// not take operators from the stack
+				stack = append(stack, unaryToken(token))
+				continue
+			}
+			current := e.binary[token]
+			for len(stack) != 0 && stack[len(stack)-1] != "(" && stack[len(stack)-1] != "[" {
+				top := e.operator(stack[len(stack)-1])
+				if top.precedence < current.precedence || (top.precedence == current.precedence && current.associativity == RightAssociative) {
+					break
+				}
 				output = append(output, stack[len(stack)-1])
 				stack = stack[:len(stack)-1]
 			}
@@ -173,49 +581,40 @@ func ToPostfix(tokens []string) []string {
 
 // EvalExpression solves tokens in Reverse Polish notation. This function return float64
 func EvalExpression(tokens []string) (float64, error) {
-	var stack []float64
-	for _, token := range tokens {
-		// If token is number
-		if IsNumber(token) {
-			num, err := strconv.ParseFloat(token, 64)
-			if err != nil {
-				return 0, ErrParseFloat
-			}
-			stack = append(stack, num)
-			continue
-		}
-		// If token is operand
-		if len(stack) < 2 {
-			return 0, ErrExtraOperands
-		}
+	return EvalExpressionWithVariables(tokens, nil)
+}
 
-		a, b := stack[len(stack)-2], stack[len(stack)-1]
-		stack = stack[:len(stack)-2]
+// EvalExpressionWithVariables solves tokens in Reverse Polish notation
+// replacing names by values of variables. Result must be a number
+func EvalExpressionWithVariables(tokens []string, variables Variables) (float64, error) {
+	return defaultEngine.EvalExpressionWithVariables(tokens, variables)
+}
 
-		var result float64
-		switch token {
-		case "+":
-			result = a + b
-		case "-":
-			result = a - b
-		case "*":
-			result = a * b
-		case "/":
-			if b == 0 {
-				return 0, ErrZeroByDivision
-			}
-			result = a / b
-		case "^":
-			result = math.Pow(a, b)
-		}
+// EvalValue solves tokens in Reverse Polish notation replacing names by values
+// of variables. Result could be a number or a matrix
+func EvalValue(tokens []string, variables Variables) (Value, error) {
+	return defaultEngine.EvalValue(tokens, variables)
+}
 
-		stack = append(stack, result)
+// EvalExpressionWithVariables solves tokens like the package-level
+// EvalExpressionWithVariables with operators, functions and constants of the engine
+func (e *Engine) EvalExpressionWithVariables(tokens []string, variables Variables) (float64, error) {
+	result, err := e.EvalValue(tokens, variables)
+	if err != nil {
+		return 0, err
 	}
-
-	// Check size of stack
-	if len(stack) == 0 {
-		return 0, nil
+	if !result.IsNumber() {
+		return 0, ErrNotNumber
 	}
 
-	return stack[0], nil
+	return result.Number(), nil
+}
+
+// EvalValue solves tokens like the package-level EvalValue with operators,
+// functions and constants of the engine
+func (e *Engine) EvalValue(tokens []string, variables Variables) (Value, error) {
+	return e.evalValue(tokens, e.withConstants(func(name string) (Value, bool) {
+		value, ok := variables[name]
+		return scalar(value, Dimension{}), ok
+	}), nil, nil)
 }
@@ -1,6 +1,7 @@
 package calc
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -61,6 +62,16 @@ func TestValidateExpression(t *testing.T) {
 			input:    "2) + 3 * (4",
 			excepted: ErrWrongBracketOrder,
 		},
+		{
+			name:     "Matrix",
+			input:    "[1, 2; 3, 4] * [5; 6]",
+			excepted: nil,
+		},
+		{
+			name:     "Mixed brackets",
+			input:    "[1, (2]",
+			excepted: ErrUnpairedBracket,
+		},
 		{
 			name:     "Empty expression",
 			input:    "",
@@ -71,7 +82,7 @@ func TestValidateExpression(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			got := ValidateExpression(tc.input)
 
-			if got != tc.excepted {
+			if !errors.Is(got, tc.excepted) {
 				t.Errorf("ValidateExpression(%q): got %q, excepted %q", tc.input, got, tc.excepted)
 			}
 		})
@@ -130,6 +141,26 @@ func TestCalc(t *testing.T) {
 			input:          "2  + 3    *      4",
 			exceptedResult: 14,
 		},
+		{
+			name:           "Negative number",
+			input:          "-2 + 3",
+			exceptedResult: 1,
+		},
+		{
+			name:           "Negation after operator",
+			input:          "2 * -3",
+			exceptedResult: -6,
+		},
+		{
+			name:           "Negation of power",
+			input:          "-2 ^ 2",
+			exceptedResult: -4,
+		},
+		{
+			name:           "Negative power",
+			input:          "2 ^ -1",
+			exceptedResult: 0.5,
+		},
 	}
 	for _, tc := range casesSuccess {
 		t.Run(tc.name, func(t *testing.T) {
@@ -167,6 +198,257 @@ func TestCalc(t *testing.T) {
 	}
 }
 
+// TestCalcNotFinite pins that Calc returns errors instead of NaN and
+// infinities, which were returned without error before
+func TestCalcNotFinite(t *testing.T) {
+	cases := []struct {
+		name        string
+		expression  string
+		expectedErr error
+	}{
+		{
+			name:        "Root of negative number",
+			expression:  "(0-8)^(1/3)",
+			expectedErr: ErrDomain,
+		},
+		{
+			name:        "Too large result",
+			expression:  "10^400",
+			expectedErr: ErrOverflow,
+		},
+		{
+			name:        "Too large intermediate result",
+			expression:  "10^400 - 10^400",
+			expectedErr: ErrOverflow,
+		},
+		{
+			name:        "Negative zero power",
+			expression:  "0^(0-1)",
+			expectedErr: ErrOverflow,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Calc(tc.expression)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Calc(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+			if got != 0 {
+				t.Errorf("Calc(%q): excepted zero result with error, got %f", tc.expression, got)
+			}
+		})
+	}
+}
+
+// TestCalcRealSyntax covers the syntax of real expressions added together
+// with complex mode: unary minus, function calls and commas between arguments
+func TestCalcRealSyntax(t *testing.T) {
+	casesSuccess := []struct {
+		name           string
+		input          string
+		exceptedResult float64
+	}{
+		{
+			name:           "Negation of brackets",
+			input:          "-(2 + 3)",
+			exceptedResult: -5,
+		},
+		{
+			name:           "Subtraction of negative number",
+			input:          "2 - -3",
+			exceptedResult: 5,
+		},
+		{
+			name:           "Negation in brackets",
+			input:          "(-2) * 3",
+			exceptedResult: -6,
+		},
+		{
+			name:           "Function call",
+			input:          "sum(1, 2) + 1",
+			exceptedResult: 4,
+		},
+		{
+			name:           "Negative arguments",
+			input:          "mean(-1, -2, -3) * -2",
+			exceptedResult: 4,
+		},
+		{
+			name:           "Nested calls",
+			input:          "sum(mean(2, 4), prod(2, 3))",
+			exceptedResult: 9,
+		},
+	}
+	for _, tc := range casesSuccess {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Calc(tc.input)
+			if err != nil {
+				t.Fatalf("Calc(%q): unexcepted error %q", tc.input, err)
+			}
+			if got != tc.exceptedResult {
+				t.Errorf("Calc(%q): got %f, excepted %f", tc.input, got, tc.exceptedResult)
+			}
+		})
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		expectedErr error
+	}{
+		{
+			name:        "Comma outside of call",
+			expression:  "1, 2",
+			expectedErr: ErrUnexpectedComma,
+		},
+		{
+			name:        "Comma in brackets",
+			expression:  "(1, 2) + 1",
+			expectedErr: ErrUnexpectedComma,
+		},
+		{
+			name:        "Wrong number of arguments",
+			expression:  "dot(1)",
+			expectedErr: ErrArguments,
+		},
+		{
+			name:        "Binary operator at the beginning",
+			expression:  "* 2",
+			expectedErr: ErrExtraOperands,
+		},
+		{
+			name:        "Minus at the end",
+			expression:  "2 -",
+			expectedErr: ErrExtraOperands,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Calc(tc.expression)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Calc(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCalcWithVariables(t *testing.T) {
+	variables := Variables{"x": 2, "rate1": 0.5}
+	casesSuccess := []struct {
+		name           string
+		input          string
+		exceptedResult float64
+	}{
+		{
+			name:           "Single variable",
+			input:          "x",
+			exceptedResult: 2,
+		},
+		{
+			name:           "Variables with priority",
+			input:          "1 + x * rate1",
+			exceptedResult: 2,
+		},
+		{
+			name:           "Variable in power",
+			input:          "(x + 1) ^ x",
+			exceptedResult: 9,
+		},
+	}
+	for _, tc := range casesSuccess {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalcWithVariables(tc.input, variables)
+			if err != nil {
+				t.Errorf("successful case %s return error %q", tc.name, err)
+			}
+
+			if got != tc.exceptedResult {
+				t.Errorf("CalcWithVariables(%q): got %f, excepted %f", tc.input, got, tc.exceptedResult)
+			}
+		})
+	}
+	casesFail := []struct {
+		name        string
+		expression  string
+		expectedErr error
+	}{
+		{
+			name:        "Unknown variable",
+			expression:  "x + y",
+			expectedErr: ErrExtraCharacters,
+		},
+		{
+			name:        "Sequential variables",
+			expression:  "x rate1",
+			expectedErr: ErrMultipleNumbers,
+		},
+		{
+			name:        "Number before variable",
+			expression:  "2 x",
+			expectedErr: ErrMultipleNumbers,
+		},
+		{
+			name:        "Point after variable",
+			expression:  "x.5 + 1",
+			expectedErr: ErrExtraCharacters,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CalcWithVariables(tc.expression, variables)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("CalcWithVariables(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestToPostfix(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		want   []string
+	}{
+		{
+			name:   "Priority",
+			tokens: []string{"2", "+", "2", "*", "2"},
+			want:   []string{"2", "2", "2", "*", "+"},
+		},
+		{
+			name:   "Brackets",
+			tokens: []string{"(", "2", "+", "2", ")", "*", "2"},
+			want:   []string{"2", "2", "+", "2", "*"},
+		},
+		{
+			name:   "Unary minus",
+			tokens: []string{"-", "2", "^", "-", "1"},
+			want:   []string{"2", "1", UnaryMinus, "^", UnaryMinus},
+		},
+		{
+			name:   "Function call",
+			tokens: []string{"2", "*", "f", "(", "1", ",", "2", "+", "3", ")"},
+			want:   []string{"2", "1", "2", "3", "+", "f(2)", "*"},
+		},
+		{
+			name:   "Matrix",
+			tokens: []string{"[", "1", ",", "2", ";", "3", "+", "1", ",", "4", "]"},
+			want:   []string{"1", "2", "3", "1", "+", "4", "[2x2]"},
+		},
+		{
+			name:   "Nested function calls",
+			tokens: []string{"f", "(", "g", "(", ")", ",", "(", "1", ")", ")"},
+			want:   []string{"g(0)", "1", "f(2)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToPostfix(tt.tokens); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToPostfix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRemoveSpaces(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -259,6 +541,11 @@ func TestParseExpression(t *testing.T) {
 			args: args{""},
 			want: nil,
 		},
+		{
+			name: "Expression with variables",
+			args: args{"2 * rate1 + x.5"},
+			want: []string{"2", "*", "rate1", "+", "x.5"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -268,3 +555,56 @@ func TestParseExpression(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateExpressionPosition(t *testing.T) {
+	cases := []struct {
+		name             string
+		input            string
+		exceptedErr      error
+		exceptedPosition int
+	}{
+		{
+			name:             "Disallowed symbol",
+			input:            "2 + 2_2",
+			exceptedErr:      ErrExtraCharacters,
+			exceptedPosition: 5,
+		},
+		{
+			name:             "Unknown name",
+			input:            "2 + abc",
+			exceptedErr:      ErrExtraCharacters,
+			exceptedPosition: 4,
+		},
+		{
+			name:             "Unclosed bracket",
+			input:            "(1 + (2 * 3)",
+			exceptedErr:      ErrUnpairedBracket,
+			exceptedPosition: 0,
+		},
+		{
+			name:             "Closing bracket of other kind",
+			input:            "[1, (2]",
+			exceptedErr:      ErrUnpairedBracket,
+			exceptedPosition: 6,
+		},
+		{
+			name:             "Closing bracket without opening",
+			input:            "1 + 2) * 3",
+			exceptedErr:      ErrWrongBracketOrder,
+			exceptedPosition: 5,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateExpression(tc.input)
+
+			var positionError *PositionError
+			if !errors.As(err, &positionError) || positionError.Err != tc.exceptedErr {
+				t.Fatalf("ValidateExpression(%q): got %q, excepted %q", tc.input, err, tc.exceptedErr)
+			}
+			if positionError.Position != tc.exceptedPosition {
+				t.Errorf("ValidateExpression(%q): got position %d, excepted %d", tc.input, positionError.Position, tc.exceptedPosition)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package calc
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+)
+
+// imaginaryUnits are the names of imaginary unit in complex expressions
+var imaginaryUnits = map[string]bool{"i": true, "j": true}
+
+// complexFunctions are the functions of a single argument available in complex expressions
+var complexFunctions = map[string]func(complex128) complex128{
+	"sqrt": cmplx.Sqrt,
+	"abs":  func(z complex128) complex128 { return complex(cmplx.Abs(z), 0) },
+	"arg":  func(z complex128) complex128 { return complex(cmplx.Phase(z), 0) },
+	"conj": cmplx.Conj,
+	"re":   func(z complex128) complex128 { return complex(real(z), 0) },
+	"im":   func(z complex128) complex128 { return complex(imag(z), 0) },
+	"exp":  cmplx.Exp,
+	"ln":   cmplx.Log,
+}
+
+// isComplexName returns the true if the name is an imaginary unit or a function otherwise false
+func isComplexName(name string) bool {
+	_, ok := complexFunctions[name]
+	return ok || imaginaryUnits[name]
+}
+
+// CalcComplex calculates the expression with complex numbers. The imaginary
+// unit is written as "i" or "j", e.g. "(3+4i)*(1-2i)". Functions sqrt, abs,
+// arg, conj, re, im, exp and ln are available
+func CalcComplex(expression string) (complex128, error) {
+	return calcComplex(expression, Options{}, nil)
+}
+
+// CalcComplexContext calculates the expression like CalcComplex within limits
+// of options. Calculation stops with the error of context when it is done
+func CalcComplexContext(ctx context.Context, expression string, options Options) (complex128, error) {
+	return calcComplex(expression, options, newLimiter(ctx, options))
+}
+
+// calcComplex calculates the expression with complex numbers within limits of
+// options, evaluation steps are counted by limit
+func calcComplex(expression string, options Options, limit *limiter) (complex128, error) {
+	if err := options.checkLength(expression); err != nil {
+		return 0, err
+	}
+
+	// Checking validity of expression
+	err := limit.validate(expression, func(expression string) error {
+		return defaultEngine.validateExpression(expression, isComplexName)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Tokenize expression, so "4i" is the same as "4 * i"
+	tokens := limit.parse(expression)
+	if err := options.checkTokens(tokens); err != nil {
+		return 0, err
+	}
+	tokens = InsertImplicitMultiplication(tokens)
+
+	// Validate Tokens
+	if err := ValidateTokens(tokens); err != nil {
+		return 0, err
+	}
+
+	// Calculate the expression
+	tokens = limit.postfix(tokens, ToPostfix)
+	return traceEval(limit, tokens, func() (complex128, error) {
+		return evalComplex(tokens, limit)
+	})
+}
+
+// EvalComplex solves tokens in Reverse Polish notation with complex numbers.
+// Unlike EvalExpression, powers of negative numbers are complex, e.g.
+// "(0-4)^0.5" is 2i
+func EvalComplex(tokens []string) (complex128, error) {
+	return evalComplex(tokens, nil)
+}
+
+// evalComplex solves tokens like EvalComplex, every token is a step counted by limit
+func evalComplex(tokens []string, limit *limiter) (complex128, error) {
+	var stack []complex128
+	for _, token := range tokens {
+		if err := limit.step(); err != nil {
+			return 0, err
+		}
+
+		// If token is number
+		if IsNumber(token) {
+			num, err := parseNumber(token)
+			if err != nil {
+				return 0, err
+			}
+			stack = append(stack, complex(num, 0))
+			continue
+		}
+		// If token is imaginary unit
+		if imaginaryUnits[token] {
+			stack = append(stack, 1i)
+			continue
+		}
+		// If token is function
+		if name, arguments, ok := ParseFunctionCall(token); ok {
+			function, ok := complexFunctions[name]
+			if !ok {
+				return 0, ErrExtraCharacters
+			}
+			if arguments != 1 {
+				return 0, ErrArguments
+			}
+			if len(stack) < 1 {
+				return 0, ErrExtraOperands
+			}
+
+			result := function(stack[len(stack)-1])
+			if err := checkComplex(result); err != nil {
+				return 0, err
+			}
+			stack[len(stack)-1] = result
+			continue
+		}
+		// Matrices are not supported with complex numbers
+		if _, _, ok := ParseMatrixLiteral(token); ok {
+			return 0, ErrExtraCharacters
+		}
+		// If token is negation
+		if token == UnaryMinus {
+			if len(stack) < 1 {
+				return 0, ErrExtraOperands
+			}
+			// Subtraction from zero keeps the sign of zero imaginary part,
+			// so sqrt(-1) is i rather than -i
+			stack[len(stack)-1] = 0 - stack[len(stack)-1]
+			continue
+		}
+		// If token is operand
+		if len(stack) < 2 {
+			return 0, ErrExtraOperands
+		}
+
+		a, b := stack[len(stack)-2], stack[len(stack)-1]
+		stack = stack[:len(stack)-2]
+
+		var result complex128
+		switch token {
+		case "+":
+			result = a + b
+		case "-":
+			result = a - b
+		case "*", ImplicitMultiplication:
+			result = a * b
+		case "/":
+			if b == 0 {
+				return 0, ErrZeroByDivision
+			}
+			result = a / b
+		case "^":
+			result = complexPow(a, b)
+		default:
+			return 0, ErrExtraCharacters
+		}
+
+		if err := checkComplex(result); err != nil {
+			return 0, err
+		}
+
+		stack = append(stack, result)
+	}
+
+	// Check size of stack
+	if len(stack) == 0 {
+		return 0, nil
+	}
+
+	return stack[0], nil
+}
+
+// maxIntegerPower limits the power calculated by multiplication in complexPow
+const maxIntegerPower = 64
+
+// complexPow raises a to the power b. Powers of real numbers and small whole
+// powers are calculated exactly, without rounding errors of cmplx.Pow
+func complexPow(a, b complex128) complex128 {
+	if imag(b) != 0 {
+		return cmplx.Pow(a, b)
+	}
+	power := real(b)
+
+	switch {
+	case imag(a) == 0 && (real(a) >= 0 || power == math.Trunc(power)):
+		return complex(math.Pow(real(a), power), 0)
+	case power == 0.5:
+		return cmplx.Sqrt(a)
+	case power == math.Trunc(power) && math.Abs(power) <= maxIntegerPower:
+		// Exponentiation by squaring
+		result, base := complex128(1), a
+		for n := int(math.Abs(power)); n > 0; n /= 2 {
+			if n%2 == 1 {
+				result *= base
+			}
+			base *= base
+		}
+		if power < 0 {
+			if result == 0 {
+				return cmplx.Inf()
+			}
+			return 1 / result
+		}
+		return result
+	default:
+		return cmplx.Pow(a, b)
+	}
+}
+
+// checkComplex returns an error if the number has not finite parts
+func checkComplex(z complex128) error {
+	if cmplx.IsNaN(z) {
+		return ErrDomain
+	}
+	if math.IsInf(real(z), 0) || math.IsInf(imag(z), 0) {
+		return ErrOverflow
+	}
+	return nil
+}
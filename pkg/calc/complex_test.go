@@ -0,0 +1,124 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestCalcComplex(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		excepted complex128
+	}{
+		{
+			name:     "Real expression",
+			input:    "2 + 2 * 2",
+			excepted: 6,
+		},
+		{
+			name:     "Product",
+			input:    "(3+4i)*(1-2i)",
+			excepted: 11 - 2i,
+		},
+		{
+			name:     "Letter j",
+			input:    "(1+2j)/(1-2j)",
+			excepted: -0.6 + 0.8i,
+		},
+		{
+			name:     "Square of imaginary unit",
+			input:    "i^2",
+			excepted: -1,
+		},
+		{
+			name:     "Square root of negative number",
+			input:    "sqrt(-1)",
+			excepted: 1i,
+		},
+		{
+			name:     "Fractional power of negative number",
+			input:    "(-4)^0.5",
+			excepted: 2i,
+		},
+		{
+			name:     "Absolute value",
+			input:    "abs(3+4i)",
+			excepted: 5,
+		},
+		{
+			name:     "Argument",
+			input:    "arg(-1)",
+			excepted: math.Pi,
+		},
+		{
+			name:     "Conjugate",
+			input:    "conj(1+2i)",
+			excepted: 1 - 2i,
+		},
+		{
+			name:     "Parts",
+			input:    "re(3+4i) - im(3+4i)",
+			excepted: -1,
+		},
+		{
+			name:     "Negation",
+			input:    "-(1+i) * 2",
+			excepted: -2 - 2i,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalcComplex(tc.input)
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if cmplx.Abs(got-tc.excepted) > 1e-12 {
+				t.Errorf("CalcComplex(%q): got %v, excepted %v", tc.input, got, tc.excepted)
+			}
+		})
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		expectedErr error
+	}{
+		{
+			name:        "Unknown function",
+			expression:  "sin(i)",
+			expectedErr: ErrExtraCharacters,
+		},
+		{
+			name:        "Too many arguments",
+			expression:  "sqrt(4, 2)",
+			expectedErr: ErrArguments,
+		},
+		{
+			name:        "Division by zero",
+			expression:  "1 / (i - i)",
+			expectedErr: ErrZeroByDivision,
+		},
+		{
+			name:        "Logarithm of zero",
+			expression:  "ln(0)",
+			expectedErr: ErrOverflow,
+		},
+		{
+			name:        "Comma outside of function",
+			expression:  "(1, 2)",
+			expectedErr: ErrUnexpectedComma,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CalcComplex(tc.expression)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("CalcComplex(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package calc
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// reservedSymbols could not be registered as operators, because they are
+// brackets, separators or tokens used in reverse Polish notation
+const reservedSymbols = "()[],;." + UnaryMinus + ImplicitMultiplication
+
+// Associativity defines the order of operators with the same precedence
+type Associativity int
+
+const (
+	// LeftAssociative operators are applied from left to right, e.g. "8/4/2" is "(8/4)/2"
+	LeftAssociative Associativity = iota
+	// RightAssociative operators are applied from right to left
+	RightAssociative
+)
+
+// operator is an unary or a binary operator of the engine
+type operator struct {
+	precedence    int
+	associativity Associativity
+	apply         func(arguments []Value) (Value, error)
+}
+
+// Engine keeps operators, functions and constants available in expressions.
+// Package-level functions use the default engine with built-in operators and
+// functions. Engine is not safe for concurrent registration, so everything
+// must be registered before calculations
+type Engine struct {
+	// binary operators by their symbols, unary operators by their tokens in
+	// reverse Polish notation made by unaryToken
+	binary    map[string]operator
+	unary     map[string]operator
+	functions map[string]builtinFunction
+	constants map[string]float64
+
+	// disallowed matches symbols which are not allowed in expression
+	disallowed *regexp.Regexp
+}
+
+// defaultEngine is used by package-level functions
+var defaultEngine = NewEngine()
+
+// NewEngine returns the engine with built-in operators and functions
+func NewEngine() *Engine {
+	e := &Engine{
+		binary:    make(map[string]operator),
+		unary:     make(map[string]operator),
+		functions: maps.Clone(builtinFunctions),
+		constants: make(map[string]float64),
+	}
+
+	for symbol, precedence := range map[string]int{"+": 1, "-": 1, "*": 2, "/": 2, ImplicitMultiplication: 3, "^": 4} {
+		e.binary[symbol] = operator{precedence: precedence, apply: func(arguments []Value) (Value, error) {
+			return evalOperator(symbol, arguments[0], arguments[1])
+		}}
+	}
+	e.unary[UnaryMinus] = operator{precedence: 3, apply: func(arguments []Value) (Value, error) {
+		return negate(arguments[0]), nil
+	}}
+
+	e.compileSymbols()
+	return e
+}
+
+// RegisterFunction adds the function of numbers to the engine. Function with
+// negative number of arguments takes any number of them, but at least one.
+// Arguments must be dimensionless numbers
+func (e *Engine) RegisterFunction(name string, arguments int, function func(arguments ...float64) (float64, error)) error {
+	if !IsIdentifier(name) || name == conversionOperator {
+		return ErrInvalidFunctionName
+	}
+	if e.isFunctionName(name) || e.isConstant(name) {
+		return ErrAlreadyRegistered
+	}
+
+	builtin := builtinFunction{arguments: arguments, call: numberFunction(name, function)}
+	if arguments < 0 {
+		builtin.arguments, builtin.variadic = 1, true
+	}
+	e.functions[name] = builtin
+	return nil
+}
+
+// RegisterOperator adds the operator of numbers to the engine. Symbol is
+// a single character which is not a letter, a digit, a bracket or a separator.
+// Operator with arity 1 is written before its operand, e.g. "-x", and operator
+// with arity 2 is written between operands. Operators with higher precedence
+// are applied first: "+" and "-" have 1, "*" and "/" have 2, negation has 3
+// and "^" has 4
+func (e *Engine) RegisterOperator(symbol string, precedence int, associativity Associativity, arity int, function func(arguments ...float64) (float64, error)) error {
+	r, size := utf8.DecodeRuneInString(symbol)
+	if size == 0 || size != len(symbol) || r == utf8.RuneError ||
+		unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune(reservedSymbols, r) {
+		return ErrInvalidOperator
+	}
+	if precedence <= 0 || (associativity != LeftAssociative && associativity != RightAssociative) {
+		return ErrInvalidOperator
+	}
+
+	operators, token := e.binary, symbol
+	switch arity {
+	case 1:
+		operators, token = e.unary, unaryToken(symbol)
+	case 2:
+	default:
+		return ErrInvalidOperator
+	}
+	if _, ok := operators[token]; ok {
+		return ErrAlreadyRegistered
+	}
+
+	call := numberFunction(symbol, function)
+	operators[token] = operator{precedence: precedence, associativity: associativity, apply: call}
+	e.compileSymbols()
+	return nil
+}
+
+// RegisterConstant adds the named number to the engine. Variables hide
+// constants with the same names, constants hide units
+func (e *Engine) RegisterConstant(name string, value float64) error {
+	if !IsIdentifier(name) || name == conversionOperator {
+		return ErrInvalidVariable
+	}
+	if e.isFunctionName(name) || e.isConstant(name) {
+		return ErrAlreadyRegistered
+	}
+
+	e.constants[name] = value
+	return nil
+}
+
+// compileSymbols prepares the expression matching symbols which are not
+// allowed in expression
+func (e *Engine) compileSymbols() {
+	var symbols strings.Builder
+	for _, operators := range []map[string]operator{e.binary, e.unary} {
+		for token := range operators {
+			if token == ImplicitMultiplication {
+				continue
+			}
+			symbol := strings.TrimPrefix(token, UnaryMinus)
+			if token == UnaryMinus {
+				symbol = "-"
+			}
+			// ASCII symbols are escaped, so that they are not special in a character class
+			if len(symbol) == 1 {
+				symbols.WriteString(`\`)
+			}
+			symbols.WriteString(symbol)
+		}
+	}
+	e.disallowed = regexp.MustCompile(fmt.Sprintf(disallowedSymbolsRegular, symbols.String()))
+}
+
+// unaryToken returns the token of unary operator used in reverse Polish notation
+func unaryToken(symbol string) string {
+	if symbol == "-" {
+		return UnaryMinus
+	}
+	return UnaryMinus + symbol
+}
+
+// operator returns the unary or binary operator with the token
+func (e *Engine) operator(token string) operator {
+	if op, ok := e.unary[token]; ok {
+		return op
+	}
+	return e.binary[token]
+}
+
+// isOperator returns the true if token is an operator written in expression otherwise false
+func (e *Engine) isOperator(token string) bool {
+	if token == ImplicitMultiplication {
+		return false
+	}
+	_, binary := e.binary[token]
+	_, unary := e.unary[unaryToken(token)]
+	return binary || unary
+}
+
+// isFunctionName returns the true if there is a function of the engine with the name otherwise false
+func (e *Engine) isFunctionName(name string) bool {
+	_, ok := e.functions[name]
+	return ok
+}
+
+// isConstant returns the true if there is a constant with the name otherwise false
+func (e *Engine) isConstant(name string) bool {
+	_, ok := e.constants[name]
+	return ok
+}
+
+// withNames returns isName which also accepts functions and constants of the engine
+func (e *Engine) withNames(isName func(name string) bool) func(name string) bool {
+	return func(name string) bool {
+		return isName(name) || e.isFunctionName(name) || e.isConstant(name)
+	}
+}
+
+// withConstants returns lookup which finds constants of the engine not found by lookup
+func (e *Engine) withConstants(lookup valueLookup) valueLookup {
+	return func(name string) (Value, bool) {
+		if value, ok := lookup(name); ok {
+			return value, true
+		}
+		constant, ok := e.constants[name]
+		return scalar(constant, Dimension{}), ok
+	}
+}
+
+// numberFunction makes the function of values from the function of
+// dimensionless numbers
+func numberFunction(name string, function func(arguments ...float64) (float64, error)) func(arguments []Value) (Value, error) {
+	return func(arguments []Value) (Value, error) {
+		numbers := make([]float64, len(arguments))
+		for i, argument := range arguments {
+			if !argument.IsNumber() {
+				shapes := make([]Shape, len(arguments))
+				for j := range arguments {
+					shapes[j] = arguments[j].Shape
+				}
+				return Value{}, &ShapeError{Operation: name, Shapes: shapes}
+			}
+			if !argument.Dimension.IsDimensionless() {
+				return Value{}, &DimensionError{Operation: name, Left: argument.Dimension, Right: Dimension{}}
+			}
+			numbers[i] = argument.Number()
+		}
+
+		result, err := function(numbers...)
+		if err != nil {
+			return Value{}, err
+		}
+		return NewNumber(result), nil
+	}
+}
@@ -0,0 +1,266 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// newTestEngine returns the engine with domain-specific functions, operators and constants
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+
+	engine := NewEngine()
+	if err := engine.RegisterFunction("vat", 1, func(x ...float64) (float64, error) {
+		return x[0] * 1.2, nil
+	}); err != nil {
+		t.Fatalf("RegisterFunction: unexcepted error %q", err)
+	}
+	if err := engine.RegisterFunction("clamp", 3, func(x ...float64) (float64, error) {
+		return math.Min(math.Max(x[0], x[1]), x[2]), nil
+	}); err != nil {
+		t.Fatalf("RegisterFunction: unexcepted error %q", err)
+	}
+	if err := engine.RegisterFunction("maximum", -1, func(x ...float64) (float64, error) {
+		result := x[0]
+		for _, v := range x[1:] {
+			result = math.Max(result, v)
+		}
+		return result, nil
+	}); err != nil {
+		t.Fatalf("RegisterFunction: unexcepted error %q", err)
+	}
+	if err := engine.RegisterOperator("%", 2, LeftAssociative, 2, func(x ...float64) (float64, error) {
+		if x[1] == 0 {
+			return 0, ErrZeroByDivision
+		}
+		return math.Mod(x[0], x[1]), nil
+	}); err != nil {
+		t.Fatalf("RegisterOperator: unexcepted error %q", err)
+	}
+	if err := engine.RegisterOperator("→", 5, RightAssociative, 2, func(x ...float64) (float64, error) {
+		return math.Pow(x[0], x[1]), nil
+	}); err != nil {
+		t.Fatalf("RegisterOperator: unexcepted error %q", err)
+	}
+	if err := engine.RegisterOperator("!", 3, LeftAssociative, 1, func(x ...float64) (float64, error) {
+		if x[0] == 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}); err != nil {
+		t.Fatalf("RegisterOperator: unexcepted error %q", err)
+	}
+	if err := engine.RegisterConstant("rate", 0.25); err != nil {
+		t.Fatalf("RegisterConstant: unexcepted error %q", err)
+	}
+	return engine
+}
+
+func TestEngineCalc(t *testing.T) {
+	engine := newTestEngine(t)
+
+	cases := []struct {
+		name           string
+		input          string
+		variables      Variables
+		exceptedResult float64
+	}{
+		{
+			name:           "Function",
+			input:          "vat(100) - 100",
+			exceptedResult: 20,
+		},
+		{
+			name:           "Function of several arguments",
+			input:          "clamp(15, 0, 10) + clamp(-5, 0, 10)",
+			exceptedResult: 10,
+		},
+		{
+			name:           "Variadic function",
+			input:          "maximum(1, 7, 3)",
+			exceptedResult: 7,
+		},
+		{
+			name:           "Binary operator",
+			input:          "1 + 17 % 5 * 2",
+			exceptedResult: 5,
+		},
+		{
+			name:           "Right associative operator",
+			input:          "2 → 3 → 2",
+			exceptedResult: 512,
+		},
+		{
+			name:           "Unary operator",
+			input:          "!0 + !(2 - 1)",
+			exceptedResult: 1,
+		},
+		{
+			name:           "Constant",
+			input:          "100 * rate",
+			exceptedResult: 25,
+		},
+		{
+			name:           "Variable hides constant",
+			input:          "100 * rate",
+			variables:      Variables{"rate": 0.5},
+			exceptedResult: 50,
+		},
+		{
+			name:           "Built-in operators and functions",
+			input:          "-2^2 + det([1,2;3,4])",
+			exceptedResult: -6,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := engine.CalcWithVariables(tc.input, tc.variables)
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if math.Abs(got-tc.exceptedResult) > 1e-9 {
+				t.Errorf("CalcWithVariables(%q): got %v, excepted %v", tc.input, got, tc.exceptedResult)
+			}
+		})
+	}
+}
+
+func TestEngineCalcError(t *testing.T) {
+	engine := newTestEngine(t)
+
+	cases := []struct {
+		name        string
+		input       string
+		expectedErr error
+	}{
+		{
+			name:        "Wrong number of arguments",
+			input:       "clamp(1, 2)",
+			expectedErr: ErrArguments,
+		},
+		{
+			name:        "Error of function",
+			input:       "5 % 0",
+			expectedErr: ErrZeroByDivision,
+		},
+		{
+			name:        "Unary operator between operands",
+			input:       "2 ! 3",
+			expectedErr: ErrMultipleNumbers,
+		},
+		{
+			name:        "Binary operator at the beginning",
+			input:       "% 3",
+			expectedErr: ErrExtraOperands,
+		},
+		{
+			name:        "Unregistered symbol",
+			input:       "2 & 3",
+			expectedErr: ErrExtraCharacters,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := engine.Calc(tc.input)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Calc(%q): got error %q, expected error %q", tc.input, err, tc.expectedErr)
+			}
+		})
+	}
+
+	// Registered functions take only dimensionless numbers
+	_, err := engine.CalcQuantity("vat(5 m)")
+	if _, ok := err.(*DimensionError); !ok {
+		t.Errorf("CalcQuantity: got error %q, excepted DimensionError", err)
+	}
+	_, err = engine.CalcValue("vat([1,2])", nil)
+	if _, ok := err.(*ShapeError); !ok {
+		t.Errorf("CalcValue: got error %q, excepted ShapeError", err)
+	}
+}
+
+func TestEngineRegisterError(t *testing.T) {
+	engine := newTestEngine(t)
+	function := func(x ...float64) (float64, error) { return x[0], nil }
+
+	cases := []struct {
+		name        string
+		register    func() error
+		expectedErr error
+	}{
+		{
+			name:        "Invalid function name",
+			register:    func() error { return engine.RegisterFunction("2f", 1, function) },
+			expectedErr: ErrInvalidFunctionName,
+		},
+		{
+			name:        "Built-in function",
+			register:    func() error { return engine.RegisterFunction("det", 1, function) },
+			expectedErr: ErrAlreadyRegistered,
+		},
+		{
+			name:        "Function with name of constant",
+			register:    func() error { return engine.RegisterFunction("rate", 1, function) },
+			expectedErr: ErrAlreadyRegistered,
+		},
+		{
+			name:        "Letter as operator",
+			register:    func() error { return engine.RegisterOperator("x", 1, LeftAssociative, 2, function) },
+			expectedErr: ErrInvalidOperator,
+		},
+		{
+			name:        "Several characters",
+			register:    func() error { return engine.RegisterOperator("**", 1, LeftAssociative, 2, function) },
+			expectedErr: ErrInvalidOperator,
+		},
+		{
+			name:        "Bracket as operator",
+			register:    func() error { return engine.RegisterOperator("(", 1, LeftAssociative, 2, function) },
+			expectedErr: ErrInvalidOperator,
+		},
+		{
+			name:        "Wrong arity",
+			register:    func() error { return engine.RegisterOperator("&", 1, LeftAssociative, 3, function) },
+			expectedErr: ErrInvalidOperator,
+		},
+		{
+			name:        "Wrong precedence",
+			register:    func() error { return engine.RegisterOperator("&", 0, LeftAssociative, 2, function) },
+			expectedErr: ErrInvalidOperator,
+		},
+		{
+			name:        "Built-in operator",
+			register:    func() error { return engine.RegisterOperator("+", 1, LeftAssociative, 2, function) },
+			expectedErr: ErrAlreadyRegistered,
+		},
+		{
+			name:        "Constant with name of function",
+			register:    func() error { return engine.RegisterConstant("vat", 1) },
+			expectedErr: ErrAlreadyRegistered,
+		},
+		{
+			name:        "Invalid constant name",
+			register:    func() error { return engine.RegisterConstant("in", 1) },
+			expectedErr: ErrInvalidVariable,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.register(); err != tc.expectedErr {
+				t.Errorf("got error %q, expected error %q", err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestDefaultEngineIsNotChanged(t *testing.T) {
+	newTestEngine(t)
+
+	for _, input := range []string{"vat(100)", "17 % 5", "!0", "rate"} {
+		if _, err := Calc(input); !errors.Is(err, ErrExtraCharacters) {
+			t.Errorf("Calc(%q): got error %q, excepted %q", input, err, ErrExtraCharacters)
+		}
+	}
+}
@@ -2,11 +2,32 @@ package calc
 
 import (
 	"errors"
+	"fmt"
+	"unicode/utf8"
 )
 
+// PositionError is the error of expression found at the position, which is
+// the number of characters before the wrong part of expression
+type PositionError struct {
+	Err      error
+	Position int
+}
+
+// newPositionError returns the error found at the byte offset of expression
+func newPositionError(err error, expression string, offset int) *PositionError {
+	return &PositionError{Err: err, Position: utf8.RuneCountInString(expression[:offset])}
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Err, e.Position)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
 // Internal errors
 var ErrRegexp = errors.New("failed to set regexp")
-var ErrParseFloat = errors.New("failed to parse float")
 
 // expression errors
 var ErrExtraCharacters = errors.New("expression has extra characters")
@@ -17,3 +38,44 @@ var ErrMultipleNumbers = errors.New("expression has multiple sequential numbers"
 var ErrZeroByDivision = errors.New("expression has zero by division")
 var ErrExtraOperands = errors.New("expression has operands at the beginning or end")
 var ErrEmptyExpression = errors.New("expression is empty")
+
+// ErrParseFloat is returned for malformed numbers like "1.2.3". Numbers which
+// are too large are ErrOverflow
+var ErrParseFloat = errors.New("expression has invalid number")
+
+// ErrDomain and ErrOverflow are returned instead of NaN and infinite results
+// of operations
+var ErrDomain = errors.New("expression is outside of the domain")
+var ErrOverflow = errors.New("expression result is too large")
+var ErrUnexpectedComma = errors.New("expression has comma outside of function arguments")
+var ErrArguments = errors.New("function has wrong number of arguments")
+var ErrInvalidConversion = errors.New("expression has invalid unit conversion")
+var ErrInvalidMatrix = errors.New("expression has invalid matrix")
+var ErrSingularMatrix = errors.New("matrix is singular")
+var ErrNotNumber = errors.New("expression result is not a number")
+
+// calculation errors
+var ErrInvalidVariable = errors.New("variable name is invalid")
+var ErrIterationLimit = errors.New("calculation exceeds iteration limit")
+var ErrInvalidRange = errors.New("range is invalid")
+
+// limit errors
+var ErrExpressionTooLong = errors.New("expression is too long")
+var ErrTooManyTokens = errors.New("expression has too many tokens")
+var ErrTooDeep = errors.New("expression has too deep nesting of brackets")
+var ErrStepLimit = errors.New("calculation exceeds step limit")
+
+// function errors
+var ErrInvalidFunctionName = errors.New("function name is invalid")
+var ErrRecursion = errors.New("function calls itself")
+var ErrUnknownFunction = errors.New("function is not defined")
+var ErrFunctionInUse = errors.New("function is called by other functions")
+
+// engine errors
+var ErrInvalidOperator = errors.New("operator is invalid")
+var ErrAlreadyRegistered = errors.New("name is already registered")
+
+// unit table errors
+var ErrInvalidUnitName = errors.New("unit name is invalid")
+var ErrUnitExists = errors.New("unit already exists")
+var ErrInvalidRate = errors.New("rate must be a positive number")
@@ -0,0 +1,146 @@
+package calc
+
+import "slices"
+
+// UserFunction is a function defined by an expression of its parameters,
+// e.g. f(x, y) = x^2 + y
+type UserFunction struct {
+	Parameters []string
+	Expression string
+	// tokens are the postfix tokens of expression and calls are the names of
+	// user-defined functions called in it
+	tokens []string
+	calls  []string
+}
+
+// Functions binds names to user-defined functions
+type Functions map[string]*UserFunction
+
+// Scope binds names used in an expression to values and user-defined functions
+type Scope struct {
+	Values    Values
+	Functions Functions
+}
+
+// Define compiles the expression and binds the function to the name. The
+// expression could use parameters, built-in functions and functions defined
+// before. Redefinition which makes functions call each other in a cycle
+// returns ErrRecursion, and redefinition which changes the number of
+// parameters of function called by other functions returns ErrFunctionInUse.
+// Built-in functions are the functions of the default engine
+func (f Functions) Define(name string, parameters []string, expression string) error {
+	if !IsIdentifier(name) || name == conversionOperator || defaultEngine.isFunctionName(name) {
+		return ErrInvalidFunctionName
+	}
+	isParameter := make(map[string]bool, len(parameters))
+	for _, parameter := range parameters {
+		if !IsIdentifier(parameter) || parameter == conversionOperator || isParameter[parameter] {
+			return ErrInvalidVariable
+		}
+		isParameter[parameter] = true
+	}
+
+	// Checking validity of expression. The function itself is a known name,
+	// so that calling it is reported as recursion
+	isName := func(n string) bool {
+		_, ok := f[n]
+		return ok || n == name || isParameter[n] || defaultEngine.isFunctionName(n)
+	}
+	if err := defaultEngine.validateExpression(expression, isName); err != nil {
+		return err
+	}
+
+	// Tokenize expression like CalcQuantity does
+	tokens := InsertImplicitMultiplication(ParseExpression(expression))
+	if err := ValidateTokens(tokens); err != nil {
+		return err
+	}
+	postfixTokens := ToPostfix(tokens)
+
+	// Check names and calls of functions
+	var calls []string
+	for _, token := range postfixTokens {
+		if IsIdentifier(token) && !isParameter[token] {
+			return ErrExtraCharacters
+		}
+		called, arguments, ok := ParseFunctionCall(token)
+		if !ok || defaultEngine.isFunctionName(called) {
+			continue
+		}
+		if called == name || f.reaches(called, name) {
+			return ErrRecursion
+		}
+		function, ok := f[called]
+		if !ok {
+			return ErrExtraCharacters
+		}
+		if arguments != len(function.Parameters) {
+			return ErrArguments
+		}
+		if !slices.Contains(calls, called) {
+			calls = append(calls, called)
+		}
+	}
+
+	// Callers of the function pass the number of arguments it had
+	if defined, ok := f[name]; ok && len(defined.Parameters) != len(parameters) && f.isCalled(name) {
+		return ErrFunctionInUse
+	}
+
+	f[name] = &UserFunction{
+		Parameters: slices.Clone(parameters),
+		Expression: expression,
+		tokens:     postfixTokens,
+		calls:      calls,
+	}
+	return nil
+}
+
+// Delete removes the function. Function called by other functions could not be deleted
+func (f Functions) Delete(name string) error {
+	if _, ok := f[name]; !ok {
+		return ErrUnknownFunction
+	}
+	if f.isCalled(name) {
+		return ErrFunctionInUse
+	}
+
+	delete(f, name)
+	return nil
+}
+
+// isCalled reports whether other functions call the function
+func (f Functions) isCalled(name string) bool {
+	for _, function := range f {
+		if slices.Contains(function.calls, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// reaches returns the true if the function from calls the function target
+// directly or through other functions otherwise false
+func (f Functions) reaches(from, target string) bool {
+	function, ok := f[from]
+	if !ok {
+		return false
+	}
+	for _, called := range function.calls {
+		if called == target || f.reaches(called, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// call calculates the function with the arguments by the engine. Steps of
+// the function are counted by limit
+func (f Functions) call(e *Engine, function *UserFunction, arguments []Value, limit *limiter) (Value, error) {
+	parameters := make(Values, len(arguments))
+	for i, parameter := range function.Parameters {
+		parameters[parameter] = arguments[i]
+	}
+
+	return e.evalValue(function.tokens, parameters.lookup, f, limit)
+}
@@ -0,0 +1,188 @@
+package calc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFunctionsDefine(t *testing.T) {
+	functions := Functions{}
+	if err := functions.Define("f", []string{"x", "y"}, "x^2 + y"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+	if err := functions.Define("g", []string{"x"}, "2f(x, 1) + mean(x, 3)"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+
+	cases := []struct {
+		name         string
+		functionName string
+		parameters   []string
+		expression   string
+		expectedErr  error
+	}{
+		{
+			name:         "Direct recursion",
+			functionName: "h",
+			parameters:   []string{"x"},
+			expression:   "h(x - 1)",
+			expectedErr:  ErrRecursion,
+		},
+		{
+			name:         "Recursion through other function",
+			functionName: "f",
+			parameters:   []string{"x", "y"},
+			expression:   "g(x) + y",
+			expectedErr:  ErrRecursion,
+		},
+		{
+			name:         "Unknown name",
+			functionName: "h",
+			parameters:   []string{"x"},
+			expression:   "x + y",
+			expectedErr:  ErrExtraCharacters,
+		},
+		{
+			name:         "Call of parameter",
+			functionName: "h",
+			parameters:   []string{"x"},
+			expression:   "x(1)",
+			expectedErr:  ErrExtraCharacters,
+		},
+		{
+			name:         "Wrong number of arguments",
+			functionName: "h",
+			parameters:   []string{"x"},
+			expression:   "f(x)",
+			expectedErr:  ErrArguments,
+		},
+		{
+			name:         "Other number of parameters of called function",
+			functionName: "f",
+			parameters:   []string{"x"},
+			expression:   "x^2",
+			expectedErr:  ErrFunctionInUse,
+		},
+		{
+			name:         "Name of built-in function",
+			functionName: "det",
+			parameters:   []string{"x"},
+			expression:   "x",
+			expectedErr:  ErrInvalidFunctionName,
+		},
+		{
+			name:         "Repeated parameter",
+			functionName: "h",
+			parameters:   []string{"x", "x"},
+			expression:   "x",
+			expectedErr:  ErrInvalidVariable,
+		},
+		{
+			name:         "Invalid expression",
+			functionName: "h",
+			parameters:   []string{"x"},
+			expression:   "x +",
+			expectedErr:  ErrExtraOperands,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := functions.Define(tc.functionName, tc.parameters, tc.expression)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Define(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+
+	// Failed definitions do not change functions
+	if len(functions) != 2 || functions["f"].Expression != "x^2 + y" {
+		t.Errorf("Define: functions are changed after errors: %+v", functions)
+	}
+
+	// Parameters of functions which are not called could be changed
+	if err := functions.Define("f", []string{"x", "y"}, "x + y"); err != nil {
+		t.Errorf("Define(f): unexcepted error %q", err)
+	}
+	if err := functions.Define("g", []string{"x", "y"}, "f(x, y)"); err != nil {
+		t.Errorf("Define(g): unexcepted error %q", err)
+	}
+}
+
+func TestCalcQuantityInScope(t *testing.T) {
+	functions := Functions{}
+	if err := functions.Define("f", []string{"x", "y"}, "x^2 + y"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+	if err := functions.Define("speed", []string{"d", "t"}, "d / t"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+
+	cases := []struct {
+		name          string
+		input         string
+		exceptedValue float64
+		exceptedUnit  string
+	}{
+		{
+			name:          "Call",
+			input:         "f(3, 4)",
+			exceptedValue: 13,
+		},
+		{
+			name:          "Nested calls",
+			input:         "f(f(1, 1), -4) * 2",
+			exceptedValue: 0,
+		},
+		{
+			name:          "Arguments with units",
+			input:         "speed(100 km, 2 h) in km/h",
+			exceptedValue: 50,
+			exceptedUnit:  "km/h",
+		},
+		{
+			name:          "Parameters hide values of scope",
+			input:         "f(x, 0) + x",
+			exceptedValue: 6,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalcQuantityInScope(tc.input, Scope{Values: Values{"x": NewNumber(2)}, Functions: functions})
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if got.Value != tc.exceptedValue || got.Unit != tc.exceptedUnit {
+				t.Errorf("CalcQuantityInScope(%q): got %v %s, excepted %v %s", tc.input, got.Value, got.Unit, tc.exceptedValue, tc.exceptedUnit)
+			}
+		})
+	}
+
+	_, err := CalcQuantityInScope("f(1)", Scope{Functions: functions})
+	if err != ErrArguments {
+		t.Errorf("CalcQuantityInScope: got error %q, excepted %q", err, ErrArguments)
+	}
+}
+
+func TestFunctionsDelete(t *testing.T) {
+	functions := Functions{}
+	if err := functions.Define("f", []string{"x"}, "x + 1"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+	if err := functions.Define("g", []string{"x"}, "f(x) * 2"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+
+	if err := functions.Delete("f"); err != ErrFunctionInUse {
+		t.Errorf("Delete(f): got error %q, excepted %q", err, ErrFunctionInUse)
+	}
+	if err := functions.Delete("g"); err != nil {
+		t.Errorf("Delete(g): unexcepted error %q", err)
+	}
+	if err := functions.Delete("f"); err != nil {
+		t.Errorf("Delete(f): unexcepted error %q", err)
+	}
+	if err := functions.Delete("f"); err != ErrUnknownFunction {
+		t.Errorf("Delete(f): got error %q, excepted %q", err, ErrUnknownFunction)
+	}
+}
@@ -0,0 +1,81 @@
+package calc
+
+import "context"
+
+// Options limits resources used by calculation. Zero limit means that there
+// is no limit
+type Options struct {
+	// MaxLength is the maximum length of expression in bytes
+	MaxLength int
+	// MaxTokens is the maximum number of tokens of expression
+	MaxTokens int
+	// MaxDepth is the maximum nesting of round and square brackets
+	MaxDepth int
+	// MaxSteps is the maximum number of evaluated tokens including tokens of
+	// called user-defined functions. Steps of every evaluation made by
+	// IntegrateContext, SumContext and SampleContext are counted separately
+	MaxSteps int
+	// MaxIterations is the maximum number of evaluations of expression made
+	// by IntegrateContext, SumContext and SampleContext
+	MaxIterations int
+}
+
+// checkLength returns ErrExpressionTooLong if the expression is longer than allowed
+func (o Options) checkLength(expression string) error {
+	if o.MaxLength > 0 && len(expression) > o.MaxLength {
+		return ErrExpressionTooLong
+	}
+	return nil
+}
+
+// checkTokens returns ErrTooManyTokens or ErrTooDeep if tokens of expression
+// exceed limits
+func (o Options) checkTokens(tokens []string) error {
+	if o.MaxTokens > 0 && len(tokens) > o.MaxTokens {
+		return ErrTooManyTokens
+	}
+	if o.MaxDepth <= 0 {
+		return nil
+	}
+
+	var depth int
+	for _, token := range tokens {
+		switch token {
+		case "(", "[":
+			depth++
+			if depth > o.MaxDepth {
+				return ErrTooDeep
+			}
+		case ")", "]":
+			depth--
+		}
+	}
+	return nil
+}
+
+// limiter counts evaluation steps and stops evaluation when the context is
+// done. Nil limiter does not limit evaluation
+type limiter struct {
+	ctx      context.Context
+	steps    int
+	maxSteps int
+}
+
+// newLimiter returns the limiter of evaluation with the context and options
+func newLimiter(ctx context.Context, options Options) *limiter {
+	return &limiter{ctx: ctx, maxSteps: options.MaxSteps}
+}
+
+// step counts the evaluation step. It returns ErrStepLimit when there are too
+// many steps and the error of context when it is done
+func (l *limiter) step() error {
+	if l == nil {
+		return nil
+	}
+
+	l.steps++
+	if l.maxSteps > 0 && l.steps > l.maxSteps {
+		return ErrStepLimit
+	}
+	return l.ctx.Err()
+}
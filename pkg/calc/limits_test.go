@@ -0,0 +1,95 @@
+package calc
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCalcContext(t *testing.T) {
+	options := Options{MaxLength: 100, MaxTokens: 20, MaxDepth: 3, MaxSteps: 10}
+
+	cases := []struct {
+		name           string
+		input          string
+		exceptedResult float64
+		expectedErr    error
+	}{
+		{
+			name:           "Within limits",
+			input:          "((1 + 2) * 3) - 4",
+			exceptedResult: 5,
+		},
+		{
+			name:        "Too long expression",
+			input:       "1" + strings.Repeat(" ", 100),
+			expectedErr: ErrExpressionTooLong,
+		},
+		{
+			name:        "Too many tokens",
+			input:       strings.Repeat("1+", 10) + "1",
+			expectedErr: ErrTooManyTokens,
+		},
+		{
+			name:        "Too deep nesting",
+			input:       "((([[1]])))",
+			expectedErr: ErrTooDeep,
+		},
+		{
+			name:        "Too many steps",
+			input:       "1 + 2 + 3 + 4 + 5 + 6",
+			expectedErr: ErrStepLimit,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalcContext(context.Background(), tc.input, options)
+			if err != tc.expectedErr {
+				t.Fatalf("CalcContext(%q): got error %q, expected error %q", tc.input, err, tc.expectedErr)
+			}
+			if got != tc.exceptedResult {
+				t.Errorf("CalcContext(%q): got %v, excepted %v", tc.input, got, tc.exceptedResult)
+			}
+		})
+	}
+}
+
+func TestCalcContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := CalcContext(ctx, "1 + 2", Options{}); err != context.Canceled {
+		t.Errorf("CalcContext: got error %q, excepted %q", err, context.Canceled)
+	}
+	if _, err := CalcComplexContext(ctx, "1 + 2i", Options{}); err != context.Canceled {
+		t.Errorf("CalcComplexContext: got error %q, excepted %q", err, context.Canceled)
+	}
+	if _, err := CalcQuantityContext(ctx, "1 km in m", Scope{}, Options{}); err != context.Canceled {
+		t.Errorf("CalcQuantityContext: got error %q, excepted %q", err, context.Canceled)
+	}
+}
+
+func TestCalcQuantityContextStepsOfFunctions(t *testing.T) {
+	functions := Functions{}
+	if err := functions.Define("f", []string{"x"}, "x + x + x + x"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+	if err := functions.Define("g", []string{"x"}, "f(x) + f(x) + f(x)"); err != nil {
+		t.Fatalf("Define: unexcepted error %q", err)
+	}
+	scope := Scope{Functions: functions}
+
+	// The expression is short, but calls of functions take many steps
+	_, err := CalcQuantityContext(context.Background(), "g(g(1))", scope, Options{MaxSteps: 50})
+	if err != ErrStepLimit {
+		t.Errorf("CalcQuantityContext: got error %q, excepted %q", err, ErrStepLimit)
+	}
+
+	got, err := CalcQuantityContext(context.Background(), "g(g(1))", scope, Options{MaxSteps: 200})
+	if err != nil {
+		t.Fatalf("CalcQuantityContext: unexcepted error %q", err)
+	}
+	if got.Value != 144 {
+		t.Errorf("CalcQuantityContext: got %v, excepted %v", got.Value, 144)
+	}
+}
@@ -0,0 +1,254 @@
+package calc
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// singularTolerance is the relative size of pivot below which a matrix is
+// treated as singular by inv
+const singularTolerance = 1e-12
+
+// MatrixLiteral returns the token of matrix literal with its shape used in
+// reverse Polish notation, e.g. "[2x3]"
+func MatrixLiteral(rows, cols int) string {
+	return "[" + Shape{Rows: rows, Cols: cols}.String() + "]"
+}
+
+// ParseMatrixLiteral returns the number of rows and columns of matrix literal
+// token made by MatrixLiteral
+func ParseMatrixLiteral(token string) (int, int, bool) {
+	shape, found := strings.CutPrefix(token, "[")
+	if !found {
+		return 0, 0, false
+	}
+	shape, found = strings.CutSuffix(shape, "]")
+	if !found {
+		return 0, 0, false
+	}
+	rows, cols, found := strings.Cut(shape, "x")
+	if !found {
+		return 0, 0, false
+	}
+	r, err := strconv.Atoi(rows)
+	if err != nil || r <= 0 {
+		return 0, 0, false
+	}
+	c, err := strconv.Atoi(cols)
+	if err != nil || c <= 0 {
+		return 0, 0, false
+	}
+	return r, c, true
+}
+
+// NewMatrix returns the dimensionless matrix with the rows. A vector is
+// a matrix with a single row or a single column
+func NewMatrix(rows [][]float64) (Value, error) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return Value{}, ErrInvalidMatrix
+	}
+
+	matrix := Value{Shape: Shape{Rows: len(rows), Cols: len(rows[0])}}
+	for _, row := range rows {
+		if len(row) != matrix.Shape.Cols {
+			return Value{}, ErrInvalidMatrix
+		}
+		matrix.Elements = append(matrix.Elements, row...)
+	}
+	return matrix, nil
+}
+
+// matrixLiteral makes the matrix from numbers written row by row. All numbers
+// must have the same dimension
+func matrixLiteral(rows, cols int, elements []Value) (Value, error) {
+	matrix := Value{Shape: Shape{Rows: rows, Cols: cols}, Elements: make([]float64, len(elements))}
+	for i, element := range elements {
+		if !element.IsNumber() {
+			return Value{}, ErrInvalidMatrix
+		}
+		if i == 0 {
+			matrix.Dimension = element.Dimension
+		} else if element.Dimension != matrix.Dimension {
+			return Value{}, &DimensionError{Operation: ",", Left: matrix.Dimension, Right: element.Dimension}
+		}
+		matrix.Elements[i] = element.Number()
+	}
+	return matrix, nil
+}
+
+// asMatrix returns the number as a matrix of a single element
+func asMatrix(v Value) Value {
+	if v.IsNumber() {
+		v.Shape = Shape{Rows: 1, Cols: 1}
+	}
+	return v
+}
+
+// matrixProduct returns the product of matrices a and b
+func matrixProduct(token string, a, b Value) (Value, error) {
+	if a.Shape.Cols != b.Shape.Rows {
+		return Value{}, &ShapeError{Operation: token, Shapes: []Shape{a.Shape, b.Shape}}
+	}
+
+	result := Value{
+		Shape:     Shape{Rows: a.Shape.Rows, Cols: b.Shape.Cols},
+		Elements:  make([]float64, a.Shape.Rows*b.Shape.Cols),
+		Dimension: a.Dimension.add(b.Dimension),
+	}
+	for i := 0; i < a.Shape.Rows; i++ {
+		for j := 0; j < b.Shape.Cols; j++ {
+			var sum float64
+			for k := 0; k < a.Shape.Cols; k++ {
+				sum += a.at(i, k) * b.at(k, j)
+			}
+			result.Elements[i*result.Shape.Cols+j] = sum
+		}
+	}
+	return result, nil
+}
+
+// transpose returns the matrix with rows written as columns
+func transpose(arguments []Value) (Value, error) {
+	a := arguments[0]
+	if a.IsNumber() {
+		return a, nil
+	}
+
+	result := Value{
+		Shape:     Shape{Rows: a.Shape.Cols, Cols: a.Shape.Rows},
+		Elements:  make([]float64, len(a.Elements)),
+		Dimension: a.Dimension,
+	}
+	for i := 0; i < a.Shape.Rows; i++ {
+		for j := 0; j < a.Shape.Cols; j++ {
+			result.Elements[j*result.Shape.Cols+i] = a.at(i, j)
+		}
+	}
+	return result, nil
+}
+
+// determinant returns the determinant of square matrix calculated by
+// Gaussian elimination with partial pivoting
+func determinant(arguments []Value) (Value, error) {
+	a := asMatrix(arguments[0])
+	if a.Shape.Rows != a.Shape.Cols {
+		return Value{}, &ShapeError{Operation: "det", Shapes: []Shape{arguments[0].Shape}}
+	}
+	n := a.Shape.Rows
+
+	rows := copyRows(a)
+	result := 1.0
+	for column := 0; column < n; column++ {
+		pivot := pivotRow(rows, column)
+		if rows[pivot][column] == 0 {
+			return scalar(0, a.Dimension.mul(n)), nil
+		}
+		if pivot != column {
+			rows[pivot], rows[column] = rows[column], rows[pivot]
+			result = -result
+		}
+		result *= rows[column][column]
+
+		for i := column + 1; i < n; i++ {
+			factor := rows[i][column] / rows[column][column]
+			for j := column; j < n; j++ {
+				rows[i][j] -= factor * rows[column][j]
+			}
+		}
+	}
+	return scalar(result, a.Dimension.mul(n)), nil
+}
+
+// inverse returns the inverse of square matrix calculated by Gauss-Jordan
+// elimination. Singular matrix has no inverse
+func inverse(arguments []Value) (Value, error) {
+	a := asMatrix(arguments[0])
+	if a.Shape.Rows != a.Shape.Cols {
+		return Value{}, &ShapeError{Operation: "inv", Shapes: []Shape{arguments[0].Shape}}
+	}
+	n := a.Shape.Rows
+
+	// Tolerance is relative to the largest element
+	var largest float64
+	for _, x := range a.Elements {
+		largest = math.Max(largest, math.Abs(x))
+	}
+
+	// Augment the matrix with the identity matrix
+	rows := copyRows(a)
+	for i := range rows {
+		identity := make([]float64, n)
+		identity[i] = 1
+		rows[i] = append(rows[i], identity...)
+	}
+
+	for column := 0; column < n; column++ {
+		pivot := pivotRow(rows, column)
+		if math.Abs(rows[pivot][column]) <= singularTolerance*largest {
+			return Value{}, ErrSingularMatrix
+		}
+		rows[pivot], rows[column] = rows[column], rows[pivot]
+
+		divisor := rows[column][column]
+		for j := range rows[column] {
+			rows[column][j] /= divisor
+		}
+		for i := range rows {
+			if i == column {
+				continue
+			}
+			factor := rows[i][column]
+			for j := range rows[i] {
+				rows[i][j] -= factor * rows[column][j]
+			}
+		}
+	}
+
+	result := Value{
+		Shape:     arguments[0].Shape,
+		Elements:  make([]float64, 0, n*n),
+		Dimension: Dimension{}.sub(a.Dimension),
+	}
+	for _, row := range rows {
+		result.Elements = append(result.Elements, row[n:]...)
+	}
+	return result, nil
+}
+
+// dot returns the dot product of vectors with the same number of elements.
+// Vectors could be written as rows or as columns
+func dot(arguments []Value) (Value, error) {
+	a, b := asMatrix(arguments[0]), asMatrix(arguments[1])
+	isVector := func(v Value) bool { return v.Shape.Rows == 1 || v.Shape.Cols == 1 }
+	if !isVector(a) || !isVector(b) || len(a.Elements) != len(b.Elements) {
+		return Value{}, &ShapeError{Operation: "dot", Shapes: []Shape{arguments[0].Shape, arguments[1].Shape}}
+	}
+
+	var result float64
+	for i := range a.Elements {
+		result += a.Elements[i] * b.Elements[i]
+	}
+	return scalar(result, a.Dimension.add(b.Dimension)), nil
+}
+
+// copyRows returns the copy of matrix elements as rows
+func copyRows(a Value) [][]float64 {
+	rows := make([][]float64, a.Shape.Rows)
+	for i := range rows {
+		rows[i] = append([]float64(nil), a.Elements[i*a.Shape.Cols:(i+1)*a.Shape.Cols]...)
+	}
+	return rows
+}
+
+// pivotRow returns the row with the largest absolute value in the column
+// among rows starting from the column
+func pivotRow(rows [][]float64, column int) int {
+	pivot := column
+	for i := column + 1; i < len(rows); i++ {
+		if math.Abs(rows[i][column]) > math.Abs(rows[pivot][column]) {
+			pivot = i
+		}
+	}
+	return pivot
+}
@@ -0,0 +1,226 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCalcValue(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		excepted Value
+	}{
+		{
+			name:     "Row vector",
+			input:    "[1, 2, 3]",
+			excepted: Value{Shape: Shape{1, 3}, Elements: []float64{1, 2, 3}},
+		},
+		{
+			name:     "Column vector with expressions",
+			input:    "[1 + 1; -2; 3 * 2]",
+			excepted: Value{Shape: Shape{3, 1}, Elements: []float64{2, -2, 6}},
+		},
+		{
+			name:     "Sum",
+			input:    "[1, 2; 3, 4] + [4, 3; 2, 1]",
+			excepted: Value{Shape: Shape{2, 2}, Elements: []float64{5, 5, 5, 5}},
+		},
+		{
+			name:     "Number and matrix",
+			input:    "2 * [1, 2] - 1",
+			excepted: Value{Shape: Shape{1, 2}, Elements: []float64{1, 3}},
+		},
+		{
+			name:     "Division by number",
+			input:    "[2, 4] / 2",
+			excepted: Value{Shape: Shape{1, 2}, Elements: []float64{1, 2}},
+		},
+		{
+			name:     "Matrix product",
+			input:    "[1, 2; 3, 4] * [5; 6]",
+			excepted: Value{Shape: Shape{2, 1}, Elements: []float64{17, 39}},
+		},
+		{
+			name:     "Transpose",
+			input:    "transpose([1, 2, 3; 4, 5, 6])",
+			excepted: Value{Shape: Shape{3, 2}, Elements: []float64{1, 4, 2, 5, 3, 6}},
+		},
+		{
+			name:     "Determinant",
+			input:    "det([1, 2; 3, 4])",
+			excepted: Value{Elements: []float64{-2}},
+		},
+		{
+			name:     "Determinant with swapped rows",
+			input:    "det([0, 1, 0; 1, 0, 0; 0, 0, 2])",
+			excepted: Value{Elements: []float64{-2}},
+		},
+		{
+			name:     "Inverse",
+			input:    "inv([2, 0; 0, 4])",
+			excepted: Value{Shape: Shape{2, 2}, Elements: []float64{0.5, 0, 0, 0.25}},
+		},
+		{
+			name:     "Dot product of row and column",
+			input:    "dot([1, 2, 3], [4; 5; 6])",
+			excepted: Value{Elements: []float64{32}},
+		},
+		{
+			name:     "Negation",
+			input:    "-[1, -2]",
+			excepted: Value{Shape: Shape{1, 2}, Elements: []float64{-1, 2}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalcValue(tc.input, nil)
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.excepted) {
+				t.Errorf("CalcValue(%q): got %+v, excepted %+v", tc.input, got, tc.excepted)
+			}
+		})
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		expectedErr error
+	}{
+		{
+			name:        "Rows of different length",
+			expression:  "[1, 2; 3]",
+			expectedErr: ErrInvalidMatrix,
+		},
+		{
+			name:        "Empty matrix",
+			expression:  "[]",
+			expectedErr: ErrInvalidMatrix,
+		},
+		{
+			name:        "Empty element",
+			expression:  "[1, , 2]",
+			expectedErr: ErrInvalidMatrix,
+		},
+		{
+			name:        "Nested matrix",
+			expression:  "[[1, 2], 3]",
+			expectedErr: ErrInvalidMatrix,
+		},
+		{
+			name:        "Semicolon outside of matrix",
+			expression:  "(1; 2)",
+			expectedErr: ErrInvalidMatrix,
+		},
+		{
+			name:        "Singular matrix",
+			expression:  "inv([1, 2; 2, 4])",
+			expectedErr: ErrSingularMatrix,
+		},
+		{
+			name:        "Wrong number of arguments",
+			expression:  "dot([1, 2])",
+			expectedErr: ErrArguments,
+		},
+		{
+			name:        "Operator before closing bracket",
+			expression:  "[1, 2 +]",
+			expectedErr: ErrExtraOperands,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CalcValue(tc.expression, nil)
+			if err != tc.expectedErr {
+				t.Errorf("CalcValue(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCalcValueShapeError(t *testing.T) {
+	cases := []struct {
+		name       string
+		expression string
+		excepted   ShapeError
+	}{
+		{
+			name:       "Sum of different shapes",
+			expression: "[1, 2] + [1, 2, 3]",
+			excepted:   ShapeError{Operation: "+", Shapes: []Shape{{1, 2}, {1, 3}}},
+		},
+		{
+			name:       "Product of incompatible matrices",
+			expression: "[1, 2] * [1, 2]",
+			excepted:   ShapeError{Operation: "*", Shapes: []Shape{{1, 2}, {1, 2}}},
+		},
+		{
+			name:       "Division by matrix",
+			expression: "1 / [1, 2]",
+			excepted:   ShapeError{Operation: "/", Shapes: []Shape{{}, {1, 2}}},
+		},
+		{
+			name:       "Determinant of not square matrix",
+			expression: "det([1, 2])",
+			excepted:   ShapeError{Operation: "det", Shapes: []Shape{{1, 2}}},
+		},
+		{
+			name:       "Dot product of vectors with different length",
+			expression: "dot([1, 2], [1, 2, 3])",
+			excepted:   ShapeError{Operation: "dot", Shapes: []Shape{{1, 2}, {1, 3}}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CalcValue(tc.expression, nil)
+
+			var shapeError *ShapeError
+			if !errors.As(err, &shapeError) {
+				t.Fatalf("CalcValue(%q): got error %q, excepted ShapeError", tc.expression, err)
+			}
+			if !reflect.DeepEqual(*shapeError, tc.excepted) {
+				t.Errorf("CalcValue(%q): got %+v, excepted %+v", tc.expression, *shapeError, tc.excepted)
+			}
+		})
+	}
+}
+
+func TestInverse(t *testing.T) {
+	matrix, err := CalcValue("[4, 7, 2; 3, 6, 1; 2, 5, 3]", nil)
+	if err != nil {
+		t.Fatalf("CalcValue: unexcepted error %q", err)
+	}
+	inverse, err := inverse([]Value{matrix})
+	if err != nil {
+		t.Fatalf("inverse: unexcepted error %q", err)
+	}
+
+	// Product of matrix and its inverse is the identity matrix
+	product, err := matrixProduct("*", matrix, inverse)
+	if err != nil {
+		t.Fatalf("matrixProduct: unexcepted error %q", err)
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			excepted := 0.0
+			if i == j {
+				excepted = 1
+			}
+			if math.Abs(product.at(i, j)-excepted) > 1e-12 {
+				t.Errorf("element (%d, %d) of product: got %v, excepted %v", i, j, product.at(i, j), excepted)
+			}
+		}
+	}
+}
+
+func TestCalcResultIsNotNumber(t *testing.T) {
+	_, err := Calc("[1, 2]")
+	if err != ErrNotNumber {
+		t.Errorf("Calc: got error %q, excepted %q", err, ErrNotNumber)
+	}
+}
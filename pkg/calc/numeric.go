@@ -0,0 +1,178 @@
+package calc
+
+import (
+	"context"
+	"math"
+)
+
+// DefaultTolerance is used by Integrate when tolerance is not positive
+const DefaultTolerance = 1e-9
+
+// MaxIntegrationSteps limits the number of expression evaluations made by
+// Integrate. IntegrateContext is limited by Options.MaxIterations instead
+const MaxIntegrationSteps = 100000
+
+// MaxSumTerms limits the number of terms added by Sum. SumContext is limited
+// by Options.MaxIterations instead
+const MaxSumTerms = 100000
+
+// maxIntegrationDepth limits the recursion of adaptive Simpson quadrature
+const maxIntegrationDepth = 50
+
+// Integrate calculates the definite integral of expression by variable from a
+// to b using adaptive Simpson quadrature. The estimated error of the result is
+// not bigger than tolerance
+func Integrate(expression, variable string, a, b, tolerance float64) (float64, error) {
+	return IntegrateContext(context.Background(), expression, variable, a, b, tolerance, Options{MaxIterations: MaxIntegrationSteps})
+}
+
+// IntegrateContext integrates the expression like Integrate. Every evaluation
+// of the expression is within limits of options, integration is stopped when
+// the context is done
+func IntegrateContext(ctx context.Context, expression, variable string, a, b, tolerance float64, options Options) (float64, error) {
+	if !IsIdentifier(variable) {
+		return 0, ErrInvalidVariable
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	f, err := newFunction(ctx, expression, variable, options)
+	if err != nil {
+		return 0, err
+	}
+
+	// Calculate values at the borders and in the middle
+	fa, err := f.eval(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := f.eval(b)
+	if err != nil {
+		return 0, err
+	}
+	fm, err := f.eval((a + b) / 2)
+	if err != nil {
+		return 0, err
+	}
+
+	whole := (b - a) / 6 * (fa + 4*fm + fb)
+	return f.simpson(a, b, fa, fm, fb, whole, tolerance, maxIntegrationDepth)
+}
+
+// Sum adds up the values of expression for every integer value of variable
+// from from to to inclusively
+func Sum(expression, variable string, from, to int) (float64, error) {
+	return SumContext(context.Background(), expression, variable, from, to, Options{MaxIterations: MaxSumTerms})
+}
+
+// SumContext adds up the values of expression like Sum. Every evaluation of
+// the expression is within limits of options, summation is stopped when the
+// context is done
+func SumContext(ctx context.Context, expression, variable string, from, to int, options Options) (float64, error) {
+	if !IsIdentifier(variable) {
+		return 0, ErrInvalidVariable
+	}
+	if from <= to && (to-from < 0 || (options.MaxIterations > 0 && to-from >= options.MaxIterations)) {
+		return 0, ErrIterationLimit
+	}
+
+	f, err := newFunction(ctx, expression, variable, options)
+	if err != nil {
+		return 0, err
+	}
+
+	var result float64
+	for i := from; i <= to; i++ {
+		term, err := f.eval(float64(i))
+		if err != nil {
+			return 0, err
+		}
+		result += term
+	}
+
+	return result, nil
+}
+
+// function is an expression of a single variable prepared for multiple
+// evaluations
+type function struct {
+	tokens     []string
+	variable   string
+	values     Values
+	limit      *limiter
+	iterations int
+	// maxIterations limits the number of evaluations, zero means that there is no limit
+	maxIterations int
+}
+
+func newFunction(ctx context.Context, expression, variable string, options Options) (*function, error) {
+	values := Values{variable: NewNumber(0)}
+
+	limit := newLimiter(ctx, options)
+	tokens, err := defaultEngine.compile(expression, values.isName, options, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &function{
+		tokens:        tokens,
+		variable:      variable,
+		values:        values,
+		limit:         limit,
+		maxIterations: options.MaxIterations,
+	}, nil
+}
+
+// eval calculates the expression with the given value of variable
+func (f *function) eval(x float64) (float64, error) {
+	f.iterations++
+	if f.maxIterations > 0 && f.iterations > f.maxIterations {
+		return 0, ErrIterationLimit
+	}
+
+	f.limit.steps = 0
+	f.values[f.variable] = NewNumber(x)
+	result, err := defaultEngine.evalValue(f.tokens, defaultEngine.withConstants(f.values.lookup), nil, f.limit)
+	if err != nil {
+		return 0, err
+	}
+	if !result.IsNumber() {
+		return 0, ErrNotNumber
+	}
+	return result.Number(), nil
+}
+
+// simpson recursively splits [a, b] in halves until the estimated error of
+// Simpson's rule fits into tolerance
+func (f *function) simpson(a, b, fa, fm, fb, whole, tolerance float64, depth int) (float64, error) {
+	m := (a + b) / 2
+
+	flm, err := f.eval((a + m) / 2)
+	if err != nil {
+		return 0, err
+	}
+	frm, err := f.eval((m + b) / 2)
+	if err != nil {
+		return 0, err
+	}
+
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	delta := left + right - whole
+
+	if depth <= 0 || math.Abs(delta) <= 15*tolerance {
+		return left + right + delta/15, nil
+	}
+
+	leftResult, err := f.simpson(a, m, fa, flm, fm, left, tolerance/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+	rightResult, err := f.simpson(m, b, fm, frm, fb, right, tolerance/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+
+	return leftResult + rightResult, nil
+}
@@ -0,0 +1,258 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestIntegrate(t *testing.T) {
+	cases := []struct {
+		name       string
+		expression string
+		variable   string
+		a, b       float64
+		excepted   float64
+	}{
+		{
+			name:       "Constant",
+			expression: "2",
+			variable:   "x",
+			a:          0,
+			b:          5,
+			excepted:   10,
+		},
+		{
+			name:       "Square",
+			expression: "x^2",
+			variable:   "x",
+			a:          0,
+			b:          3,
+			excepted:   9,
+		},
+		{
+			name:       "Reversed borders",
+			expression: "x^2",
+			variable:   "x",
+			a:          3,
+			b:          0,
+			excepted:   -9,
+		},
+		{
+			name:       "Fractional power",
+			expression: "t^0.5",
+			variable:   "t",
+			a:          0,
+			b:          4,
+			excepted:   16.0 / 3,
+		},
+		{
+			name:       "Hyperbola",
+			expression: "1 / x",
+			variable:   "x",
+			a:          1,
+			b:          math.E,
+			excepted:   1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Integrate(tc.expression, tc.variable, tc.a, tc.b, 1e-9)
+			if err != nil {
+				t.Fatalf("Integrate(%q): unexcepted error %q", tc.expression, err)
+			}
+			if math.Abs(got-tc.excepted) > 1e-6 {
+				t.Errorf("Integrate(%q): got %f, excepted %f", tc.expression, got, tc.excepted)
+			}
+		})
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		variable    string
+		a, b        float64
+		expectedErr error
+	}{
+		{
+			name:        "Invalid variable",
+			expression:  "x",
+			variable:    "1x",
+			b:           1,
+			expectedErr: ErrInvalidVariable,
+		},
+		{
+			name:        "Unknown variable",
+			expression:  "x + y",
+			variable:    "x",
+			b:           1,
+			expectedErr: ErrExtraCharacters,
+		},
+		{
+			name:        "Division by zero",
+			expression:  "1 / x",
+			variable:    "x",
+			a:           -1,
+			b:           1,
+			expectedErr: ErrZeroByDivision,
+		},
+		{
+			name:        "Singularity",
+			expression:  "1 / (x - 0.1)",
+			variable:    "x",
+			a:           0,
+			b:           1,
+			expectedErr: ErrIterationLimit,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Integrate(tc.expression, tc.variable, tc.a, tc.b, 1e-9)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Integrate(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	cases := []struct {
+		name       string
+		expression string
+		variable   string
+		from, to   int
+		excepted   float64
+	}{
+		{
+			name:       "Arithmetic progression",
+			expression: "i",
+			variable:   "i",
+			from:       1,
+			to:         100,
+			excepted:   5050,
+		},
+		{
+			name:       "Discounted cash flow",
+			expression: "100 / (1 + 0.1)^n",
+			variable:   "n",
+			from:       1,
+			to:         2,
+			excepted:   100/1.1 + 100/1.21,
+		},
+		{
+			name:       "Empty range",
+			expression: "i",
+			variable:   "i",
+			from:       5,
+			to:         1,
+			excepted:   0,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Sum(tc.expression, tc.variable, tc.from, tc.to)
+			if err != nil {
+				t.Fatalf("Sum(%q): unexcepted error %q", tc.expression, err)
+			}
+			if math.Abs(got-tc.excepted) > 1e-9 {
+				t.Errorf("Sum(%q): got %f, excepted %f", tc.expression, got, tc.excepted)
+			}
+		})
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		variable    string
+		from, to    int
+		expectedErr error
+	}{
+		{
+			name:        "Too many terms",
+			expression:  "i",
+			variable:    "i",
+			from:        0,
+			to:          MaxSumTerms,
+			expectedErr: ErrIterationLimit,
+		},
+		{
+			name:        "Overflowed range",
+			expression:  "i",
+			variable:    "i",
+			from:        math.MinInt,
+			to:          math.MaxInt,
+			expectedErr: ErrIterationLimit,
+		},
+		{
+			name:        "Division by zero",
+			expression:  "1 / i",
+			variable:    "i",
+			from:        0,
+			to:          1,
+			expectedErr: ErrZeroByDivision,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Sum(tc.expression, tc.variable, tc.from, tc.to)
+			if err != tc.expectedErr {
+				t.Errorf("Sum(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestNumericContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name        string
+		calculate   func() (float64, error)
+		expectedErr error
+	}{
+		{
+			name: "Sum within iterations",
+			calculate: func() (float64, error) {
+				return SumContext(context.Background(), "i", "i", 1, 10, Options{MaxIterations: 10})
+			},
+		},
+		{
+			name: "Too many terms",
+			calculate: func() (float64, error) {
+				return SumContext(context.Background(), "i", "i", 0, 10, Options{MaxIterations: 10})
+			},
+			expectedErr: ErrIterationLimit,
+		},
+		{
+			name: "Too many evaluations of integral",
+			calculate: func() (float64, error) {
+				return IntegrateContext(context.Background(), "x^0.5", "x", 0, 1, 1e-12, Options{MaxIterations: 5})
+			},
+			expectedErr: ErrIterationLimit,
+		},
+		{
+			name: "Steps of term",
+			calculate: func() (float64, error) {
+				return SumContext(context.Background(), "i * 2", "i", 1, 10, Options{MaxSteps: 2})
+			},
+			expectedErr: ErrStepLimit,
+		},
+		{
+			name: "Cancelled context",
+			calculate: func() (float64, error) {
+				return IntegrateContext(cancelled, "x", "x", 0, 1, 0, Options{})
+			},
+			expectedErr: context.Canceled,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.calculate()
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("got error %q, expected error %q", err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// MaxSamplePoints limits the number of points returned by Sample
+const MaxSamplePoints = 10000
+
+// Point is a value of expression at X. Err is set instead of Y when
+// the expression could not be calculated at X or its value is not finite
+type Point struct {
+	X   float64
+	Y   float64
+	Err error
+}
+
+// Sample calculates the expression by variable at points evenly spaced from
+// from to to inclusively. Errors of calculation at separate points do not stop
+// the sampling and are returned in the points themselves
+func Sample(expression, variable string, from, to float64, points int) ([]Point, error) {
+	return SampleContext(context.Background(), expression, variable, from, to, points, Options{})
+}
+
+// SampleContext samples the expression like Sample. Every evaluation of the
+// expression is within limits of options, and the number of points is limited
+// by Options.MaxIterations too. Sampling is stopped when the context is done or
+// a point exceeds the limit of steps
+func SampleContext(ctx context.Context, expression, variable string, from, to float64, points int, options Options) ([]Point, error) {
+	if !IsIdentifier(variable) {
+		return nil, ErrInvalidVariable
+	}
+	if points < 2 || from >= to || math.IsInf(from, 0) || math.IsInf(to, 0) || math.IsNaN(from) || math.IsNaN(to) {
+		return nil, ErrInvalidRange
+	}
+	if points > MaxSamplePoints || (options.MaxIterations > 0 && points > options.MaxIterations) {
+		return nil, ErrIterationLimit
+	}
+
+	f, err := newFunction(ctx, expression, variable, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// The width of huge range overflows, and points would not be finite
+	width := to - from
+	if math.IsInf(width, 0) {
+		return nil, ErrInvalidRange
+	}
+
+	step := width / float64(points-1)
+	result := make([]Point, points)
+	for i := range result {
+		x := from + step*float64(i)
+		if i == points-1 {
+			x = to
+		}
+
+		y, err := f.eval(x)
+		// Limits stop the whole sampling, other errors are gaps
+		if err != nil && (errors.Is(err, ErrStepLimit) || ctx.Err() != nil) {
+			return nil, err
+		}
+		if err == nil && math.IsNaN(y) {
+			err = ErrDomain
+		} else if err == nil && math.IsInf(y, 0) {
+			err = ErrOverflow
+		}
+		result[i] = Point{X: x, Y: y, Err: err}
+	}
+
+	return result, nil
+}
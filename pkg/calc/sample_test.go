@@ -0,0 +1,175 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	points, err := Sample("1 / x", "x", -1, 1, 5)
+	if err != nil {
+		t.Fatalf("Sample: unexcepted error %q", err)
+	}
+
+	excepted := []Point{
+		{X: -1, Y: -1},
+		{X: -0.5, Y: -2},
+		{X: 0, Err: ErrZeroByDivision},
+		{X: 0.5, Y: 2},
+		{X: 1, Y: 1},
+	}
+	if len(points) != len(excepted) {
+		t.Fatalf("Sample: got %d points, excepted %d", len(points), len(excepted))
+	}
+	for i := range excepted {
+		if points[i] != excepted[i] {
+			t.Errorf("Sample: point %d is %v, excepted %v", i, points[i], excepted[i])
+		}
+	}
+
+	// Domain errors are gaps too
+	points, err = Sample("x ^ 0.5", "x", -1, 1, 3)
+	if err != nil {
+		t.Fatalf("Sample: unexcepted error %q", err)
+	}
+	if points[0].Err != ErrDomain || points[2].Err != nil {
+		t.Errorf("Sample: got points %v, excepted gap at the first point", points)
+	}
+
+	// Results which are too large are gaps
+	points, err = Sample("x ^ 2", "x", 1e100, 1e200, 2)
+	if err != nil {
+		t.Fatalf("Sample: unexcepted error %q", err)
+	}
+	if points[0].Err != nil || points[1].Err != ErrOverflow {
+		t.Errorf("Sample: got points %v, excepted gap at the last point", points)
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		variable    string
+		from, to    float64
+		points      int
+		expectedErr error
+	}{
+		{
+			name:        "Single point",
+			expression:  "x",
+			variable:    "x",
+			from:        0,
+			to:          1,
+			points:      1,
+			expectedErr: ErrInvalidRange,
+		},
+		{
+			name:        "Reversed range",
+			expression:  "x",
+			variable:    "x",
+			from:        1,
+			to:          0,
+			points:      10,
+			expectedErr: ErrInvalidRange,
+		},
+		{
+			name:        "Too wide range",
+			expression:  "x",
+			variable:    "x",
+			from:        -1.7e308,
+			to:          1.7e308,
+			points:      3,
+			expectedErr: ErrInvalidRange,
+		},
+		{
+			name:        "Too many points",
+			expression:  "x",
+			variable:    "x",
+			from:        0,
+			to:          1,
+			points:      MaxSamplePoints + 1,
+			expectedErr: ErrIterationLimit,
+		},
+		{
+			name:        "Invalid expression",
+			expression:  "x +",
+			variable:    "x",
+			from:        0,
+			to:          1,
+			points:      10,
+			expectedErr: ErrExtraOperands,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Sample(tc.expression, tc.variable, tc.from, tc.to, tc.points)
+			if err != tc.expectedErr {
+				t.Errorf("Sample(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestSampleContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name        string
+		ctx         context.Context
+		expression  string
+		points      int
+		options     Options
+		expectedErr error
+	}{
+		{
+			name:       "Within limits",
+			ctx:        context.Background(),
+			expression: "1 / x",
+			points:     11,
+			options:    Options{MaxSteps: 3, MaxIterations: 11},
+		},
+		{
+			name:        "Too many points",
+			ctx:         context.Background(),
+			expression:  "x",
+			points:      11,
+			options:     Options{MaxIterations: 10},
+			expectedErr: ErrIterationLimit,
+		},
+		{
+			name:        "Steps of point",
+			ctx:         context.Background(),
+			expression:  "x * 2 + 1",
+			points:      11,
+			options:     Options{MaxSteps: 2},
+			expectedErr: ErrStepLimit,
+		},
+		{
+			name:        "Too long expression",
+			ctx:         context.Background(),
+			expression:  "x + 1",
+			points:      11,
+			options:     Options{MaxLength: 3},
+			expectedErr: ErrExpressionTooLong,
+		},
+		{
+			name:        "Cancelled context",
+			ctx:         cancelled,
+			expression:  "x",
+			points:      11,
+			expectedErr: context.Canceled,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			points, err := SampleContext(tc.ctx, tc.expression, "x", -1, 1, tc.points, tc.options)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("got error %q, expected error %q", err, tc.expectedErr)
+			}
+			if err == nil && len(points) != tc.points {
+				t.Errorf("got %d points, expected %d", len(points), tc.points)
+			}
+		})
+	}
+}
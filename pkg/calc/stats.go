@@ -0,0 +1,152 @@
+package calc
+
+import (
+	"math"
+	"slices"
+)
+
+// elements returns all elements of arguments and their common dimension
+func elements(operation string, arguments []Value) ([]float64, Dimension, error) {
+	var result []float64
+	dimension := arguments[0].Dimension
+	for _, argument := range arguments {
+		if argument.Dimension != dimension {
+			return nil, Dimension{}, &DimensionError{Operation: operation, Left: dimension, Right: argument.Dimension}
+		}
+		result = append(result, argument.Elements...)
+	}
+	return result, dimension, nil
+}
+
+// sumOf returns the sum of all elements
+func sumOf(arguments []Value) (Value, error) {
+	values, dimension, err := elements("sum", arguments)
+	if err != nil {
+		return Value{}, err
+	}
+
+	var result float64
+	for _, x := range values {
+		result += x
+	}
+	return scalar(result, dimension), nil
+}
+
+// productOf returns the product of all elements
+func productOf(arguments []Value) (Value, error) {
+	values, dimension, err := elements("prod", arguments)
+	if err != nil {
+		return Value{}, err
+	}
+	if len(values) > maxUnitPower && !dimension.IsDimensionless() {
+		return Value{}, ErrOverflow
+	}
+
+	result := 1.0
+	for _, x := range values {
+		result *= x
+	}
+	return scalar(result, dimension.mul(len(values))), nil
+}
+
+// mean returns the arithmetic mean of all elements
+func mean(arguments []Value) (Value, error) {
+	values, dimension, err := elements("mean", arguments)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return scalar(average(values), dimension), nil
+}
+
+// median returns the middle element, or the mean of two middle elements
+func median(arguments []Value) (Value, error) {
+	values, dimension, err := elements("median", arguments)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return scalar(quantile(values, 0.5), dimension), nil
+}
+
+// variance returns the sample variance of all elements. It needs at least two elements
+func variance(arguments []Value) (Value, error) {
+	values, dimension, err := elements("variance", arguments)
+	if err != nil {
+		return Value{}, err
+	}
+	if len(values) < 2 {
+		return Value{}, ErrDomain
+	}
+
+	return scalar(sampleVariance(values), dimension.mul(2)), nil
+}
+
+// stdev returns the sample standard deviation of all elements. It needs at least two elements
+func stdev(arguments []Value) (Value, error) {
+	values, dimension, err := elements("stdev", arguments)
+	if err != nil {
+		return Value{}, err
+	}
+	if len(values) < 2 {
+		return Value{}, ErrDomain
+	}
+
+	return scalar(math.Sqrt(sampleVariance(values)), dimension), nil
+}
+
+// percentile returns the p-th percentile of elements following the first
+// argument p from 0 to 100. Values between elements are interpolated linearly
+func percentile(arguments []Value) (Value, error) {
+	p := arguments[0]
+	if !p.IsNumber() {
+		return Value{}, &ShapeError{Operation: "percentile", Shapes: []Shape{p.Shape}}
+	}
+	if !p.Dimension.IsDimensionless() {
+		return Value{}, &DimensionError{Operation: "percentile", Left: p.Dimension, Right: Dimension{}}
+	}
+	if p.Number() < 0 || p.Number() > 100 {
+		return Value{}, ErrDomain
+	}
+
+	values, dimension, err := elements("percentile", arguments[1:])
+	if err != nil {
+		return Value{}, err
+	}
+
+	return scalar(quantile(values, p.Number()/100), dimension), nil
+}
+
+// average returns the arithmetic mean of values
+func average(values []float64) float64 {
+	var sum float64
+	for _, x := range values {
+		sum += x
+	}
+	return sum / float64(len(values))
+}
+
+// sampleVariance returns the variance of values with Bessel's correction
+func sampleVariance(values []float64) float64 {
+	m := average(values)
+
+	var sum float64
+	for _, x := range values {
+		sum += (x - m) * (x - m)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// quantile returns the q-th quantile of values, q is from 0 to 1. Values
+// between elements are interpolated linearly
+func quantile(values []float64, q float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	if lower == len(sorted)-1 {
+		return sorted[lower]
+	}
+	return sorted[lower] + (position-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
@@ -0,0 +1,182 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStatistics(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		excepted float64
+	}{
+		{
+			name:     "Sum of arguments",
+			input:    "sum(1, 2, 3.5)",
+			excepted: 6.5,
+		},
+		{
+			name:     "Sum of matrix elements",
+			input:    "sum([1, 2; 3, 4])",
+			excepted: 10,
+		},
+		{
+			name:     "Product of numbers and vector",
+			input:    "prod(2, [3, 4])",
+			excepted: 24,
+		},
+		{
+			name:     "Mean",
+			input:    "mean(1, 2, 3, 4)",
+			excepted: 2.5,
+		},
+		{
+			name:     "Median of odd number of elements",
+			input:    "median(5, 1, 3)",
+			excepted: 3,
+		},
+		{
+			name:     "Median of even number of elements",
+			input:    "median([4, 1, 3, 2])",
+			excepted: 2.5,
+		},
+		{
+			name:     "Sample variance",
+			input:    "variance(2, 4, 4, 4, 5, 5, 7, 9)",
+			excepted: 32.0 / 7,
+		},
+		{
+			name:     "Sample standard deviation",
+			input:    "stdev(1, 3)",
+			excepted: math.Sqrt2,
+		},
+		{
+			name:     "Percentile",
+			input:    "percentile(90, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11])",
+			excepted: 10,
+		},
+		{
+			name:     "Interpolated percentile",
+			input:    "percentile(25, 1, 2, 3, 4)",
+			excepted: 1.75,
+		},
+		{
+			name:     "Maximum as percentile",
+			input:    "percentile(100, 3, 1, 2)",
+			excepted: 3,
+		},
+		{
+			name:     "Single element",
+			input:    "mean(7)",
+			excepted: 7,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Calc(tc.input)
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if math.Abs(got-tc.excepted) > 1e-12 {
+				t.Errorf("Calc(%q): got %v, excepted %v", tc.input, got, tc.excepted)
+			}
+		})
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		expectedErr error
+	}{
+		{
+			name:        "Without arguments",
+			expression:  "mean()",
+			expectedErr: ErrArguments,
+		},
+		{
+			name:        "Percentile without elements",
+			expression:  "percentile(50)",
+			expectedErr: ErrArguments,
+		},
+		{
+			name:        "Percentile out of range",
+			expression:  "percentile(101, 1, 2)",
+			expectedErr: ErrDomain,
+		},
+		{
+			name:        "Variance of single element",
+			expression:  "variance(1)",
+			expectedErr: ErrDomain,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Calc(tc.expression)
+			if err != tc.expectedErr {
+				t.Errorf("Calc(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestStatisticsWithUnits(t *testing.T) {
+	cases := []struct {
+		name          string
+		input         string
+		exceptedValue float64
+		exceptedUnit  string
+	}{
+		{
+			name:          "Mean of lengths",
+			input:         "mean(1 m, 300 cm) in m",
+			exceptedValue: 2,
+			exceptedUnit:  "m",
+		},
+		{
+			name:          "Variance of times",
+			input:         "variance([1, 3] s)",
+			exceptedValue: 2,
+			exceptedUnit:  "s^2",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalcQuantity(tc.input)
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if math.Abs(got.Value-tc.exceptedValue) > 1e-12 || got.Unit != tc.exceptedUnit {
+				t.Errorf("CalcQuantity(%q): got %v %s, excepted %v %s", tc.input, got.Value, got.Unit, tc.exceptedValue, tc.exceptedUnit)
+			}
+		})
+	}
+
+	_, err := CalcQuantity("sum(1 m, 1 s)")
+	if _, ok := err.(*DimensionError); !ok {
+		t.Errorf("CalcQuantity: got error %q, excepted DimensionError", err)
+	}
+}
+
+func TestCalcQuantityWithValues(t *testing.T) {
+	values := Values{
+		"sales": Value{Shape: Shape{1, 4}, Elements: []float64{10, 20, 30, 40}},
+		"m":     NewNumber(2),
+	}
+
+	// Value m hides the unit of metre
+	got, err := CalcQuantityWithValues("mean(sales) * m + 1 km / 1 cm", values)
+	if err != nil {
+		t.Fatalf("CalcQuantityWithValues: unexcepted error %q", err)
+	}
+	if got.Value != 100050 || got.Unit != "" {
+		t.Errorf("CalcQuantityWithValues: got %v %s, excepted 100050", got.Value, got.Unit)
+	}
+
+	_, err = CalcQuantityWithValues("1", Values{"2x": NewNumber(1)})
+	if err != ErrInvalidVariable {
+		t.Errorf("CalcQuantityWithValues: got error %q, excepted %q", err, ErrInvalidVariable)
+	}
+}
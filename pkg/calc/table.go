@@ -0,0 +1,134 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// money is the index of money in Dimension
+const money = len(Dimension{}) - 1
+
+// UnitTable is a set of currencies and custom units added to the built-in
+// units of CalcQuantity, e.g. to calculate "100 USD in EUR"
+type UnitTable struct {
+	// Base is the currency in which results with money are measured
+	Base string
+	// Timestamp is the time when the rates were actual
+	Timestamp time.Time
+
+	units   map[string]Unit
+	symbols [len(Dimension{})]string
+}
+
+// NewUnitTable creates the table with the base currency and the rates of other
+// currencies. Rate is the amount of currency for a single unit of base currency.
+// Custom units are given by expressions with built-in units and currencies of
+// the table, e.g. "furlong": "201.168 m" or "BTC": "65000 USD"
+func NewUnitTable(base string, rates map[string]float64, custom map[string]string, timestamp time.Time) (*UnitTable, error) {
+	table := &UnitTable{
+		Base:      base,
+		Timestamp: timestamp,
+		units:     make(map[string]Unit),
+		symbols:   baseUnits,
+	}
+	table.symbols[money] = base
+
+	// Add currencies. The table could have only custom units without base currency
+	var currency Dimension
+	currency[money] = 1
+	if base != "" || len(rates) != 0 {
+		if err := table.add(base, Unit{Factor: 1, Dimension: currency}); err != nil {
+			return nil, err
+		}
+	}
+	for name, rate := range rates {
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("currency %q: %w", name, ErrInvalidRate)
+		}
+		if name == base {
+			continue
+		}
+		if err := table.add(name, Unit{Factor: 1 / rate, Dimension: currency}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add custom units. They are calculated with currencies only, so the result
+	// does not depend on the order of definitions
+	currencies := make(map[string]Unit, len(table.units))
+	for name, unit := range table.units {
+		currencies[name] = unit
+	}
+	lookup := func(name string) (Unit, bool) {
+		if unit, ok := LookupUnit(name); ok {
+			return unit, true
+		}
+		unit, ok := currencies[name]
+		return unit, ok
+	}
+	for name, definition := range custom {
+		value, err := defaultEngine.calcQuantity(definition, Scope{}, lookup, table.symbols, Options{}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unit %q: %w", name, err)
+		}
+		if value.Value <= 0 {
+			return nil, fmt.Errorf("unit %q: %w", name, ErrInvalidRate)
+		}
+		if err := table.add(name, Unit{Factor: value.Value, Dimension: value.Dimension}); err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+// add adds the unit to the table. Built-in units could not be redefined
+func (t *UnitTable) add(name string, unit Unit) error {
+	if !IsIdentifier(name) || name == conversionOperator {
+		return fmt.Errorf("unit %q: %w", name, ErrInvalidUnitName)
+	}
+	if _, ok := LookupUnit(name); ok {
+		return fmt.Errorf("unit %q: %w", name, ErrUnitExists)
+	}
+	if _, ok := t.units[name]; ok {
+		return fmt.Errorf("unit %q: %w", name, ErrUnitExists)
+	}
+
+	t.units[name] = unit
+	return nil
+}
+
+// LookupUnit returns the built-in unit or the unit of the table with the name
+func (t *UnitTable) LookupUnit(name string) (Unit, bool) {
+	if unit, ok := LookupUnit(name); ok {
+		return unit, true
+	}
+	unit, ok := t.units[name]
+	return unit, ok
+}
+
+// CalcQuantity calculates the expression like the package-level CalcQuantity
+// with units of the table. Money is measured in the base currency
+func (t *UnitTable) CalcQuantity(expression string) (Quantity, error) {
+	return defaultEngine.calcQuantity(expression, Scope{}, t.LookupUnit, t.symbols, Options{}, nil)
+}
+
+// CalcQuantityWithValues calculates the expression like the package-level
+// CalcQuantityWithValues with currencies and custom units of the table
+func (t *UnitTable) CalcQuantityWithValues(expression string, values Values) (Quantity, error) {
+	return defaultEngine.calcQuantity(expression, Scope{Values: values}, t.LookupUnit, t.symbols, Options{}, nil)
+}
+
+// CalcQuantityInScope calculates the expression like the package-level
+// CalcQuantityInScope with currencies and custom units of the table
+func (t *UnitTable) CalcQuantityInScope(expression string, scope Scope) (Quantity, error) {
+	return defaultEngine.calcQuantity(expression, scope, t.LookupUnit, t.symbols, Options{}, nil)
+}
+
+// CalcQuantityContext calculates the expression like the package-level
+// CalcQuantityContext with currencies and custom units of the table
+func (t *UnitTable) CalcQuantityContext(ctx context.Context, expression string, scope Scope, options Options) (Quantity, error) {
+	return defaultEngine.calcQuantity(expression, scope, t.LookupUnit, t.symbols, options, newLimiter(ctx, options))
+}
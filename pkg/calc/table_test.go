@@ -0,0 +1,162 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestUnitTable(t *testing.T) {
+	timestamp := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	table, err := NewUnitTable(
+		"USD",
+		map[string]float64{"EUR": 0.5, "RUB": 100},
+		map[string]string{"furlong": "201.168 m", "BTC": "1000 USD", "price": "10 EUR/kg"},
+		timestamp,
+	)
+	if err != nil {
+		t.Fatalf("NewUnitTable: unexcepted error %q", err)
+	}
+	if table.Timestamp != timestamp {
+		t.Errorf("NewUnitTable: got timestamp %s, excepted %s", table.Timestamp, timestamp)
+	}
+
+	cases := []struct {
+		name          string
+		input         string
+		exceptedValue float64
+		exceptedUnit  string
+	}{
+		{
+			name:          "Conversion of currency",
+			input:         "100 USD in EUR",
+			exceptedValue: 50,
+			exceptedUnit:  "EUR",
+		},
+		{
+			name:          "Sum in base currency",
+			input:         "100 USD + 100 EUR",
+			exceptedValue: 300,
+			exceptedUnit:  "USD",
+		},
+		{
+			name:          "Cross rate",
+			input:         "1 EUR in RUB",
+			exceptedValue: 200,
+			exceptedUnit:  "RUB",
+		},
+		{
+			name:          "Custom unit",
+			input:         "1 furlong in m",
+			exceptedValue: 201.168,
+			exceptedUnit:  "m",
+		},
+		{
+			name:          "Custom unit with currency",
+			input:         "0.5 BTC in EUR",
+			exceptedValue: 250,
+			exceptedUnit:  "EUR",
+		},
+		{
+			name:          "Price by weight",
+			input:         "3 kg * price in RUB",
+			exceptedValue: 6000,
+			exceptedUnit:  "RUB",
+		},
+		{
+			name:          "Currency in dimension",
+			input:         "10 USD / 2 h",
+			exceptedValue: 5.0 / 3600,
+			exceptedUnit:  "USD/s",
+		},
+		{
+			name:          "Built-in units",
+			input:         "5 km + 300 m",
+			exceptedValue: 5300,
+			exceptedUnit:  "m",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := table.CalcQuantity(tc.input)
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if math.Abs(got.Value-tc.exceptedValue) > 1e-9 || got.Unit != tc.exceptedUnit {
+				t.Errorf("CalcQuantity(%q): got %f %q, excepted %f %q", tc.input, got.Value, got.Unit, tc.exceptedValue, tc.exceptedUnit)
+			}
+		})
+	}
+
+	// Currencies are not known without the table
+	if _, err := CalcQuantity("100 USD in EUR"); !errors.Is(err, ErrExtraCharacters) {
+		t.Errorf("CalcQuantity without table: got error %q, excepted %q", err, ErrExtraCharacters)
+	}
+
+	// Money could not be added to length
+	var dimensionError *DimensionError
+	if _, err := table.CalcQuantity("100 USD + 1 m"); !errors.As(err, &dimensionError) {
+		t.Errorf("CalcQuantity: got error %q, excepted DimensionError", err)
+	}
+}
+
+func TestNewUnitTableErrors(t *testing.T) {
+	cases := []struct {
+		name        string
+		base        string
+		rates       map[string]float64
+		custom      map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "Negative rate",
+			base:        "USD",
+			rates:       map[string]float64{"EUR": -1},
+			expectedErr: ErrInvalidRate,
+		},
+		{
+			name:        "Redefined built-in unit",
+			base:        "USD",
+			custom:      map[string]string{"km": "1000 m"},
+			expectedErr: ErrUnitExists,
+		},
+		{
+			name:        "Currency and custom unit with the same name",
+			base:        "USD",
+			rates:       map[string]float64{"EUR": 0.5},
+			custom:      map[string]string{"EUR": "2 USD"},
+			expectedErr: ErrUnitExists,
+		},
+		{
+			name:        "Invalid name",
+			base:        "US$",
+			rates:       map[string]float64{"EUR": 0.5},
+			expectedErr: ErrInvalidUnitName,
+		},
+		{
+			name:        "Rates without base",
+			rates:       map[string]float64{"EUR": 0.5},
+			expectedErr: ErrInvalidUnitName,
+		},
+		{
+			name:        "Invalid definition",
+			custom:      map[string]string{"furlong": "201.168 parsec"},
+			expectedErr: ErrExtraCharacters,
+		},
+		{
+			name:        "Conversion operator",
+			custom:      map[string]string{"in": "2.54 cm"},
+			expectedErr: ErrInvalidUnitName,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewUnitTable(tc.base, tc.rates, tc.custom, time.Time{})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("NewUnitTable: got error %q, expected error %q", err, tc.expectedErr)
+			}
+		})
+	}
+}
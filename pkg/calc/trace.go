@@ -0,0 +1,83 @@
+package calc
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of tracer of calculation stages
+const TracerName = "github.com/Irurnnen/ordinary-calc/pkg/calc"
+
+// startSpan starts the span of calculation stage in the context of limiter
+// with the global tracer provider, so spans are not recorded until the
+// provider is set by the application. Calculations without context are not
+// traced, the returned span does nothing
+func (l *limiter) startSpan(name string, attributes ...attribute.KeyValue) trace.Span {
+	if l == nil {
+		return trace.SpanFromContext(context.Background())
+	}
+	_, span := otel.Tracer(TracerName).Start(l.ctx, name, trace.WithAttributes(attributes...))
+	return span
+}
+
+// endSpan ends the span of calculation stage with the error of stage
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tokensAttribute returns the attribute with the number of tokens
+func tokensAttribute(tokens []string) attribute.KeyValue {
+	return attribute.Int("calc.tokens", len(tokens))
+}
+
+// validate validates the expression in the span of ValidateExpression stage
+func (l *limiter) validate(expression string, validate func(expression string) error) error {
+	span := l.startSpan("calc.ValidateExpression", attribute.Int("calc.expression_length", len(expression)))
+	err := validate(expression)
+	endSpan(span, err)
+	return err
+}
+
+// parse tokenizes the expression in the span of ParseExpression stage
+func (l *limiter) parse(expression string) []string {
+	span := l.startSpan("calc.ParseExpression")
+	tokens := ParseExpression(expression)
+	span.SetAttributes(tokensAttribute(tokens))
+	span.End()
+	return tokens
+}
+
+// postfix changes tokens to postfix in the span of ToPostfix stage
+func (l *limiter) postfix(tokens []string, toPostfix func(tokens []string) []string) []string {
+	span := l.startSpan("calc.ToPostfix", tokensAttribute(tokens))
+	tokens = toPostfix(tokens)
+	span.End()
+	return tokens
+}
+
+// traceEval evaluates postfix tokens in the span of EvalExpression stage. The
+// number of evaluation steps is added to the span
+func traceEval[T any](l *limiter, tokens []string, evaluate func() (T, error)) (T, error) {
+	span := l.startSpan("calc.EvalExpression", tokensAttribute(tokens))
+	steps := l.stepsCount()
+	result, err := evaluate()
+	span.SetAttributes(attribute.Int("calc.steps", l.stepsCount()-steps))
+	endSpan(span, err)
+	return result, err
+}
+
+// stepsCount returns the number of counted steps
+func (l *limiter) stepsCount() int {
+	if l == nil {
+		return 0
+	}
+	return l.steps
+}
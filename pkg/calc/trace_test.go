@@ -0,0 +1,153 @@
+package calc
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans sets the global tracer provider recording spans until the end of test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+// spanAttribute returns the value of attribute of span
+func spanAttribute(span tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for _, a := range span.Attributes {
+		if string(a.Key) == key {
+			return a.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestCalcContextSpans(t *testing.T) {
+	cases := []struct {
+		name          string
+		calculate     func(ctx context.Context) error
+		exceptedSpans []string
+		exceptedError string
+	}{
+		{
+			name: "Real numbers",
+			calculate: func(ctx context.Context) error {
+				_, err := CalcContext(ctx, "1 + 2 * 3", Options{})
+				return err
+			},
+			exceptedSpans: []string{"calc.ValidateExpression", "calc.ParseExpression", "calc.ToPostfix", "calc.EvalExpression"},
+		},
+		{
+			name: "Complex numbers",
+			calculate: func(ctx context.Context) error {
+				_, err := CalcComplexContext(ctx, "(1 + 2i) * 3", Options{})
+				return err
+			},
+			exceptedSpans: []string{"calc.ValidateExpression", "calc.ParseExpression", "calc.ToPostfix", "calc.EvalExpression"},
+		},
+		{
+			name: "Quantity with conversion",
+			calculate: func(ctx context.Context) error {
+				_, err := CalcQuantityContext(ctx, "2 km in m", Scope{}, Options{})
+				return err
+			},
+			exceptedSpans: []string{"calc.ValidateExpression", "calc.ParseExpression", "calc.ToPostfix", "calc.EvalExpression", "calc.ToPostfix", "calc.EvalExpression"},
+		},
+		{
+			name: "Invalid expression",
+			calculate: func(ctx context.Context) error {
+				_, err := CalcContext(ctx, "(1 + 2", Options{})
+				return err
+			},
+			exceptedSpans: []string{"calc.ValidateExpression"},
+			exceptedError: "calc.ValidateExpression",
+		},
+		{
+			name: "Evaluation error",
+			calculate: func(ctx context.Context) error {
+				_, err := CalcContext(ctx, "1 / 0", Options{})
+				return err
+			},
+			exceptedSpans: []string{"calc.ValidateExpression", "calc.ParseExpression", "calc.ToPostfix", "calc.EvalExpression"},
+			exceptedError: "calc.EvalExpression",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := recordSpans(t)
+			ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+			err := tt.calculate(ctx)
+			parent.End()
+			if (err != nil) != (tt.exceptedError != "") {
+				t.Fatalf("unexcepted error %v", err)
+			}
+
+			// The last span is the parent
+			spans := exporter.GetSpans()
+			var names []string
+			for _, span := range spans[:len(spans)-1] {
+				names = append(names, span.Name)
+				if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+					t.Errorf("span %s is not a child of the parent span", span.Name)
+				}
+				if failed := span.Status.Code == codes.Error; failed != (span.Name == tt.exceptedError) {
+					t.Errorf("span %s has unexcepted status %v", span.Name, span.Status)
+				}
+			}
+			if !slices.Equal(names, tt.exceptedSpans) {
+				t.Errorf("excepted spans %v, got %v", tt.exceptedSpans, names)
+			}
+		})
+	}
+}
+
+func TestCalcContextSpanAttributes(t *testing.T) {
+	exporter := recordSpans(t)
+	if _, err := CalcContext(context.Background(), "(1 + 2) * 3", Options{}); err != nil {
+		t.Fatalf("CalcContext: unexcepted error %q", err)
+	}
+
+	cases := []struct {
+		span     string
+		key      string
+		excepted int64
+	}{
+		{"calc.ValidateExpression", "calc.expression_length", 11},
+		{"calc.ParseExpression", "calc.tokens", 7},
+		{"calc.ToPostfix", "calc.tokens", 7},
+		{"calc.EvalExpression", "calc.tokens", 5},
+		{"calc.EvalExpression", "calc.steps", 5},
+	}
+	for _, tt := range cases {
+		i := slices.IndexFunc(exporter.GetSpans(), func(span tracetest.SpanStub) bool { return span.Name == tt.span })
+		if i < 0 {
+			t.Fatalf("span %s is not found", tt.span)
+		}
+		value, ok := spanAttribute(exporter.GetSpans()[i], tt.key)
+		if !ok || value.AsInt64() != tt.excepted {
+			t.Errorf("span %s: excepted %s=%d, got %v", tt.span, tt.key, tt.excepted, value.Emit())
+		}
+	}
+}
+
+func TestCalcWithoutContextIsNotTraced(t *testing.T) {
+	exporter := recordSpans(t)
+	if _, err := Calc("1 + 2"); err != nil {
+		t.Fatalf("Calc: unexcepted error %q", err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("excepted no spans, got %d", len(spans))
+	}
+}
@@ -0,0 +1,382 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// conversionOperator separates an expression and the unit its result is converted to
+const conversionOperator = "in"
+
+// maxUnitPower limits the power of a quantity with dimension
+const maxUnitPower = 100
+
+// Dimension is a vector of powers of SI base units: metre, kilogram, second,
+// ampere, kelvin, mole and candela, followed by the power of money
+type Dimension [8]int
+
+// baseUnits are the symbols of base units in order of Dimension. Money has
+// no base unit until a UnitTable sets its base currency
+var baseUnits = [len(Dimension{})]string{"m", "kg", "s", "A", "K", "mol", "cd", "¤"}
+
+// IsDimensionless returns the true if all powers of base units are zero otherwise false
+func (d Dimension) IsDimensionless() bool {
+	return d == Dimension{}
+}
+
+// String returns the dimension as a product of base units, e.g. "m^2*kg/s^2".
+// Dimensionless quantity has empty string
+func (d Dimension) String() string {
+	return d.format(baseUnits)
+}
+
+// format returns the dimension as a product of base units with the symbols
+func (d Dimension) format(symbols [len(Dimension{})]string) string {
+	var numerator, denominator []string
+	for i, power := range d {
+		switch {
+		case power == 1:
+			numerator = append(numerator, symbols[i])
+		case power > 1:
+			numerator = append(numerator, symbols[i]+"^"+strconv.Itoa(power))
+		case power == -1:
+			denominator = append(denominator, symbols[i])
+		case power < -1:
+			denominator = append(denominator, symbols[i]+"^"+strconv.Itoa(-power))
+		}
+	}
+
+	if len(numerator) == 0 && len(denominator) == 0 {
+		return ""
+	}
+	result := strings.Join(numerator, "*")
+	if result == "" {
+		result = "1"
+	}
+	for _, unit := range denominator {
+		result += "/" + unit
+	}
+	return result
+}
+
+// Unit returns the dimension as a product of base units, or "1" for dimensionless quantity
+func (d Dimension) Unit() string {
+	if d.IsDimensionless() {
+		return "1"
+	}
+	return d.String()
+}
+
+func (d Dimension) add(other Dimension) Dimension {
+	for i := range d {
+		d[i] += other[i]
+	}
+	return d
+}
+
+func (d Dimension) sub(other Dimension) Dimension {
+	for i := range d {
+		d[i] -= other[i]
+	}
+	return d
+}
+
+func (d Dimension) mul(power int) Dimension {
+	for i := range d {
+		d[i] *= power
+	}
+	return d
+}
+
+// Unit is a unit of measurement given by its size in SI base units
+type Unit struct {
+	Factor    float64
+	Dimension Dimension
+}
+
+var units = map[string]Unit{
+	// SI base units. Kilogram is made of gram and prefix
+	"m":   {1, Dimension{1, 0, 0, 0, 0, 0, 0}},
+	"g":   {1e-3, Dimension{0, 1, 0, 0, 0, 0, 0}},
+	"s":   {1, Dimension{0, 0, 1, 0, 0, 0, 0}},
+	"A":   {1, Dimension{0, 0, 0, 1, 0, 0, 0}},
+	"K":   {1, Dimension{0, 0, 0, 0, 1, 0, 0}},
+	"mol": {1, Dimension{0, 0, 0, 0, 0, 1, 0}},
+	"cd":  {1, Dimension{0, 0, 0, 0, 0, 0, 1}},
+
+	// SI derived units
+	"Hz":  {1, Dimension{0, 0, -1, 0, 0, 0, 0}},
+	"N":   {1, Dimension{1, 1, -2, 0, 0, 0, 0}},
+	"Pa":  {1, Dimension{-1, 1, -2, 0, 0, 0, 0}},
+	"J":   {1, Dimension{2, 1, -2, 0, 0, 0, 0}},
+	"W":   {1, Dimension{2, 1, -3, 0, 0, 0, 0}},
+	"C":   {1, Dimension{0, 0, 1, 1, 0, 0, 0}},
+	"V":   {1, Dimension{2, 1, -3, -1, 0, 0, 0}},
+	"Ohm": {1, Dimension{2, 1, -3, -2, 0, 0, 0}},
+	"L":   {1e-3, Dimension{3, 0, 0, 0, 0, 0, 0}},
+
+	// Common units outside SI
+	"min": {60, Dimension{0, 0, 1, 0, 0, 0, 0}},
+	"h":   {3600, Dimension{0, 0, 1, 0, 0, 0, 0}},
+	"d":   {86400, Dimension{0, 0, 1, 0, 0, 0, 0}},
+	"t":   {1000, Dimension{0, 1, 0, 0, 0, 0, 0}},
+	"ft":  {0.3048, Dimension{1, 0, 0, 0, 0, 0, 0}},
+	"mi":  {1609.344, Dimension{1, 0, 0, 0, 0, 0, 0}},
+	"lb":  {0.45359237, Dimension{0, 1, 0, 0, 0, 0, 0}},
+}
+
+// prefixedUnits are the units which could be written with SI prefixes
+var prefixedUnits = []string{"m", "g", "s", "A", "K", "mol", "cd", "Hz", "N", "Pa", "J", "W", "C", "V", "Ohm", "L"}
+
+var prefixes = map[string]float64{
+	"T": 1e12,
+	"G": 1e9,
+	"M": 1e6,
+	"k": 1e3,
+	"h": 1e2,
+	"c": 1e-2,
+	"m": 1e-3,
+	"u": 1e-6,
+	"n": 1e-9,
+	"p": 1e-12,
+}
+
+// LookupUnit returns the unit with the name. Names of SI units could start with
+// a prefix, e.g. "km" or "mA"
+func LookupUnit(name string) (Unit, bool) {
+	if unit, ok := units[name]; ok {
+		return unit, true
+	}
+
+	for _, base := range prefixedUnits {
+		prefix, found := strings.CutSuffix(name, base)
+		if !found {
+			continue
+		}
+		if factor, ok := prefixes[prefix]; ok {
+			unit := units[base]
+			unit.Factor *= factor
+			return unit, true
+		}
+	}
+	return Unit{}, false
+}
+
+// unitLookup finds the unit by its name
+type unitLookup func(name string) (Unit, bool)
+
+// Quantity is a result of calculation with units. Value is measured in Unit,
+// empty Unit means a dimensionless number. Matrix has the rows of result
+// measured in Unit when it is a matrix, then Value is zero
+type Quantity struct {
+	Value     float64
+	Unit      string
+	Dimension Dimension
+	Matrix    [][]float64
+}
+
+// newQuantity returns the quantity with the value measured in the unit
+func newQuantity(value Value, unit string) Quantity {
+	if !value.IsNumber() {
+		return Quantity{Unit: unit, Dimension: value.Dimension, Matrix: value.Matrix()}
+	}
+	return Quantity{Value: value.Number(), Unit: unit, Dimension: value.Dimension}
+}
+
+// DimensionError is returned when an operation gets quantities with dimensions
+// it could not work with, e.g. "5 m + 2 s"
+type DimensionError struct {
+	Operation string
+	Left      Dimension
+	Right     Dimension
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("expression has incompatible units in %q: %s and %s", e.Operation, e.Left.Unit(), e.Right.Unit())
+}
+
+// CalcQuantity calculates the expression with units of measurement. The result
+// is measured in SI base units unless the expression ends with a conversion,
+// e.g. "3 h * 60 km/h in km"
+func CalcQuantity(expression string) (Quantity, error) {
+	return defaultEngine.CalcQuantity(expression)
+}
+
+// CalcQuantityWithValues calculates the expression like CalcQuantity, where
+// names of values are replaced by them. Values hide units with the same names
+func CalcQuantityWithValues(expression string, values Values) (Quantity, error) {
+	return defaultEngine.CalcQuantityWithValues(expression, values)
+}
+
+// CalcQuantityInScope calculates the expression like CalcQuantityWithValues
+// where user-defined functions of the scope could be called
+func CalcQuantityInScope(expression string, scope Scope) (Quantity, error) {
+	return defaultEngine.CalcQuantityInScope(expression, scope)
+}
+
+// CalcQuantityContext calculates the expression like CalcQuantityInScope
+// within limits of options. Calculation stops with the error of context when
+// it is done
+func CalcQuantityContext(ctx context.Context, expression string, scope Scope, options Options) (Quantity, error) {
+	return defaultEngine.CalcQuantityContext(ctx, expression, scope, options)
+}
+
+// CalcQuantity calculates the expression like the package-level CalcQuantity
+// with operators, functions and constants of the engine
+func (e *Engine) CalcQuantity(expression string) (Quantity, error) {
+	return e.calcQuantity(expression, Scope{}, LookupUnit, baseUnits, Options{}, nil)
+}
+
+// CalcQuantityWithValues calculates the expression like the package-level
+// CalcQuantityWithValues with operators, functions and constants of the engine
+func (e *Engine) CalcQuantityWithValues(expression string, values Values) (Quantity, error) {
+	return e.calcQuantity(expression, Scope{Values: values}, LookupUnit, baseUnits, Options{}, nil)
+}
+
+// CalcQuantityInScope calculates the expression like the package-level
+// CalcQuantityInScope with operators, functions and constants of the engine
+func (e *Engine) CalcQuantityInScope(expression string, scope Scope) (Quantity, error) {
+	return e.calcQuantity(expression, scope, LookupUnit, baseUnits, Options{}, nil)
+}
+
+// CalcQuantityContext calculates the expression like the package-level
+// CalcQuantityContext with operators, functions and constants of the engine
+func (e *Engine) CalcQuantityContext(ctx context.Context, expression string, scope Scope, options Options) (Quantity, error) {
+	return e.calcQuantity(expression, scope, LookupUnit, baseUnits, options, newLimiter(ctx, options))
+}
+
+// calcQuantity calculates the expression with values and functions of the
+// scope and units found by lookup. Symbols are used to write the unit of result.
+// Expression must be within limits of options, evaluation steps are counted by limit
+func (e *Engine) calcQuantity(expression string, scope Scope, lookup unitLookup, symbols [len(Dimension{})]string, options Options, limit *limiter) (Quantity, error) {
+	if err := options.checkLength(expression); err != nil {
+		return Quantity{}, err
+	}
+	values := scope.Values
+	if err := values.validate(); err != nil {
+		return Quantity{}, err
+	}
+	if _, ok := values[conversionOperator]; ok {
+		return Quantity{}, ErrInvalidVariable
+	}
+
+	// Values hide constants and units with the same names
+	unitValue := func(name string) (Value, bool) {
+		unit, ok := lookup(name)
+		return scalar(unit.Factor, unit.Dimension), ok
+	}
+	valueOrUnit := func(name string) (Value, bool) {
+		if value, ok := e.withConstants(values.lookup)(name); ok {
+			return value, true
+		}
+		return unitValue(name)
+	}
+
+	// Checking validity of expression
+	isName := func(name string) bool {
+		_, ok := valueOrUnit(name)
+		_, isFunction := scope.Functions[name]
+		return ok || isFunction || name == conversionOperator
+	}
+	err := limit.validate(expression, func(expression string) error {
+		return e.validateExpression(expression, e.withNames(isName))
+	})
+	if err != nil {
+		return Quantity{}, err
+	}
+
+	// Tokenize expression and separate the conversion
+	tokens := limit.parse(expression)
+	if err := options.checkTokens(tokens); err != nil {
+		return Quantity{}, err
+	}
+	tokens, target, err := splitConversion(tokens)
+	if err != nil {
+		return Quantity{}, err
+	}
+
+	result, err := e.evalQuantityTokens(tokens, valueOrUnit, scope.Functions, limit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	if target == nil {
+		return newQuantity(result, result.Dimension.format(symbols)), nil
+	}
+
+	// Convert the result to the target unit
+	unit, err := e.evalQuantityTokens(target, unitValue, nil, limit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	if unit.Dimension != result.Dimension {
+		return Quantity{}, &DimensionError{Operation: conversionOperator, Left: result.Dimension, Right: unit.Dimension}
+	}
+	converted, err := evalOperator("/", result, unit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	converted.Dimension = result.Dimension
+
+	return newQuantity(converted, strings.Join(target, "")), nil
+}
+
+// splitConversion separates tokens of expression and tokens of the target unit.
+// The target is nil when there is no conversion
+func splitConversion(tokens []string) ([]string, []string, error) {
+	var depth int
+	for i, token := range tokens {
+		switch token {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case conversionOperator:
+			if depth != 0 || i == 0 || i == len(tokens)-1 {
+				return nil, nil, ErrInvalidConversion
+			}
+			for _, v := range tokens[i+1:] {
+				if v == conversionOperator {
+					return nil, nil, ErrInvalidConversion
+				}
+			}
+			return tokens[:i], tokens[i+1:], nil
+		}
+	}
+	return tokens, nil, nil
+}
+
+// evalQuantityTokens validates tokens and calculates them with values and
+// units found by lookup and with user-defined functions
+func (e *Engine) evalQuantityTokens(tokens []string, lookup valueLookup, functions Functions, limit *limiter) (Value, error) {
+	tokens = InsertImplicitMultiplication(tokens)
+
+	// Validate Tokens
+	if err := e.validateTokens(tokens); err != nil {
+		return Value{}, err
+	}
+
+	tokens = limit.postfix(tokens, e.toPostfix)
+	return traceEval(limit, tokens, func() (Value, error) {
+		return e.evalValue(tokens, lookup, functions, limit)
+	})
+}
+
+// EvalQuantity solves tokens in Reverse Polish notation where names are units
+// of measurement. The result is measured in SI base units
+func EvalQuantity(tokens []string) (Quantity, error) {
+	result, err := evalQuantity(tokens, LookupUnit)
+	if err != nil {
+		return Quantity{}, err
+	}
+
+	return newQuantity(result, result.Dimension.String()), nil
+}
+
+func evalQuantity(tokens []string, lookup unitLookup) (Value, error) {
+	return defaultEngine.evalValue(tokens, func(name string) (Value, bool) {
+		unit, ok := lookup(name)
+		return scalar(unit.Factor, unit.Dimension), ok
+	}, nil, nil)
+}
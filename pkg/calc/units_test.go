@@ -0,0 +1,250 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCalcQuantity(t *testing.T) {
+	cases := []struct {
+		name          string
+		input         string
+		exceptedValue float64
+		exceptedUnit  string
+	}{
+		{
+			name:          "Without units",
+			input:         "2 + 2 * 2",
+			exceptedValue: 6,
+			exceptedUnit:  "",
+		},
+		{
+			name:          "Sum of lengths",
+			input:         "5 km + 300 m",
+			exceptedValue: 5300,
+			exceptedUnit:  "m",
+		},
+		{
+			name:          "Distance by time and speed",
+			input:         "3 h * 60 km/h",
+			exceptedValue: 180000,
+			exceptedUnit:  "m",
+		},
+		{
+			name:          "Conversion",
+			input:         "3 h * 60 km/h in km",
+			exceptedValue: 180,
+			exceptedUnit:  "km",
+		},
+		{
+			name:          "Implicit multiplication before division",
+			input:         "100 km / 2 h in km/h",
+			exceptedValue: 50,
+			exceptedUnit:  "km/h",
+		},
+		{
+			name:          "Derived unit",
+			input:         "2 kg * 9.81 m/s^2 in N",
+			exceptedValue: 19.62,
+			exceptedUnit:  "N",
+		},
+		{
+			name:          "Base units of derived unit",
+			input:         "1 kJ",
+			exceptedValue: 1000,
+			exceptedUnit:  "m^2*kg/s^2",
+		},
+		{
+			name:          "Power of unit",
+			input:         "(2 m)^2",
+			exceptedValue: 4,
+			exceptedUnit:  "m^2",
+		},
+		{
+			name:          "Inverse unit",
+			input:         "5 / (2 s)",
+			exceptedValue: 2.5,
+			exceptedUnit:  "1/s",
+		},
+		{
+			name:          "Units outside SI",
+			input:         "1 mi in ft",
+			exceptedValue: 5280,
+			exceptedUnit:  "ft",
+		},
+		{
+			name:          "Dimensionless ratio",
+			input:         "1 km / 1 m",
+			exceptedValue: 1000,
+			exceptedUnit:  "",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalcQuantity(tc.input)
+			if err != nil {
+				t.Fatalf("successful case %s return error %q", tc.name, err)
+			}
+
+			if math.Abs(got.Value-tc.exceptedValue) > 1e-9 || got.Unit != tc.exceptedUnit {
+				t.Errorf("CalcQuantity(%q): got %f %q, excepted %f %q", tc.input, got.Value, got.Unit, tc.exceptedValue, tc.exceptedUnit)
+			}
+		})
+	}
+
+	casesFail := []struct {
+		name        string
+		expression  string
+		expectedErr error
+	}{
+		{
+			name:        "Unknown unit",
+			expression:  "5 parsec",
+			expectedErr: ErrExtraCharacters,
+		},
+		{
+			name:        "Conversion in brackets",
+			expression:  "(5 km in m) + 1 m",
+			expectedErr: ErrInvalidConversion,
+		},
+		{
+			name:        "Conversion without unit",
+			expression:  "5 km in",
+			expectedErr: ErrInvalidConversion,
+		},
+		{
+			name:        "Fractional power of unit",
+			expression:  "4 m ^ 0.5",
+			expectedErr: ErrDomain,
+		},
+		{
+			name:        "Too large power of unit",
+			expression:  "((((m^100)^100)^100)^100)^100",
+			expectedErr: ErrOverflow,
+		},
+		{
+			name:        "Too large power of product",
+			expression:  "m^100 * m",
+			expectedErr: ErrOverflow,
+		},
+		{
+			name:        "Too large negative power of quotient",
+			expression:  "1 / m^100 / m",
+			expectedErr: ErrOverflow,
+		},
+		{
+			name:        "Division by zero",
+			expression:  "5 m / (0 s)",
+			expectedErr: ErrZeroByDivision,
+		},
+	}
+	for _, tc := range casesFail {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CalcQuantity(tc.expression)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("CalcQuantity(%q): got error %q, expected error %q", tc.expression, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCalcQuantityDimensionError(t *testing.T) {
+	cases := []struct {
+		name       string
+		expression string
+		excepted   DimensionError
+	}{
+		{
+			name:       "Sum of length and time",
+			expression: "5 m + 2 s",
+			excepted:   DimensionError{Operation: "+", Left: Dimension{1}, Right: Dimension{0, 0, 1}},
+		},
+		{
+			name:       "Power with unit",
+			expression: "2 ^ (3 m)",
+			excepted:   DimensionError{Operation: "^", Left: Dimension{}, Right: Dimension{1}},
+		},
+		{
+			name:       "Conversion to other dimension",
+			expression: "5 km in h",
+			excepted:   DimensionError{Operation: "in", Left: Dimension{1}, Right: Dimension{0, 0, 1}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CalcQuantity(tc.expression)
+
+			var dimensionError *DimensionError
+			if !errors.As(err, &dimensionError) {
+				t.Fatalf("CalcQuantity(%q): got error %q, excepted DimensionError", tc.expression, err)
+			}
+			if *dimensionError != tc.excepted {
+				t.Errorf("CalcQuantity(%q): got %+v, excepted %+v", tc.expression, *dimensionError, tc.excepted)
+			}
+		})
+	}
+}
+
+func TestCalcQuantityMatrix(t *testing.T) {
+	got, err := CalcQuantity("[1 m, 2 m] + [50 cm, 1 km] in cm")
+	if err != nil {
+		t.Fatalf("CalcQuantity: unexcepted error %q", err)
+	}
+
+	excepted := Quantity{Unit: "cm", Dimension: Dimension{1}, Matrix: [][]float64{{150, 100200}}}
+	if !reflect.DeepEqual(got, excepted) {
+		t.Errorf("CalcQuantity: got %+v, excepted %+v", got, excepted)
+	}
+
+	_, err = CalcQuantity("[1 m, 2 s]")
+	var dimensionError *DimensionError
+	if !errors.As(err, &dimensionError) {
+		t.Errorf("CalcQuantity: got error %q, excepted DimensionError", err)
+	}
+}
+
+func TestLookupUnit(t *testing.T) {
+	cases := []struct {
+		name     string
+		excepted Unit
+		found    bool
+	}{
+		{"m", Unit{1, Dimension{1}}, true},
+		{"km", Unit{1000, Dimension{1}}, true},
+		{"mm", Unit{1e-3, Dimension{1}}, true},
+		{"kg", Unit{1, Dimension{0, 1}}, true},
+		{"min", Unit{60, Dimension{0, 0, 1}}, true},
+		{"hPa", Unit{100, Dimension{-1, 1, -2}}, true},
+		{"kh", Unit{}, false},
+		{"x", Unit{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found := LookupUnit(tc.name)
+			if found != tc.found || math.Abs(got.Factor-tc.excepted.Factor) > 1e-12 || got.Dimension != tc.excepted.Dimension {
+				t.Errorf("LookupUnit(%q): got %v %t, excepted %v %t", tc.name, got, found, tc.excepted, tc.found)
+			}
+		})
+	}
+}
+
+func TestDimensionString(t *testing.T) {
+	cases := []struct {
+		dimension Dimension
+		excepted  string
+	}{
+		{Dimension{}, ""},
+		{Dimension{1}, "m"},
+		{Dimension{1, 0, -1}, "m/s"},
+		{Dimension{2, 1, -2}, "m^2*kg/s^2"},
+		{Dimension{0, 0, -1}, "1/s"},
+		{Dimension{-1, 1, -2}, "kg/m/s^2"},
+	}
+	for _, tc := range cases {
+		if got := tc.dimension.String(); got != tc.excepted {
+			t.Errorf("Dimension(%v).String(): got %q, excepted %q", tc.dimension, got, tc.excepted)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Shape is the number of rows and columns of a matrix. Numbers have zero shape
+type Shape struct {
+	Rows int
+	Cols int
+}
+
+// IsNumber returns the true if the shape is the shape of a number otherwise false
+func (s Shape) IsNumber() bool {
+	return s == Shape{}
+}
+
+// String returns the shape as "rows x columns", e.g. "2x3", or "number"
+func (s Shape) String() string {
+	if s.IsNumber() {
+		return "number"
+	}
+	return strconv.Itoa(s.Rows) + "x" + strconv.Itoa(s.Cols)
+}
+
+// ShapeError is returned when an operation gets values with shapes it could
+// not work with, e.g. "[1,2] + [1,2,3]" or "det([1,2])"
+type ShapeError struct {
+	Operation string
+	Shapes    []Shape
+}
+
+func (e *ShapeError) Error() string {
+	return fmt.Sprintf("expression has incompatible shapes in %q: %s", e.Operation, e.ShapesString())
+}
+
+// ShapesString returns the shapes of operands separated by "and", e.g. "1x2 and 1x3"
+func (e *ShapeError) ShapesString() string {
+	shapes := make([]string, len(e.Shapes))
+	for i, shape := range e.Shapes {
+		shapes[i] = shape.String()
+	}
+	return strings.Join(shapes, " and ")
+}
+
+// Value is a number or a matrix of numbers measured in the same units
+type Value struct {
+	Shape Shape
+	// Elements has a single number or the elements of matrix row by row
+	Elements  []float64
+	Dimension Dimension
+}
+
+// NewNumber returns the dimensionless number
+func NewNumber(x float64) Value {
+	return scalar(x, Dimension{})
+}
+
+// scalar returns the number with the dimension
+func scalar(x float64, dimension Dimension) Value {
+	return Value{Elements: []float64{x}, Dimension: dimension}
+}
+
+// IsNumber returns the true if the value is a number otherwise false
+func (v Value) IsNumber() bool {
+	return v.Shape.IsNumber()
+}
+
+// Number returns the number, or the first element of matrix
+func (v Value) Number() float64 {
+	return v.Elements[0]
+}
+
+// Matrix returns the rows of matrix, or nil for a number
+func (v Value) Matrix() [][]float64 {
+	if v.IsNumber() {
+		return nil
+	}
+	rows := make([][]float64, v.Shape.Rows)
+	for i := range rows {
+		rows[i] = v.Elements[i*v.Shape.Cols : (i+1)*v.Shape.Cols]
+	}
+	return rows
+}
+
+// at returns the element of matrix in row i and column j
+func (v Value) at(i, j int) float64 {
+	return v.Elements[i*v.Shape.Cols+j]
+}
+
+// check returns an error if the value has not finite elements or the power
+// of its base unit is larger than maxUnitPower
+func (v Value) check() error {
+	for _, power := range v.Dimension {
+		if power > maxUnitPower || power < -maxUnitPower {
+			return ErrOverflow
+		}
+	}
+	for _, x := range v.Elements {
+		if math.IsNaN(x) {
+			return ErrDomain
+		}
+		if math.IsInf(x, 0) {
+			return ErrOverflow
+		}
+	}
+	return nil
+}
+
+// builtinFunction is a function available in real expressions. Variadic
+// function takes at least the number of arguments
+type builtinFunction struct {
+	arguments int
+	variadic  bool
+	call      func(arguments []Value) (Value, error)
+}
+
+// builtinFunctions are the functions available in real expressions. Numbers
+// are treated as matrices of a single element, statistics functions take
+// all elements of matrices as their arguments
+var builtinFunctions = map[string]builtinFunction{
+	// Matrix functions
+	"transpose": {1, false, transpose},
+	"det":       {1, false, determinant},
+	"inv":       {1, false, inverse},
+	"dot":       {2, false, dot},
+
+	// Statistics functions
+	"sum":        {1, true, sumOf},
+	"prod":       {1, true, productOf},
+	"mean":       {1, true, mean},
+	"median":     {1, true, median},
+	"variance":   {1, true, variance},
+	"stdev":      {1, true, stdev},
+	"percentile": {2, true, percentile},
+}
+
+// Values binds names used in an expression to numbers or matrices
+type Values map[string]Value
+
+// isName returns the true if there is a value with the name
+func (v Values) isName(name string) bool {
+	_, ok := v[name]
+	return ok
+}
+
+// lookup returns the value with the name
+func (v Values) lookup(name string) (Value, bool) {
+	value, ok := v[name]
+	return value, ok
+}
+
+// validate checks that names of values are valid
+func (v Values) validate() error {
+	for name := range v {
+		if !IsIdentifier(name) {
+			return ErrInvalidVariable
+		}
+	}
+	return nil
+}
+
+// valueLookup finds the value of name used in an expression
+type valueLookup func(name string) (Value, bool)
+
+// parseNumber parses the number token. Numbers which do not fit into float64
+// are ErrOverflow, malformed numbers like "1.2.3" are ErrParseFloat
+func parseNumber(token string) (float64, error) {
+	num, err := strconv.ParseFloat(token, 64)
+	if errors.Is(err, strconv.ErrRange) && math.IsInf(num, 0) {
+		return 0, ErrOverflow
+	}
+	if err != nil {
+		return 0, ErrParseFloat
+	}
+	return num, nil
+}
+
+// evalValue solves tokens in Reverse Polish notation where names are replaced
+// by values found by lookup. Functions are registered in the engine or user-defined.
+// Every token is a step counted by limit
+func (e *Engine) evalValue(tokens []string, lookup valueLookup, functions Functions, limit *limiter) (Value, error) {
+	var stack []Value
+	for _, token := range tokens {
+		if err := limit.step(); err != nil {
+			return Value{}, err
+		}
+
+		// If token is number
+		if IsNumber(token) {
+			num, err := parseNumber(token)
+			if err != nil {
+				return Value{}, err
+			}
+			stack = append(stack, scalar(num, Dimension{}))
+			continue
+		}
+		// If token is name
+		if IsIdentifier(token) {
+			value, ok := lookup(token)
+			if !ok {
+				return Value{}, ErrExtraCharacters
+			}
+			stack = append(stack, value)
+			continue
+		}
+		// If token is matrix literal
+		if rows, cols, ok := ParseMatrixLiteral(token); ok {
+			if len(stack) < rows*cols {
+				return Value{}, ErrExtraOperands
+			}
+			matrix, err := matrixLiteral(rows, cols, stack[len(stack)-rows*cols:])
+			if err != nil {
+				return Value{}, err
+			}
+			stack = append(stack[:len(stack)-rows*cols], matrix)
+			continue
+		}
+		// If token is function
+		if name, arguments, ok := ParseFunctionCall(token); ok {
+			if len(stack) < arguments {
+				return Value{}, ErrExtraOperands
+			}
+
+			result, err := e.callFunction(name, stack[len(stack)-arguments:], functions, limit)
+			if err != nil {
+				return Value{}, err
+			}
+			if err := result.check(); err != nil {
+				return Value{}, err
+			}
+			stack = append(stack[:len(stack)-arguments], result)
+			continue
+		}
+		// If token is operand
+		op, arity := e.binary[token], 2
+		if unary, ok := e.unary[token]; ok {
+			op, arity = unary, 1
+		} else if op.apply == nil {
+			return Value{}, ErrExtraCharacters
+		}
+		if len(stack) < arity {
+			return Value{}, ErrExtraOperands
+		}
+
+		result, err := op.apply(stack[len(stack)-arity:])
+		if err != nil {
+			return Value{}, err
+		}
+		stack = stack[:len(stack)-arity]
+
+		// Check that result is a finite real number
+		if err := result.check(); err != nil {
+			return Value{}, err
+		}
+
+		stack = append(stack, result)
+	}
+
+	// Check size of stack
+	if len(stack) == 0 {
+		return scalar(0, Dimension{}), nil
+	}
+
+	return stack[0], nil
+}
+
+// callFunction calculates the function of the engine or the user-defined function with the arguments
+func (e *Engine) callFunction(name string, arguments []Value, functions Functions, limit *limiter) (Value, error) {
+	if function, ok := e.functions[name]; ok {
+		if len(arguments) != function.arguments && (!function.variadic || len(arguments) < function.arguments) {
+			return Value{}, ErrArguments
+		}
+		return function.call(arguments)
+	}
+
+	function, ok := functions[name]
+	if !ok {
+		return Value{}, ErrExtraCharacters
+	}
+	if len(arguments) != len(function.Parameters) {
+		return Value{}, ErrArguments
+	}
+	return functions.call(e, function, arguments, limit)
+}
+
+// evalOperator applies the binary operator to values. Numbers are combined
+// with every element of matrix, "*" of two matrices is the matrix product
+func evalOperator(token string, a, b Value) (Value, error) {
+	switch token {
+	case "+", "-":
+		if a.Dimension != b.Dimension {
+			return Value{}, &DimensionError{Operation: token, Left: a.Dimension, Right: b.Dimension}
+		}
+		if token == "+" {
+			return elementwise(token, a, b, a.Dimension, func(x, y float64) float64 { return x + y })
+		}
+		return elementwise(token, a, b, a.Dimension, func(x, y float64) float64 { return x - y })
+	case "*", ImplicitMultiplication:
+		if !a.IsNumber() && !b.IsNumber() {
+			return matrixProduct(token, a, b)
+		}
+		return elementwise(token, a, b, a.Dimension.add(b.Dimension), func(x, y float64) float64 { return x * y })
+	case "/":
+		if !b.IsNumber() {
+			return Value{}, &ShapeError{Operation: token, Shapes: []Shape{a.Shape, b.Shape}}
+		}
+		if b.Number() == 0 {
+			return Value{}, ErrZeroByDivision
+		}
+		return elementwise(token, a, b, a.Dimension.sub(b.Dimension), func(x, y float64) float64 { return x / y })
+	case "^":
+		if !a.IsNumber() || !b.IsNumber() {
+			return Value{}, &ShapeError{Operation: token, Shapes: []Shape{a.Shape, b.Shape}}
+		}
+		if !b.Dimension.IsDimensionless() {
+			return Value{}, &DimensionError{Operation: token, Left: a.Dimension, Right: b.Dimension}
+		}
+		// Only whole powers of units are allowed
+		var dimension Dimension
+		if !a.Dimension.IsDimensionless() {
+			if b.Number() != math.Trunc(b.Number()) {
+				return Value{}, ErrDomain
+			}
+			if math.Abs(b.Number()) > maxUnitPower {
+				return Value{}, ErrOverflow
+			}
+			dimension = a.Dimension.mul(int(b.Number()))
+		}
+		return scalar(math.Pow(a.Number(), b.Number()), dimension), nil
+	default:
+		return Value{}, ErrExtraCharacters
+	}
+}
+
+// elementwise applies operation to the elements of values with the same shape,
+// or to a number and every element of matrix
+func elementwise(token string, a, b Value, dimension Dimension, operation func(x, y float64) float64) (Value, error) {
+	shape := a.Shape
+	switch {
+	case a.IsNumber():
+		shape = b.Shape
+	case !b.IsNumber() && a.Shape != b.Shape:
+		return Value{}, &ShapeError{Operation: token, Shapes: []Shape{a.Shape, b.Shape}}
+	}
+
+	result := Value{Shape: shape, Elements: make([]float64, max(len(a.Elements), len(b.Elements))), Dimension: dimension}
+	for i := range result.Elements {
+		x, y := a.Elements[0], b.Elements[0]
+		if !a.IsNumber() {
+			x = a.Elements[i]
+		}
+		if !b.IsNumber() {
+			y = b.Elements[i]
+		}
+		result.Elements[i] = operation(x, y)
+	}
+	return result, nil
+}
+
+// negate returns the value with opposite elements
+func negate(v Value) Value {
+	elements := make([]float64, len(v.Elements))
+	for i, x := range v.Elements {
+		elements[i] = -x
+	}
+	v.Elements = elements
+	return v
+}